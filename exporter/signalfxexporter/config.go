@@ -88,6 +88,11 @@ type Config struct {
 	// backend. If translations enabled with SendCompatibleMetrics or TranslationRules
 	// options, the exclusion will be applied on translated metrics.
 	ExcludeMetrics []string `mapstructure:"exclude_metrics"`
+
+	// MetricsConversion holds optional settings changing how metrics are
+	// converted to SignalFx datapoints, the default conversion is kept if
+	// not set.
+	MetricsConversion translation.MetricsConverterConfig `mapstructure:"metrics_conversion"`
 }
 
 func (cfg *Config) getOptionsFromConfig() (*exporterOptions, error) {
@@ -117,6 +122,11 @@ func (cfg *Config) getOptionsFromConfig() (*exporterOptions, error) {
 		}
 	}
 
+	converterOptions, err := cfg.MetricsConversion.Options()
+	if err != nil {
+		return nil, fmt.Errorf("invalid \"metrics_conversion\": %v", err)
+	}
+
 	return &exporterOptions{
 		ingestURL:        ingestURL,
 		apiURL:           apiURL,
@@ -124,6 +134,7 @@ func (cfg *Config) getOptionsFromConfig() (*exporterOptions, error) {
 		token:            cfg.AccessToken,
 		logDimUpdate:     cfg.LogDimensionUpdates,
 		metricTranslator: metricTranslator,
+		converterOptions: converterOptions,
 	}, nil
 }
 
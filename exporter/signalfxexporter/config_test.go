@@ -27,6 +27,7 @@ import (
 	"go.opentelemetry.io/collector/component/componenttest"
 	"go.opentelemetry.io/collector/config/configmodels"
 	"go.opentelemetry.io/collector/config/configtest"
+	"go.opentelemetry.io/collector/consumer/pdata"
 	"go.opentelemetry.io/collector/exporter/exporterhelper"
 	"go.uber.org/zap"
 
@@ -92,6 +93,18 @@ func TestLoadConfig(t *testing.T) {
 			},
 		},
 		DeltaTranslationTTL: 3600,
+		MetricsConversion: translation.MetricsConverterConfig{
+			DropZeroValueCounters: true,
+			DeltaToCumulative:     true,
+			DeltaToCumulativeTTL:  10 * time.Minute,
+			ExcludeMetricTypes:    []string{"IntHistogram", "DoubleHistogram"},
+			MetricTypeOverrides: map[string]string{
+				"^http\\.requests$": "CUMULATIVE_COUNTER",
+			},
+			HostIDAttributes: translation.HostIDAttributes{
+				GCPProjectNumber: "gcp.project_number",
+			},
+		},
 	}
 	assert.Equal(t, &expectedCfg, e1)
 
@@ -112,6 +125,7 @@ func TestConfig_getOptionsFromConfig(t *testing.T) {
 		SendCompatibleMetrics bool
 		TranslationRules      []translation.Rule
 		SyncHostMetadata      bool
+		MetricsConversion     translation.MetricsConverterConfig
 	}
 	tests := []struct {
 		name    string
@@ -216,6 +230,46 @@ func TestConfig_getOptionsFromConfig(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 		},
+		{
+			name: "Test metrics conversion",
+			fields: fields{
+				Realm:       "us0",
+				AccessToken: "access_token",
+				MetricsConversion: translation.MetricsConverterConfig{
+					DropZeroValueCounters: true,
+					ExcludeMetricTypes:    []string{"IntHistogram"},
+				},
+			},
+			want: &exporterOptions{
+				ingestURL: &url.URL{
+					Scheme: "https",
+					Host:   "ingest.us0.signalfx.com",
+				},
+				apiURL: &url.URL{
+					Scheme: "https",
+					Host:   "api.us0.signalfx.com",
+				},
+				httpTimeout: 5 * time.Second,
+				token:       "access_token",
+				converterOptions: translation.MetricsConverterOptions{
+					DropZeroValueCounters: true,
+					ExcludeMetricTypes:    []pdata.MetricDataType{pdata.MetricDataTypeIntHistogram},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Test invalid metrics conversion",
+			fields: fields{
+				Realm:       "us0",
+				AccessToken: "access_token",
+				MetricsConversion: translation.MetricsConverterConfig{
+					NonMonotonicSumAs: "histogram",
+				},
+			},
+			want:    nil,
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -232,6 +286,7 @@ func TestConfig_getOptionsFromConfig(t *testing.T) {
 				SendCompatibleMetrics: tt.fields.SendCompatibleMetrics,
 				TranslationRules:      tt.fields.TranslationRules,
 				SyncHostMetadata:      tt.fields.SyncHostMetadata,
+				MetricsConversion:     tt.fields.MetricsConversion,
 			}
 			got, err := cfg.getOptionsFromConfig()
 			if (err != nil) != tt.wantErr {
@@ -81,17 +81,10 @@ func (s *sfxDPClient) pushMetricsData(
 	// All metrics in the pdata.Metrics will have the same access token because of the BatchPerResourceMetrics.
 	metricToken := s.retrieveAccessToken(rms.At(0))
 
-	var sfxDataPoints []*sfxpb.DataPoint
+	sfxDataPoints, numDroppedTimeSeries := s.converter.MetricsToSignalFxV2(md)
 
-	for i := 0; i < rms.Len(); i++ {
-		rm := rms.At(i)
-		if rm.IsNil() {
-			continue
-		}
-		sfxDataPoints = append(sfxDataPoints, s.converter.MetricDataToSignalFxV2(rm)...)
-	}
-
-	return s.pushMetricsDataForToken(ctx, sfxDataPoints, metricToken)
+	numDroppedPushed, err := s.pushMetricsDataForToken(ctx, sfxDataPoints, metricToken)
+	return numDroppedTimeSeries + numDroppedPushed, err
 }
 
 func (s *sfxDPClient) pushMetricsDataForToken(ctx context.Context, sfxDataPoints []*sfxpb.DataPoint, accessToken string) (int, error) {
@@ -62,6 +62,7 @@ type signalfxExporter struct {
 	pushMetadata       func(metadata []*collection.MetadataUpdate) error
 	pushLogsData       func(ctx context.Context, ld pdata.Logs) (droppedLogRecords int, err error)
 	hostMetadataSyncer *hostmetadata.Syncer
+	converter          *translation.MetricsConverter
 }
 
 type exporterOptions struct {
@@ -71,6 +72,7 @@ type exporterOptions struct {
 	token            string
 	logDimUpdate     bool
 	metricTranslator *translation.MetricTranslator
+	converterOptions translation.MetricsConverterOptions
 }
 
 // newSignalFxExporter returns a new SignalFx exporter.
@@ -90,6 +92,12 @@ func newSignalFxExporter(
 
 	headers := buildHeaders(config)
 
+	converter, err := translation.NewMetricsConverter(logger, options.metricTranslator, options.converterOptions)
+	if err != nil {
+		return nil,
+			fmt.Errorf("failed to create metrics converter for %q: %v", config.Name(), err)
+	}
+
 	dpClient := &sfxDPClient{
 		sfxClientBase: sfxClientBase{
 			ingestURL: options.ingestURL,
@@ -103,7 +111,7 @@ func newSignalFxExporter(
 		},
 		logger:                 logger,
 		accessTokenPassthrough: config.AccessTokenPassthrough,
-		converter:              translation.NewMetricsConverter(logger, options.metricTranslator),
+		converter:              converter,
 	}
 
 	dimClient := dimensions.NewDimensionClient(
@@ -134,6 +142,7 @@ func newSignalFxExporter(
 		pushMetricsData:    dpClient.pushMetricsData,
 		pushMetadata:       dimClient.PushMetadata,
 		hostMetadataSyncer: hms,
+		converter:          converter,
 	}, nil
 }
 
@@ -185,6 +194,14 @@ func (se *signalfxExporter) pushMetrics(ctx context.Context, md pdata.Metrics) (
 	return numDroppedTimeSeries, err
 }
 
+// shutdown stops the background work of the metrics converter.
+func (se *signalfxExporter) shutdown(context.Context) error {
+	if se.converter != nil {
+		se.converter.Shutdown()
+	}
+	return nil
+}
+
 func (se *signalfxExporter) pushLogs(ctx context.Context, ld pdata.Logs) (int, error) {
 	return se.pushLogsData(ctx, ld)
 }
@@ -192,6 +192,8 @@ func TestConsumeMetrics(t *testing.T) {
 			serverURL, err := url.Parse(server.URL)
 			assert.NoError(t, err)
 
+			c, err := translation.NewMetricsConverter(zap.NewNop(), nil, translation.MetricsConverterOptions{})
+			require.NoError(t, err)
 			dpClient := &sfxDPClient{
 				sfxClientBase: sfxClientBase{
 					ingestURL: serverURL,
@@ -204,7 +206,7 @@ func TestConsumeMetrics(t *testing.T) {
 					}},
 				},
 				logger:    zap.NewNop(),
-				converter: translation.NewMetricsConverter(zap.NewNop(), nil),
+				converter: c,
 			}
 
 			numDroppedTimeSeries, err := dpClient.pushMetricsData(context.Background(), tt.md)
@@ -990,6 +992,8 @@ func BenchmarkExporterConsumeData(b *testing.B) {
 	serverURL, err := url.Parse(server.URL)
 	assert.NoError(b, err)
 
+	c, err := translation.NewMetricsConverter(zap.NewNop(), nil, translation.MetricsConverterOptions{})
+	require.NoError(b, err)
 	dpClient := &sfxDPClient{
 		sfxClientBase: sfxClientBase{
 			ingestURL: serverURL,
@@ -1001,7 +1005,7 @@ func BenchmarkExporterConsumeData(b *testing.B) {
 			}},
 		},
 		logger:    zap.NewNop(),
-		converter: translation.NewMetricsConverter(zap.NewNop(), nil),
+		converter: c,
 	}
 
 	for i := 0; i < b.N; i++ {
@@ -85,6 +85,7 @@ func createMetricsExporter(
 		expCfg,
 		params.Logger,
 		exp.pushMetrics,
+		exporterhelper.WithShutdown(exp.shutdown),
 		// explicitly disable since we rely on http.Client timeout logic.
 		exporterhelper.WithTimeout(exporterhelper.TimeoutSettings{Timeout: 0}),
 		exporterhelper.WithRetry(expCfg.RetrySettings),
@@ -155,6 +155,22 @@ func TestFactory_CreateMetricsExporterFails(t *testing.T) {
 			errorMessage: "failed to process \"signalfx\" config: requires a non-empty \"realm\"," +
 				" or \"ingest_url\" and \"api_url\" should be explicitly set",
 		},
+		{
+			name: "invalid_metrics_conversion",
+			config: &Config{
+				ExporterSettings: configmodels.ExporterSettings{
+					TypeVal: configmodels.Type(typeStr),
+					NameVal: typeStr,
+				},
+				AccessToken: "testToken",
+				Realm:       "us1",
+				MetricsConversion: translation.MetricsConverterConfig{
+					IncludeMetricTypes: []string{"Summary"},
+				},
+			},
+			errorMessage: "failed to process \"signalfx\" config: invalid \"metrics_conversion\":" +
+				" invalid metric data type: \"Summary\"",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -268,6 +284,26 @@ func TestCreateMetricsExporterWithDefinedRulesAndExcludedMetrics(t *testing.T) {
 	assert.True(t, config.TranslationRules[1].MetricNames["metric1"])
 }
 
+func TestCreateMetricsExporterWithMetricsConversion(t *testing.T) {
+	config := &Config{
+		ExporterSettings: configmodels.ExporterSettings{
+			TypeVal: configmodels.Type(typeStr),
+			NameVal: typeStr,
+		},
+		AccessToken: "testToken",
+		Realm:       "us1",
+		MetricsConversion: translation.MetricsConverterConfig{
+			DeltaToCumulative:  true,
+			IncludeMetricTypes: []string{"IntSum", "DoubleSum"},
+		},
+	}
+
+	te, err := createMetricsExporter(context.Background(), component.ExporterCreateParams{Logger: zap.NewNop()}, config)
+	require.NoError(t, err)
+	require.NotNil(t, te)
+	assert.NoError(t, te.Shutdown(context.Background()))
+}
+
 func TestDefaultTranslationRules(t *testing.T) {
 	rules, err := loadDefaultTranslationRules()
 	require.NoError(t, err)
@@ -276,8 +312,9 @@ func TestDefaultTranslationRules(t *testing.T) {
 	require.NoError(t, err)
 	data := testMetricsData()
 
-	c := translation.NewMetricsConverter(zap.NewNop(), tr)
-	translated := c.MetricDataToSignalFxV2(data)
+	c, err := translation.NewMetricsConverter(zap.NewNop(), tr, translation.MetricsConverterOptions{})
+	require.NoError(t, err)
+	translated, _ := c.MetricDataToSignalFxV2(data)
 	require.NotNil(t, translated)
 
 	metrics := make(map[string][]*sfxpb.DataPoint)
@@ -0,0 +1,71 @@
+// Copyright 2020, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package translation
+
+import (
+	"time"
+
+	"go.opentelemetry.io/collector/consumer/pdata"
+)
+
+// ConversionObserver is notified about the conversions done by a
+// MetricsConverter, e.g. to report telemetry about the converted metrics.
+type ConversionObserver interface {
+	// OnMetricKindSummary is called after the conversion of a ResourceMetrics
+	// with the number of converted metrics per kind.
+	OnMetricKindSummary(summary MetricKindSummary)
+
+	// OnMetricConverted is called after the conversion of each metric with
+	// the time spent converting it, including translation rules, and the
+	// number of emitted datapoints. The count is zero if the datapoints are
+	// dropped because of a type conflict or the batch limit, later drops of
+	// single datapoints by DedupLatestGauge are not reflected.
+	OnMetricConverted(metric string, duration time.Duration, datapoints int)
+
+	// OnTranslationFailure is called for each metric whose datapoints are
+	// left untranslated because a translation rule panicked.
+	OnTranslationFailure(metric string)
+}
+
+// MetricKind identifies the data type, aggregation temporality and
+// monotonicity of a metric. Unexpected kinds, like non-monotonic cumulative
+// sums, usually point to misconfigured instrumentation.
+type MetricKind struct {
+	DataType pdata.MetricDataType
+	// Temporality is unspecified for gauges.
+	Temporality pdata.AggregationTemporality
+	// Monotonic is only set for sums.
+	Monotonic bool
+}
+
+// MetricKindSummary holds the number of metrics per MetricKind.
+type MetricKindSummary map[MetricKind]int
+
+func metricKindOf(metric pdata.Metric) MetricKind {
+	kind := MetricKind{DataType: metric.DataType()}
+	switch metric.DataType() {
+	case pdata.MetricDataTypeIntSum:
+		kind.Temporality = metric.IntSum().AggregationTemporality()
+		kind.Monotonic = metric.IntSum().IsMonotonic()
+	case pdata.MetricDataTypeDoubleSum:
+		kind.Temporality = metric.DoubleSum().AggregationTemporality()
+		kind.Monotonic = metric.DoubleSum().IsMonotonic()
+	case pdata.MetricDataTypeIntHistogram:
+		kind.Temporality = metric.IntHistogram().AggregationTemporality()
+	case pdata.MetricDataTypeDoubleHistogram:
+		kind.Temporality = metric.DoubleHistogram().AggregationTemporality()
+	}
+	return kind
+}
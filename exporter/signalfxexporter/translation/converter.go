@@ -17,15 +17,21 @@ package translation
 import (
 	"fmt"
 	"math"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
+	"unicode/utf8"
 
 	sfxpb "github.com/signalfx/com_signalfx_metrics_protobuf/model"
 	"go.opentelemetry.io/collector/consumer/pdata"
 	"go.opentelemetry.io/collector/translator/conventions"
 	tracetranslator "go.opentelemetry.io/collector/translator/trace"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 
 	"github.com/open-telemetry/opentelemetry-collector-contrib/internal/splunk"
 )
@@ -41,9 +47,95 @@ var (
 	// Some standard dimension keys.
 	// upper bound dimension key for histogram buckets.
 	upperBoundDimensionKey = "upper_bound"
+	// bucket index dimension key for histogram buckets with IncludeBucketIndex.
+	bucketIndexDimensionKey = "bucket_index"
 
 	// infinity bound dimension value is used on all histograms.
 	infinityBoundSFxDimValue = float64ToDimValue(math.Inf(1))
+
+	// gcpProjectNumberAttribute is the default resource attribute holding the
+	// numeric GCP project number, preferred over the textual project ID in
+	// gcp_id. It isn't a semantic convention attribute and no resource
+	// detector of the collector sets it, it has to be added to resources,
+	// e.g. with the resource processor, or overridden with
+	// HostIDAttributes.GCPProjectNumber.
+	gcpProjectNumberAttribute = "gcp.project.number"
+
+	// maxWarnedGCPProjectIDs bounds the GCP project IDs remembered to warn
+	// only once about their missing project number. The set is reset once
+	// full, warning again about the forgotten projects.
+	maxWarnedGCPProjectIDs = 1024
+
+	// startTimestampPropertyKey is the key of the property holding the start
+	// timestamp of counter datapoints with the StartTimestampProperty option.
+	startTimestampPropertyKey = "start_timestamp_ms"
+
+	// heartbeatMetricName is the name of the gauge emitted for each resource
+	// with the EmitHeartbeat option.
+	heartbeatMetricName = "sf.up"
+
+	// conversionStatsMetricName is the name of the gauge emitted for each
+	// converted metric with the EmitConversionStats option.
+	conversionStatsMetricName = "sf.converter.datapoints"
+
+	// buildInfoMetricName is the name of the gauge emitted for each
+	// conversion call with the EmitBuildInfo option, with the version in the
+	// buildInfoVersionDimensionKey dimension.
+	buildInfoMetricName          = "sf.collector.build_info"
+	buildInfoVersionDimensionKey = "version"
+
+	// sourceMetricDimensionKey is the dimension key holding the name of the
+	// converted metric on conversion stats datapoints.
+	sourceMetricDimensionKey = "source_metric"
+
+	// serviceDimensionKey is the dimension key holding the service name with
+	// the RequireServiceName option.
+	serviceDimensionKey = "service"
+
+	// hostDimensionKey is the dimension key holding the host name with the
+	// HostNameFallback option.
+	hostDimensionKey = "host"
+
+	// counterNameSuffix is the suffix of the names of gauges converted to
+	// cumulative counters with the InferCounterFromName option.
+	counterNameSuffix = "_total"
+
+	// otlpTypeDimensionKey is the dimension key holding the OTLP data type of
+	// the source metric with the AnnotateOTLPType option.
+	otlpTypeDimensionKey = "otlp_type"
+
+	// sampleRateDimensionKey is the dimension key holding the sample rate of
+	// datapoints with the SampleRateLabel option.
+	sampleRateDimensionKey = "sample_rate"
+
+	// defaultServiceNameFallback is the service dimension value of resources
+	// without a service name, unless overridden by ServiceNameFallback.
+	defaultServiceNameFallback = "unknown_service"
+
+	// attributeTypeDimensionKeySuffix is the suffix of the keys of the type
+	// dimensions added with the AttributeTypeDimensions option.
+	attributeTypeDimensionKeySuffix = "_type"
+
+	// defaultMetricNameContextDimension is the default key of the context
+	// dimension added with the MetricNameDelimiter option.
+	defaultMetricNameContextDimension = "context"
+
+	// collectorIDDimensionKey is the dimension key holding the collector
+	// instance ID with the CollectorInstanceID option.
+	collectorIDDimensionKey = "collector_id"
+
+	// defaultMetricNamespaceDelimiter is the default delimiter of the
+	// namespace added with the MetricNamespaceAttributes option.
+	defaultMetricNamespaceDelimiter = "."
+
+	// Defaults of the DeltaToCumulativeTTL and DeltaToCumulativeMaxSeries
+	// options.
+	defaultDeltaToCumulativeTTL       = 10 * time.Minute
+	defaultDeltaToCumulativeMaxSeries = 100000
+
+	// Defaults of the DropEventsInterval and MaxDropEventsPerInterval options.
+	defaultDropEventsInterval       = time.Minute
+	defaultMaxDropEventsPerInterval = 100
 )
 
 // MetricsConverter converts MetricsData to sfxpb DataPoints. It holds an optional
@@ -51,145 +143,2369 @@ var (
 type MetricsConverter struct {
 	logger           *zap.Logger
 	metricTranslator *MetricTranslator
+	options          MetricsConverterOptions
+
+	// Set of MetricsConverterOptions.PropertyAttributes for fast lookups.
+	propertyAttributes map[string]bool
+
+	// Compiled MetricsConverterOptions.MetricTypeOverrides sorted by pattern.
+	metricTypeOverrides []metricTypeOverride
+
+	// Sets of MetricsConverterOptions.DimensionAllowLists and
+	// DefaultDimensionAllowList for fast lookups.
+	dimensionAllowLists       map[string]map[string]bool
+	defaultDimensionAllowList map[string]bool
+
+	// Set of MetricsConverterOptions.BlockedDimensionKeys for fast lookups.
+	blockedDimensionKeys map[string]bool
+
+	// Metric metadata fields emitted as properties, from the
+	// IncludeMetricDescription and MetricMetadataProperties options.
+	metadataProperties []MetricMetadataField
+
+	// Running totals of delta sums with the DeltaToCumulative option.
+	cumulativeAccumulator *cumulativeAccumulator
+
+	// Drops counted for the DropEvents method with the EmitDropEvents option.
+	dropEvents *dropEventAggregator
+
+	// GCP project IDs already warned about missing project numbers, so the
+	// warning is only logged once per project instead of for every resource.
+	// Bounded by maxWarnedGCPProjectIDs.
+	warnedGCPProjectIDs   map[string]bool
+	warnedGCPProjectIDsMu sync.Mutex
+}
+
+type metricTypeOverride struct {
+	pattern    *regexp.Regexp
+	metricType sfxpb.MetricType
+}
+
+// MetricsConverterOptions holds optional settings changing how metrics are
+// converted. The zero value keeps the default conversion behavior.
+//
+// The signalfx exporter sets the options from the "metrics_conversion" config
+// section, see MetricsConverterConfig for the options without a config
+// counterpart.
+type MetricsConverterOptions struct {
+	// DropZeroValueCounters drops COUNTER and CUMULATIVE_COUNTER datapoints
+	// converted from sums whose value is zero. Gauges are not affected.
+	DropZeroValueCounters bool
+
+	// ArrayAttributeRendering controls how array resource attributes are
+	// rendered to dimension values. Defaults to ArrayAttributeRenderingJSON.
+	ArrayAttributeRendering ArrayAttributeRendering
+	// ArrayAttributeSeparator is the separator used to join array elements
+	// with ArrayAttributeRenderingJoin.
+	ArrayAttributeSeparator string
+
+	// BooleanDimensionEncoding controls how boolean resource attributes,
+	// including the elements of arrays rendered with
+	// ArrayAttributeRenderingFirst or ArrayAttributeRenderingJoin, are
+	// rendered to dimension values. Defaults to BooleanDimensionEncodingTrueFalse.
+	BooleanDimensionEncoding BooleanDimensionEncoding
+
+	// PropertyAttributes lists resource attribute keys that are emitted as
+	// SignalFx properties instead of dimensions. Properties are not indexed,
+	// which keeps high cardinality metadata out of the dimension space. They
+	// are only returned by MetricDataToSignalFxV2WithProperties.
+	PropertyAttributes []string
+
+	// InfinityBoundDimensionValue is the upper_bound dimension value of the
+	// histogram bucket counting all values, defaults to "+Inf".
+	InfinityBoundDimensionValue string
+	// InfinityBucketMetricSuffix, if set, emits the histogram bucket counting
+	// all values as a distinct metric named after the histogram with the
+	// suffix appended, e.g. "_bucket_inf", without the upper_bound dimension,
+	// instead of a "_bucket" datapoint with an infinity bound.
+	InfinityBucketMetricSuffix string
+
+	// IncludeMetricDescription adds the description of metrics as the
+	// "description" property of the first datapoint of each metric. Properties
+	// are only returned by MetricDataToSignalFxV2WithProperties. It is the
+	// same as listing MetricMetadataFieldDescription in
+	// MetricMetadataProperties.
+	IncludeMetricDescription bool
+
+	// MetricMetadataProperties lists the metric metadata fields added as
+	// properties, named after the fields, of the first datapoint of each
+	// metric. Empty fields are skipped. Properties are only returned by
+	// MetricDataToSignalFxV2WithProperties.
+	MetricMetadataProperties []MetricMetadataField
+
+	// StartTimestampProperty adds the start timestamp of the datapoints of
+	// sums converted to COUNTER and CUMULATIVE_COUNTER datapoints as the
+	// "start_timestamp_ms" property, in milliseconds regardless of
+	// TimestampResolution, e.g. to debug counter windows without adding a
+	// dimension. Datapoints without start timestamp are skipped. Properties
+	// are only returned by MetricDataToSignalFxV2WithProperties.
+	StartTimestampProperty bool
+
+	// AccessTokenHandler is called with the SignalFx access token found in the
+	// splunk.SFxAccessTokenLabel resource attribute, allowing callers to route
+	// datapoints by token. The token is never emitted as a dimension.
+	AccessTokenHandler func(accessToken string)
+
+	// PoolDimensionBuffers allocates the dimensions of converted datapoints in
+	// chunks shared by consecutive datapoints, keeping the unused part of the
+	// last chunk in a sync.Pool for the next conversion. This reduces
+	// allocations at the cost of a whole chunk being kept in memory as long
+	// as any of the datapoints using it is referenced.
+	PoolDimensionBuffers bool
+
+	// EmitHeartbeat adds a "sf.up" gauge datapoint with value 1 and the
+	// resource dimensions for each converted resource, allowing to alert on
+	// resources not sending metrics. Resources without dimensions are skipped,
+	// the collector_id and DimensionProvider dimensions added by the converter
+	// don't identify a resource and are not taken into account.
+	EmitHeartbeat bool
+
+	// EmitBuildInfo, if set, is the collector version emitted as the
+	// "version" dimension of a "sf.collector.build_info" gauge datapoint with
+	// value 1, added once per conversion call regardless of the number of
+	// resources, e.g. for fleet auditing. The CollectorInstanceID dimension
+	// is added to it if set.
+	EmitBuildInfo string
+
+	// AttributeFilter, if set, is called with the key of each resource
+	// attribute, only attributes for which it returns true are converted to
+	// dimensions. It applies in addition to the filtering of attributes used
+	// to build cloud host ids.
+	AttributeFilter func(key string) bool
+
+	// DimensionAllowListAttribute is the resource attribute, e.g. a tenant
+	// ID, whose value selects the allow-list of DimensionAllowLists applied
+	// to a resource. Allow-lists are disabled if empty.
+	DimensionAllowListAttribute string
+	// DimensionAllowLists maps values of DimensionAllowListAttribute to the
+	// keys of the resource attributes converted to dimensions for resources
+	// with that value, other attributes are dropped. It applies in addition
+	// to AttributeFilter. Dimensions built from several attributes, like
+	// cloud host ids, and the service dimension of RequireServiceName are
+	// not affected.
+	DimensionAllowLists map[string][]string
+	// DefaultDimensionAllowList is the allow-list of resources without
+	// DimensionAllowListAttribute or with a value missing from
+	// DimensionAllowLists. All resource attributes of such resources are
+	// converted if empty.
+	DefaultDimensionAllowList []string
+
+	// BlockedDimensionKeys lists dimension keys, e.g. "auth.token", that
+	// never end up on converted datapoints, regardless of allow-lists and
+	// other options. Resource attributes and datapoint labels with these keys
+	// are skipped before DimensionKeyMapping and SampleRateLabel are applied,
+	// and all the dimensions of converted datapoints whose key matches, as
+	// listed or once sanitized, are dropped as a last step, including
+	// dimensions added by other options, like CollectorInstanceID, by the
+	// DimensionProvider or by translation rules. Blocked keys are logged at
+	// debug level.
+	BlockedDimensionKeys []string
+
+	// NonMonotonicSumAs is the SignalFx type of the datapoints of
+	// non-monotonic sums, defaults to NonMonotonicSumAsGauge.
+	NonMonotonicSumAs NonMonotonicSumAs
+
+	// InferCounterFromName converts gauges whose name ends in "_total", the
+	// usual counter naming, to CUMULATIVE_COUNTER datapoints, e.g. for
+	// upstreams wrongly emitting counters as gauges. Sums, including
+	// non-monotonic ones, and metrics matching MetricTypeOverrides are not
+	// affected. Converted metrics are logged at debug level.
+	InferCounterFromName bool
+
+	// AnnotateOTLPType adds the OTLP data type of the source metric as the
+	// "otlp_type" dimension, e.g. "int_gauge" or "double_sum", to debug type
+	// mappings. It adds a dimension to all datapoints and can split series
+	// whose type changes, so it is best enabled only while debugging.
+	AnnotateOTLPType bool
+
+	// DropConflictingMetricTypes drops the datapoints of a metric whose name
+	// was already converted with a different SignalFx type for the same
+	// resource, e.g. a gauge and a counter both named "requests", keeping
+	// the first one. Such conflicts are logged as warnings either way.
+	DropConflictingMetricTypes bool
+
+	// SampleRateLabel is the datapoint label, e.g. "sampling.rate", holding
+	// the rate at which upstream sampling kept the datapoint, in (0, 1]. It
+	// is converted to the "sample_rate" dimension instead of a dimension
+	// named after the label.
+	SampleRateLabel string
+	// ScaleCountersBySampleRate divides the values of COUNTER and
+	// CUMULATIVE_COUNTER datapoints by their SampleRateLabel rate, e.g.
+	// multiplying them by 10 with a rate of 0.1, to estimate the unsampled
+	// values. Values are scaled after ValueTransformer is applied and int
+	// values are converted to doubles if the result isn't integral.
+	// Datapoints without a valid rate are left as is.
+	ScaleCountersBySampleRate bool
+
+	// DeltaToCumulative accumulates the values of delta sums converted to
+	// COUNTER datapoints per metric and dimensions, emitting the running
+	// totals as CUMULATIVE_COUNTER datapoints, for SignalFx organizations
+	// only supporting cumulative counters. Totals are kept in memory by the
+	// converter, bounded by DeltaToCumulativeTTL and
+	// DeltaToCumulativeMaxSeries. A negative delta is considered a reset of
+	// the source counter, the total of the series then starts over from
+	// zero. Translation rules see the totals. The converter must be shut
+	// down with Shutdown to stop the eviction of totals.
+	DeltaToCumulative bool
+	// DeltaToCumulativeTTL is the time after which the total of a series
+	// without new datapoints is evicted, the next datapoint of the series
+	// starting a new total. It is rounded down to seconds and defaults to 10
+	// minutes.
+	DeltaToCumulativeTTL time.Duration
+	// DeltaToCumulativeMaxSeries is the maximum number of series whose total
+	// is kept, datapoints of new series are dropped while it is reached.
+	// Defaults to 100000.
+	DeltaToCumulativeMaxSeries int
+
+	// NilHandling selects how nil resource metrics, instrumentation library
+	// metrics, metrics and gauge and sum datapoints, which are always
+	// skipped, are reported. Defaults to NilHandlingSkip.
+	NilHandling NilHandling
+
+	// IncludeMetricTypes restricts the conversion to metrics of the listed
+	// data types, all types are converted if empty.
+	IncludeMetricTypes []pdata.MetricDataType
+	// ExcludeMetricTypes lists data types of metrics that are not converted,
+	// e.g. to leave histograms to another exporter.
+	ExcludeMetricTypes []pdata.MetricDataType
+
+	// DimensionValueCase maps dimension keys, as they are before sanitization
+	// and after DimensionKeyMapping, to a case normalization applied to their
+	// values. Values of other dimensions are kept as is.
+	DimensionValueCase map[string]DimensionValueCase
+
+	// TrimDimensionValues strips leading and trailing whitespace from
+	// dimension values during sanitization, so that padded values don't
+	// create distinct time series.
+	TrimDimensionValues bool
+
+	// DimensionValueDefaults maps dimension keys, as they are before
+	// sanitization and after DimensionKeyMapping, to the value set during
+	// sanitization on dimensions with that key and an empty value, e.g.
+	// "unknown" for an empty "environment" dimension. Values that are empty
+	// once trimmed with TrimDimensionValues are filled too. Dimensions
+	// dropped by DropEmptyDimensions aren't restored.
+	DimensionValueDefaults map[string]string
+
+	// Observer is notified about the conversions if set.
+	Observer ConversionObserver
+
+	// PrometheusCumulativeBuckets emits the value of each histogram bucket as
+	// the number of observations less than or equal to its upper bound, as
+	// Prometheus "le" buckets, instead of the OTLP per bucket count.
+	PrometheusCumulativeBuckets bool
+
+	// MaxDimensions is the maximum number of dimensions of a datapoint, extra
+	// dimensions are dropped according to DimensionPriority. The upper_bound
+	// and bucket_index dimensions of histogram buckets are always kept. No
+	// limit if zero.
+	MaxDimensions int
+	// DimensionPriority selects the dimensions kept first on datapoints with
+	// more than MaxDimensions, defaults to DimensionPriorityResource.
+	DimensionPriority DimensionPriority
+
+	// DimensionOrder selects whether dimensions from resource attributes,
+	// including CollectorInstanceID and DimensionProvider dimensions, come
+	// before or after dimensions from datapoint labels on datapoints, for
+	// consumers depending on the order. Histogram bucket dimensions always
+	// come last. It doesn't change which dimension is kept on key
+	// collisions. Defaults to DimensionOrderResourceFirst.
+	DimensionOrder DimensionOrder
+
+	// DedupLatestGauge keeps only the datapoint with the newest timestamp
+	// among gauge datapoints of the same metric and dimensions converted at
+	// once, the latest one in the input order wins on equal timestamps.
+	DedupLatestGauge bool
+
+	// RequireServiceName adds a "service" dimension with the value of the
+	// service.name resource attribute to all datapoints, using
+	// ServiceNameFallback if the attribute is missing or empty. The
+	// service.name attribute itself is still converted as usual.
+	RequireServiceName bool
+	// ServiceNameFallback is the "service" dimension value of resources
+	// without service name, defaults to "unknown_service".
+	ServiceNameFallback string
+
+	// TimestampResolution is the resolution of the timestamps of converted
+	// datapoints, defaults to TimestampResolutionMillis. Higher resolutions
+	// are only accepted by some SignalFx ingest endpoints, check that the
+	// endpoint supports the chosen resolution before changing it.
+	TimestampResolution TimestampResolution
+
+	// AttributeTypeDimensions adds a "<key>_type" dimension next to each
+	// dimension converted from a non-string resource attribute, with the type
+	// of the attribute value as value: "bool", "int", "double", "map" or
+	// "array". It allows filtering on the type lost by the conversion of
+	// attribute values to strings.
+	AttributeTypeDimensions bool
+
+	// FlattenMapAttributes converts map resource attributes to a dimension
+	// per value of the map, keyed by the attribute key and the keys of the
+	// value joined with ".", e.g. "k8s.labels.app", instead of a single
+	// dimension holding the JSON encoded map. Nested maps are flattened too.
+	// Keys are sanitized like other dimension keys.
+	FlattenMapAttributes bool
+
+	// HistogramSumAsGauge emits the sum datapoint of histograms, named after
+	// the histogram, as a gauge regardless of the histogram temporality,
+	// avoiding double rate calculations on charts. The count and bucket
+	// datapoints keep the type of the histogram.
+	HistogramSumAsGauge bool
+
+	// HistogramCountAsBase emits the count datapoint of histograms named
+	// after the histogram instead of with the "_count" suffix, e.g. to keep
+	// dashboards working after a producer switched from a counter to a
+	// histogram. The sum datapoint is then emitted with the "_sum" suffix.
+	HistogramCountAsBase bool
+
+	// HistogramBucketsAsGauge emits the bucket datapoints of histograms as
+	// gauges regardless of the histogram temporality, for charts showing
+	// each bucket as an independent time series. The count and sum
+	// datapoints keep their type.
+	HistogramBucketsAsGauge bool
+
+	// IncludeBucketIndex adds a "bucket_index" dimension with the zero-based
+	// index of the bucket to the bucket datapoints of histograms, the
+	// infinity bucket having the highest index. It allows ordering buckets
+	// in charts where the lexical order of upper_bound values is wrong.
+	IncludeBucketIndex bool
+
+	// EmitQuantileEstimates lists quantiles, between 0 and 1, estimated from
+	// the buckets of each histogram datapoint and emitted as
+	// "<metric>.p<100*quantile>" gauges, e.g. "latency.p99" for 0.99.
+	// Quantiles are estimated like the Prometheus histogram_quantile
+	// function: observations are assumed to be uniformly distributed in each
+	// bucket, linearly interpolating the quantile between the bounds of the
+	// bucket holding it. The lower bound of the first bucket is 0, quantiles
+	// falling in it are estimated as its upper bound if it isn't positive.
+	// Quantiles falling in the infinity bucket are estimated as the highest
+	// bound. Histograms without
+	// observations or bounds, or with invalid bounds, get no estimates.
+	EmitQuantileEstimates []float64
+
+	// MaxHistogramBuckets is the maximum number of buckets of a histogram
+	// datapoint, histograms with more buckets are handled according to
+	// HistogramBucketOverflow and logged. No limit if zero.
+	MaxHistogramBuckets int
+	// HistogramBucketOverflow selects how histograms with more than
+	// MaxHistogramBuckets buckets are converted, defaults to
+	// HistogramBucketOverflowDrop.
+	HistogramBucketOverflow HistogramBucketOverflow
+
+	// DropEmptyDimensions skips datapoint labels and resource attributes with
+	// an empty value instead of converting them to dimensions, SignalFx
+	// considers empty dimension values as distinct time series.
+	DropEmptyDimensions bool
+
+	// DimensionKeyMapping renames the keys of dimensions converted from
+	// resource attributes and datapoint labels, e.g. to map semantic
+	// convention keys to SignalFx conventional dimension names. Keys are
+	// mapped before sanitization and unmapped keys are kept as is. When set,
+	// dimensions whose key is already present on the datapoint are dropped,
+	// keeping the first one, resource dimensions coming before labels.
+	DimensionKeyMapping map[string]string
+
+	// OmitHistogramSum skips the sum datapoint of histograms, keeping the
+	// count and bucket datapoints, for histograms whose sum is meaningless.
+	OmitHistogramSum bool
+
+	// OmitEmptyHistograms skips all the datapoints of histogram datapoints
+	// whose count is zero, e.g. histograms of windows without observations.
+	OmitEmptyHistograms bool
+
+	// MetricNameDelimiter, if set, splits hierarchical metric names on the
+	// delimiter, e.g. "." for "http.server.duration". The last segment is
+	// used as the metric name, histogram suffixes being appended to it, and
+	// the prior segments joined with "_" are emitted as a context dimension,
+	// e.g. "duration" with context "http_server". Names with a single segment
+	// are kept as is. Translation rules apply to the split names.
+	MetricNameDelimiter string
+	// MetricNameContextDimension is the key of the context dimension added
+	// with MetricNameDelimiter, defaults to "context".
+	MetricNameContextDimension string
+
+	// SanitizeMetricNames replaces the characters of metric names that are
+	// not letters, digits, "_", "-" or "." with "_", for upstreams emitting
+	// metric names rejected by SignalFx. It applies after the split of
+	// MetricNameDelimiter and before translation rules, which must match the
+	// sanitized names. Metric names are emitted as is if false.
+	SanitizeMetricNames bool
+
+	// LowercaseMetricNames converts metric names to lower case, e.g. to
+	// merge series of upstreams using different casings. It applies before
+	// the split of MetricNameDelimiter and the histogram suffixes, the
+	// namespace of MetricNamespaceAttributes being kept as is, and before
+	// translation rules, which must match the lowercased names.
+	// MetricTypeOverrides patterns still match the original names.
+	LowercaseMetricNames bool
+
+	// MetricNamespaceAttributes lists resource attributes whose values, in
+	// order, prefix the names of the metrics of the resource, joined with
+	// MetricNamespaceDelimiter, e.g. "teamA.serviceX.metric" for the "team"
+	// and "service.name" attributes. Missing or empty attributes are
+	// skipped. The namespace is added after the split of MetricNameDelimiter
+	// and before SanitizeMetricNames and translation rules, which must match
+	// the namespaced names.
+	MetricNamespaceAttributes []string
+	// MetricNamespaceDelimiter joins the MetricNamespaceAttributes values
+	// and the metric name, defaults to ".".
+	MetricNamespaceDelimiter string
+
+	// CollectorInstanceID, if set, is emitted as the "collector_id" dimension
+	// of all datapoints converted by MetricDataToSignalFxV2, identifying the
+	// collector instance that produced them.
+	CollectorInstanceID string
+
+	// DimensionProvider, if set, provides dimensions added to all datapoints
+	// converted by MetricDataToSignalFxV2 and MetricsToSignalFxV2, after the
+	// resource dimensions. It is called once per call of these functions,
+	// provided dimensions whose key is already a resource dimension are
+	// skipped.
+	DimensionProvider DimensionProvider
+
+	// HostIDAttributes overrides the resource attribute keys used to build
+	// the cloud host id dimensions, AWSUniqueId and gcp_id, for resource
+	// detection not following the semantic conventions.
+	HostIDAttributes HostIDAttributes
+	// HostNameFallback emits the "host.name" resource attribute as the "host"
+	// dimension when no cloud host id dimension can be built, e.g. because
+	// the cloud account is missing, so that hosts can still be correlated.
+	// Resources already having a "host" attribute are not affected.
+	HostNameFallback bool
+
+	// MaxDimensionKeyLength is the maximum length in bytes of dimension keys,
+	// longer keys are truncated after sanitization since SignalFx rejects
+	// them. No limit if zero.
+	MaxDimensionKeyLength int
+
+	// MaxPropertyValueLength is the maximum length in bytes of string
+	// property values, from the PropertyAttributes and
+	// MetricMetadataProperties options, longer values are truncated since
+	// SignalFx rejects them. Dimension values are not affected. No limit if
+	// zero.
+	MaxPropertyValueLength int
+
+	// ValueTransformer, if set, is called with the metric name and value of
+	// each gauge and sum datapoint and its result is emitted instead of the
+	// value, e.g. to clamp values. Int values are passed as float64 and are
+	// emitted as doubles if the result isn't integral. Histograms are not
+	// transformed. It runs once per datapoint in the conversion hot path, so
+	// it must be fast and it should return the value as is for metrics it
+	// doesn't transform.
+	ValueTransformer func(metric string, value float64) float64
+
+	// UnsignedCounterStringThreshold emits the value of COUNTER and
+	// CUMULATIVE_COUNTER datapoints converted from int sums as a string
+	// holding the value read as an unsigned integer if it is greater than or
+	// equal to the threshold, e.g. 1<<63 for values of uint64 counters
+	// wrapped to negative int64 values. Translation rules don't compute on
+	// string values. Disabled if zero.
+	UnsignedCounterStringThreshold uint64
+
+	// DoubleValuePrecision rounds the values of double gauge and sum
+	// datapoints to the given number of decimal places, half away from zero,
+	// to avoid sending meaningless digits. It applies after ValueTransformer.
+	// Values are not rounded if zero.
+	DoubleValuePrecision int
+
+	// BucketBoundPrecision rounds the explicit bounds of histogram buckets to
+	// the given number of significant digits in upper_bound dimension values,
+	// e.g. 0.30000000000000004 becomes 0.3 with a precision of 15. It must be
+	// high enough to keep distinct bounds distinct. Bounds are emitted with
+	// the shortest exact representation if zero.
+	BucketBoundPrecision int
+	// FullPrecisionBucketBounds emits the explicit bounds of histogram
+	// buckets with all the 17 significant digits of a float64 in upper_bound
+	// dimension values, e.g. 0.1 becomes 0.10000000000000001, matching
+	// producers formatting bounds with "%.17g". It can't be combined with
+	// BucketBoundPrecision.
+	FullPrecisionBucketBounds bool
+
+	// MaxStaleness drops datapoints with a timestamp older than the current
+	// time minus MaxStaleness, e.g. replayed or long buffered datapoints,
+	// before translation rules are applied. Dropped datapoints are counted as
+	// dropped. Disabled if zero.
+	MaxStaleness time.Duration
+
+	// Clock returns the current time used for heartbeats and MaxStaleness,
+	// defaults to time.Now.
+	Clock func() time.Time
+
+	// MetricTypeOverrides maps regular expressions matched against metric
+	// names to the SignalFx type of the datapoints of matching metrics,
+	// overriding the type derived from the metric data type, e.g. to emit
+	// some gauges as counters. If several patterns match a metric, the first
+	// one in lexical order applies.
+	MetricTypeOverrides map[string]sfxpb.MetricType
+
+	// DefaultMetricTypeForNone, if set, is the SignalFx type of metrics
+	// without data type, which are then converted like any other metric
+	// instead of being dropped with a warning. pdata keeps no datapoints for
+	// such metrics, so they convert to no datapoints and are only logged at
+	// debug level, but the type applies to the detection of conflicting
+	// metric types.
+	DefaultMetricTypeForNone *sfxpb.MetricType
+
+	// EmitConversionStats adds a "sf.converter.datapoints" gauge datapoint
+	// for each converted metric, with the number of datapoints the metric was
+	// converted to as value and the resource dimensions plus a
+	// "source_metric" dimension holding the metric name. Metrics converted to
+	// no datapoints and metrics named "sf.converter.datapoints" are skipped.
+	EmitConversionStats bool
+
+	// EmitDropEvents counts the datapoints dropped by the conversion per
+	// metric name and reason, e.g. because of NaN histogram bounds, so that
+	// recurring conversion failures can be reported as SignalFx events
+	// returned by DropEvents.
+	EmitDropEvents bool
+	// DropEventsInterval is the minimum time between two batches of events
+	// returned by DropEvents, drops being summed up in between. Defaults to
+	// 1 minute.
+	DropEventsInterval time.Duration
+	// MaxDropEventsPerInterval is the maximum number of events, one per
+	// metric name, returned by a DropEvents call. Events of metrics beyond
+	// the limit in lexical order are omitted. Defaults to 100.
+	MaxDropEventsPerInterval int
+
+	// MaxDatapointsPerBatch caps the number of datapoints returned by a
+	// single MetricDataToSignalFxV2 or MetricsToSignalFxV2 call. Metrics are
+	// only emitted as a whole: once the datapoints of a metric don't fit, it
+	// and all the following metrics of the call are dropped and counted as
+	// dropped. Disabled if zero.
+	MaxDatapointsPerBatch int
+
+	// SanitizeBucketCounts emits histogram counts and bucket counts that are
+	// invalid once converted to SignalFx int values, i.e. above
+	// math.MaxInt64, as zero instead of dropping the buckets of the
+	// histogram datapoint. Invalid counts are logged.
+	SanitizeBucketCounts bool
+
+	// LargeBucketCountsAsDouble emits all the bucket counts of histogram
+	// datapoints with a bucket count above math.MaxInt64, and histogram
+	// counts above math.MaxInt64, as double values instead of dropping or
+	// sanitizing them. Such counts lose precision but keep their magnitude.
+	// It takes precedence over SanitizeBucketCounts.
+	LargeBucketCountsAsDouble bool
+
+	// InternDimensionStrings makes identical dimension keys and values of
+	// the datapoints of a converted resource share the same backing storage,
+	// and sanitizes each distinct dimension key only once. This reduces the
+	// memory and allocations needed for batches with repetitive dimensions.
+	// The interning pool only lives for the conversion of a resource, it is
+	// not shared between conversions.
+	InternDimensionStrings bool
+}
+
+// HostIDAttributes holds the resource attribute keys used to build cloud host
+// id dimensions. Empty keys default to the semantic convention keys.
+type HostIDAttributes struct {
+	// CloudAccount defaults to "cloud.account.id".
+	CloudAccount string `mapstructure:"cloud_account"`
+	// CloudRegion defaults to "cloud.region".
+	CloudRegion string `mapstructure:"cloud_region"`
+	// HostID defaults to "host.id".
+	HostID string `mapstructure:"host_id"`
+	// CloudProvider defaults to "cloud.provider".
+	CloudProvider string `mapstructure:"cloud_provider"`
+	// GCPProjectNumber defaults to "gcp.project.number".
+	GCPProjectNumber string `mapstructure:"gcp_project_number"`
+}
+
+// withDefaults returns the keys with empty ones set to the convention keys.
+func (a HostIDAttributes) withDefaults() HostIDAttributes {
+	if a.CloudAccount == "" {
+		a.CloudAccount = conventions.AttributeCloudAccount
+	}
+	if a.CloudRegion == "" {
+		a.CloudRegion = conventions.AttributeCloudRegion
+	}
+	if a.HostID == "" {
+		a.HostID = conventions.AttributeHostID
+	}
+	if a.CloudProvider == "" {
+		a.CloudProvider = conventions.AttributeCloudProvider
+	}
+	if a.GCPProjectNumber == "" {
+		a.GCPProjectNumber = gcpProjectNumberAttribute
+	}
+	return a
+}
+
+// DataPointProperties holds the SignalFx properties of converted datapoints.
+// sfxpb.DataPoint has no field for properties, so they are returned separately
+// keyed by the datapoint they belong to. Property slices can be shared between
+// datapoints and must not be modified.
+type DataPointProperties map[*sfxpb.DataPoint][]*sfxpb.Property
+
+// ArrayAttributeRendering is the enum to capture how array attribute values
+// are rendered to a single dimension value.
+type ArrayAttributeRendering string
+
+const (
+	// ArrayAttributeRenderingJSON renders the array as a JSON-like string,
+	// e.g. ["a","b"].
+	ArrayAttributeRenderingJSON ArrayAttributeRendering = "json"
+	// ArrayAttributeRenderingFirst renders only the first element of the array.
+	ArrayAttributeRenderingFirst ArrayAttributeRendering = "first"
+	// ArrayAttributeRenderingJoin renders the elements joined with
+	// MetricsConverterOptions.ArrayAttributeSeparator.
+	ArrayAttributeRenderingJoin ArrayAttributeRendering = "join"
+)
+
+// BooleanDimensionEncoding is the enum to capture how boolean attribute values
+// are rendered to dimension values.
+type BooleanDimensionEncoding string
+
+const (
+	// BooleanDimensionEncodingTrueFalse renders booleans as "true" and "false".
+	BooleanDimensionEncodingTrueFalse BooleanDimensionEncoding = "truefalse"
+	// BooleanDimensionEncodingOneZero renders booleans as "1" and "0".
+	BooleanDimensionEncodingOneZero BooleanDimensionEncoding = "onezero"
+)
+
+// MetricMetadataField is the enum to capture the metric metadata fields that
+// can be emitted as properties.
+type MetricMetadataField string
+
+const (
+	// MetricMetadataFieldDescription is the description of the metric.
+	MetricMetadataFieldDescription MetricMetadataField = "description"
+	// MetricMetadataFieldUnit is the unit of the metric.
+	MetricMetadataFieldUnit MetricMetadataField = "unit"
+)
+
+// value returns the value of the field for the metric.
+func (f MetricMetadataField) value(metric pdata.Metric) string {
+	switch f {
+	case MetricMetadataFieldDescription:
+		return metric.Description()
+	case MetricMetadataFieldUnit:
+		return metric.Unit()
+	}
+	return ""
 }
 
-// NewMetricsConverter creates a MetricsConverter from the passed in logger and
-// MetricTranslator. Pass in a nil MetricTranslator to not use translation
-// rules.
-func NewMetricsConverter(logger *zap.Logger, t *MetricTranslator) *MetricsConverter {
-	return &MetricsConverter{logger: logger, metricTranslator: t}
+// DimensionValueCase is the enum to capture the case normalization of
+// dimension values.
+type DimensionValueCase string
+
+const (
+	// DimensionValueCaseLower converts dimension values to lower case.
+	DimensionValueCaseLower DimensionValueCase = "lower"
+	// DimensionValueCaseUpper converts dimension values to upper case.
+	DimensionValueCaseUpper DimensionValueCase = "upper"
+)
+
+func (dvc DimensionValueCase) apply(val string) string {
+	switch dvc {
+	case DimensionValueCaseLower:
+		return strings.ToLower(val)
+	case DimensionValueCaseUpper:
+		return strings.ToUpper(val)
+	}
+	return val
+}
+
+// DimensionPriority is the enum to capture which dimensions are kept first
+// when datapoints have too many dimensions.
+type DimensionPriority string
+
+const (
+	// DimensionPriorityResource keeps dimensions from resource attributes
+	// before dimensions from datapoint labels.
+	DimensionPriorityResource DimensionPriority = "resource"
+	// DimensionPriorityLabels keeps dimensions from datapoint labels before
+	// dimensions from resource attributes.
+	DimensionPriorityLabels DimensionPriority = "labels"
+)
+
+// DimensionOrder is the enum to capture the order of dimensions from resource
+// attributes and datapoint labels on datapoints.
+type DimensionOrder string
+
+const (
+	// DimensionOrderResourceFirst places dimensions from resource attributes
+	// before dimensions from datapoint labels.
+	DimensionOrderResourceFirst DimensionOrder = "resource_first"
+	// DimensionOrderLabelsFirst places dimensions from datapoint labels
+	// before dimensions from resource attributes.
+	DimensionOrderLabelsFirst DimensionOrder = "labels_first"
+)
+
+// NonMonotonicSumAs is the enum to capture how non-monotonic sums are
+// converted.
+type NonMonotonicSumAs string
+
+const (
+	// NonMonotonicSumAsGauge converts non-monotonic sums to gauges.
+	NonMonotonicSumAsGauge NonMonotonicSumAs = "gauge"
+	// NonMonotonicSumAsCounter converts non-monotonic sums to counters that
+	// can decrease, like monotonic sums: cumulative counters for cumulative
+	// sums and counters for delta sums.
+	NonMonotonicSumAsCounter NonMonotonicSumAs = "counter"
+)
+
+// HistogramBucketOverflow is the enum to capture how histograms with more
+// buckets than MaxHistogramBuckets are converted.
+type HistogramBucketOverflow string
+
+const (
+	// HistogramBucketOverflowDrop drops the bucket datapoints, keeping the
+	// count and sum datapoints.
+	HistogramBucketOverflowDrop HistogramBucketOverflow = "drop"
+	// HistogramBucketOverflowMerge merges runs of adjacent buckets of the
+	// same size into a single bucket with the upper bound of the last one,
+	// so that there are at most MaxHistogramBuckets buckets.
+	HistogramBucketOverflowMerge HistogramBucketOverflow = "merge"
+)
+
+// NilHandling is the enum to capture how nil elements of converted metrics are
+// reported.
+type NilHandling string
+
+const (
+	// NilHandlingSkip silently skips nil elements.
+	NilHandlingSkip NilHandling = "skip"
+	// NilHandlingWarn skips nil elements, logging a warning.
+	NilHandlingWarn NilHandling = "warn"
+	// NilHandlingErrorCount skips nil elements, logging an error and counting
+	// each of them as a dropped datapoint, e.g. for pipelines where nils
+	// reveal upstream bugs.
+	NilHandlingErrorCount NilHandling = "error_count"
+)
+
+// TimestampResolution is the enum to capture the resolution of the timestamps
+// of converted datapoints.
+type TimestampResolution string
+
+const (
+	// TimestampResolutionMillis emits timestamps in milliseconds since epoch.
+	TimestampResolutionMillis TimestampResolution = "millis"
+	// TimestampResolutionMicros emits timestamps in microseconds since epoch.
+	TimestampResolutionMicros TimestampResolution = "micros"
+	// TimestampResolutionNanos emits timestamps in nanoseconds since epoch.
+	TimestampResolutionNanos TimestampResolution = "nanos"
+)
+
+// nanosPerUnit returns the number of nanoseconds per unit of the resolution.
+func (tr TimestampResolution) nanosPerUnit() int64 {
+	switch tr {
+	case TimestampResolutionMicros:
+		return 1e3
+	case TimestampResolutionNanos:
+		return 1
+	}
+	return 1e6
+}
+
+// NewMetricsConverter creates a MetricsConverter from the passed in logger,
+// MetricTranslator and options. Pass in a nil MetricTranslator to not use
+// translation rules. An error is returned if the translation rules or the
+// options are invalid.
+func NewMetricsConverter(logger *zap.Logger, t *MetricTranslator, options MetricsConverterOptions) (*MetricsConverter, error) {
+	if t != nil {
+		if err := t.ValidateRules(); err != nil {
+			return nil, fmt.Errorf("invalid translation rules: %v", err)
+		}
+	}
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+
+	metricTypeOverrides, err := compileMetricTypeOverrides(options.MetricTypeOverrides)
+	if err != nil {
+		return nil, err
+	}
+
+	var propertyAttributes map[string]bool
+	if len(options.PropertyAttributes) > 0 {
+		propertyAttributes = stringSet(options.PropertyAttributes)
+	}
+
+	var dimensionAllowLists map[string]map[string]bool
+	if len(options.DimensionAllowLists) > 0 {
+		dimensionAllowLists = make(map[string]map[string]bool, len(options.DimensionAllowLists))
+		for v, keys := range options.DimensionAllowLists {
+			dimensionAllowLists[v] = stringSet(keys)
+		}
+	}
+	var defaultDimensionAllowList map[string]bool
+	if len(options.DefaultDimensionAllowList) > 0 {
+		defaultDimensionAllowList = stringSet(options.DefaultDimensionAllowList)
+	}
+	var blockedDimensionKeys map[string]bool
+	if len(options.BlockedDimensionKeys) > 0 {
+		blockedDimensionKeys = stringSet(options.BlockedDimensionKeys)
+		// Dimension keys are sanitized before blocked dimensions are
+		// dropped.
+		for _, k := range options.BlockedDimensionKeys {
+			blockedDimensionKeys[filterKeyChars(k)] = true
+		}
+	}
+
+	var metadataProperties []MetricMetadataField
+	if options.IncludeMetricDescription {
+		metadataProperties = append(metadataProperties, MetricMetadataFieldDescription)
+	}
+	for _, field := range options.MetricMetadataProperties {
+		if !metricMetadataFieldIn(field, metadataProperties) {
+			metadataProperties = append(metadataProperties, field)
+		}
+	}
+
+	var accumulator *cumulativeAccumulator
+	if options.DeltaToCumulative {
+		ttl := options.DeltaToCumulativeTTL
+		if ttl == 0 {
+			ttl = defaultDeltaToCumulativeTTL
+		}
+		maxSeries := options.DeltaToCumulativeMaxSeries
+		if maxSeries == 0 {
+			maxSeries = defaultDeltaToCumulativeMaxSeries
+		}
+		accumulator = newCumulativeAccumulator(int64(ttl/time.Second), maxSeries)
+	}
+
+	var dropEvents *dropEventAggregator
+	if options.EmitDropEvents {
+		interval := options.DropEventsInterval
+		if interval == 0 {
+			interval = defaultDropEventsInterval
+		}
+		maxEvents := options.MaxDropEventsPerInterval
+		if maxEvents == 0 {
+			maxEvents = defaultMaxDropEventsPerInterval
+		}
+		dropEvents = newDropEventAggregator(interval, maxEvents)
+	}
+
+	return &MetricsConverter{
+		logger:                    logger,
+		metricTranslator:          t,
+		options:                   options,
+		propertyAttributes:        propertyAttributes,
+		metricTypeOverrides:       metricTypeOverrides,
+		dimensionAllowLists:       dimensionAllowLists,
+		defaultDimensionAllowList: defaultDimensionAllowList,
+		blockedDimensionKeys:      blockedDimensionKeys,
+		metadataProperties:        metadataProperties,
+		cumulativeAccumulator:     accumulator,
+		dropEvents:                dropEvents,
+	}, nil
+}
+
+func metricMetadataFieldIn(field MetricMetadataField, fields []MetricMetadataField) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// stringSet returns a set of the passed in strings.
+func stringSet(strs []string) map[string]bool {
+	set := make(map[string]bool, len(strs))
+	for _, s := range strs {
+		set[s] = true
+	}
+	return set
+}
+
+// validate returns an error for invalid option values. It is called by
+// NewMetricsConverter and MetricsConverterConfig.Options.
+func (o MetricsConverterOptions) validate() error {
+	switch o.ArrayAttributeRendering {
+	case "", ArrayAttributeRenderingJSON, ArrayAttributeRenderingFirst, ArrayAttributeRenderingJoin:
+	default:
+		return fmt.Errorf("invalid array attribute rendering: %q", o.ArrayAttributeRendering)
+	}
+	switch o.BooleanDimensionEncoding {
+	case "", BooleanDimensionEncodingTrueFalse, BooleanDimensionEncodingOneZero:
+	default:
+		return fmt.Errorf("invalid boolean dimension encoding: %q", o.BooleanDimensionEncoding)
+	}
+	switch o.DimensionPriority {
+	case "", DimensionPriorityResource, DimensionPriorityLabels:
+	default:
+		return fmt.Errorf("invalid dimension priority: %q", o.DimensionPriority)
+	}
+	switch o.DimensionOrder {
+	case "", DimensionOrderResourceFirst, DimensionOrderLabelsFirst:
+	default:
+		return fmt.Errorf("invalid dimension order: %q", o.DimensionOrder)
+	}
+	switch o.TimestampResolution {
+	case "", TimestampResolutionMillis, TimestampResolutionMicros, TimestampResolutionNanos:
+	default:
+		return fmt.Errorf("invalid timestamp resolution: %q", o.TimestampResolution)
+	}
+	switch o.NonMonotonicSumAs {
+	case "", NonMonotonicSumAsGauge, NonMonotonicSumAsCounter:
+	default:
+		return fmt.Errorf("invalid non-monotonic sum conversion: %q", o.NonMonotonicSumAs)
+	}
+	if o.DeltaToCumulativeTTL < 0 || (o.DeltaToCumulativeTTL > 0 && o.DeltaToCumulativeTTL < time.Second) {
+		return fmt.Errorf("invalid delta to cumulative ttl: %v", o.DeltaToCumulativeTTL)
+	}
+	if o.DeltaToCumulativeMaxSeries < 0 {
+		return fmt.Errorf("invalid delta to cumulative max series: %d", o.DeltaToCumulativeMaxSeries)
+	}
+	if o.DropEventsInterval < 0 {
+		return fmt.Errorf("invalid drop events interval: %v", o.DropEventsInterval)
+	}
+	if o.MaxDropEventsPerInterval < 0 {
+		return fmt.Errorf("invalid max drop events per interval: %d", o.MaxDropEventsPerInterval)
+	}
+	for _, q := range o.EmitQuantileEstimates {
+		if !(q >= 0 && q <= 1) {
+			return fmt.Errorf("invalid quantile estimate: %v", q)
+		}
+	}
+	if o.DoubleValuePrecision < 0 {
+		return fmt.Errorf("invalid double value precision: %d", o.DoubleValuePrecision)
+	}
+	if o.MaxHistogramBuckets < 0 {
+		return fmt.Errorf("invalid max histogram buckets: %d", o.MaxHistogramBuckets)
+	}
+	switch o.HistogramBucketOverflow {
+	case "", HistogramBucketOverflowDrop, HistogramBucketOverflowMerge:
+	default:
+		return fmt.Errorf("invalid histogram bucket overflow: %q", o.HistogramBucketOverflow)
+	}
+	switch o.NilHandling {
+	case "", NilHandlingSkip, NilHandlingWarn, NilHandlingErrorCount:
+	default:
+		return fmt.Errorf("invalid nil handling: %q", o.NilHandling)
+	}
+	if o.BucketBoundPrecision < 0 {
+		return fmt.Errorf("invalid bucket bound precision: %d", o.BucketBoundPrecision)
+	}
+	if o.BucketBoundPrecision > 0 && o.FullPrecisionBucketBounds {
+		return fmt.Errorf("invalid bucket bound precision with full precision bucket bounds: %d", o.BucketBoundPrecision)
+	}
+	for k, valueCase := range o.DimensionValueCase {
+		if valueCase != DimensionValueCaseLower && valueCase != DimensionValueCaseUpper {
+			return fmt.Errorf("invalid dimension value case %q for dimension %q", valueCase, k)
+		}
+	}
+	for _, field := range o.MetricMetadataProperties {
+		if field != MetricMetadataFieldDescription && field != MetricMetadataFieldUnit {
+			return fmt.Errorf("invalid metric metadata property: %q", field)
+		}
+	}
+	if _, err := compileMetricTypeOverrides(o.MetricTypeOverrides); err != nil {
+		return err
+	}
+	return nil
+}
+
+func compileMetricTypeOverrides(overrides map[string]sfxpb.MetricType) ([]metricTypeOverride, error) {
+	if len(overrides) == 0 {
+		return nil, nil
+	}
+	patterns := make([]string, 0, len(overrides))
+	for p := range overrides {
+		patterns = append(patterns, p)
+	}
+	sort.Strings(patterns)
+
+	compiled := make([]metricTypeOverride, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid metric type override pattern %q: %v", p, err)
+		}
+		if _, ok := sfxpb.MetricType_name[int32(overrides[p])]; !ok {
+			return nil, fmt.Errorf("invalid metric type override %d for pattern %q", overrides[p], p)
+		}
+		compiled = append(compiled, metricTypeOverride{pattern: re, metricType: overrides[p]})
+	}
+	return compiled, nil
 }
 
 // MetricDataToSignalFxV2 converts the passed in MetricsData to SFx datapoints,
 // returning those datapoints and the number of time series that had to be
 // dropped because of errors or warnings.
-func (c *MetricsConverter) MetricDataToSignalFxV2(rm pdata.ResourceMetrics) []*sfxpb.DataPoint {
+func (c *MetricsConverter) MetricDataToSignalFxV2(rm pdata.ResourceMetrics) ([]*sfxpb.DataPoint, int) {
+	sfxDatapoints, _, numDropped := c.MetricDataToSignalFxV2WithProperties(rm)
+	return sfxDatapoints, numDropped
+}
+
+// MetricDataToSignalFxV2WithProperties converts the passed in MetricsData to
+// SFx datapoints like MetricDataToSignalFxV2, also returning the properties of
+// the datapoints.
+func (c *MetricsConverter) MetricDataToSignalFxV2WithProperties(rm pdata.ResourceMetrics) ([]*sfxpb.DataPoint, DataPointProperties, int) {
+	limit := c.newBatchLimit()
+	sfxDatapoints, properties, numDropped := c.resourceMetricsToSignalFxV2(rm, limit, nil, c.providedDimensions())
+	sfxDatapoints = c.appendBuildInfo(sfxDatapoints, limit)
+	c.logBatchLimit(limit)
+	return sfxDatapoints, properties, numDropped
+}
+
+// MetricsToSignalFxV2 converts all the resource metrics of the passed in
+// Metrics to SFx datapoints like MetricDataToSignalFxV2, the
+// MaxDatapointsPerBatch option applying to all of them at once.
+func (c *MetricsConverter) MetricsToSignalFxV2(md pdata.Metrics) ([]*sfxpb.DataPoint, int) {
+	return c.metricsToSignalFxV2(md, nil)
+}
+
+// Shutdown stops the background work of the converter, e.g. the eviction of
+// the totals of the DeltaToCumulative option. The converter must not be used
+// afterwards.
+func (c *MetricsConverter) Shutdown() {
+	if c.cumulativeAccumulator != nil {
+		c.cumulativeAccumulator.shutdown()
+	}
+}
+
+// DropEvents returns SignalFx events summarizing the datapoints dropped by the
+// conversion since the last returned events, one per metric name with the
+// number of dropped datapoints per reason as properties. Events are returned at
+// most once per DropEventsInterval, nil is returned in between, if nothing was
+// dropped or if the EmitDropEvents option isn't set.
+func (c *MetricsConverter) DropEvents() []*sfxpb.Event {
+	if c.dropEvents == nil {
+		return nil
+	}
+	events, numOmitted := c.dropEvents.events(c.now())
+	if numOmitted > 0 {
+		c.logger.Debug("too many metrics with dropped datapoints, omitting drop events",
+			zap.Int("max_drop_events_per_interval", c.dropEvents.maxEvents),
+			zap.Int("omitted", numOmitted))
+	}
+	return events
+}
+
+// MetricsToSignalFxV2WithDropStats converts the passed in Metrics to SFx
+// datapoints like MetricsToSignalFxV2, also returning the breakdown per reason
+// of the datapoints dropped by the conversion.
+func (c *MetricsConverter) MetricsToSignalFxV2WithDropStats(md pdata.Metrics) ([]*sfxpb.DataPoint, int, DropStats) {
+	var stats DropStats
+	sfxDatapoints, numDropped := c.metricsToSignalFxV2(md, &stats)
+	return sfxDatapoints, numDropped, stats
+}
+
+// MetricsToSignalFxV2ByToken converts the passed in Metrics to SFx datapoints
+// like MetricsToSignalFxV2, grouping the datapoints by the value of the
+// splunk.SFxAccessTokenLabel resource attribute of their resource so they can
+// be sent with the right token. Datapoints of resources without token are
+// grouped under the empty string, groups without datapoints are omitted.
+// The signalfx exporter doesn't use it, it is meant for library users.
+func (c *MetricsConverter) MetricsToSignalFxV2ByToken(md pdata.Metrics) map[string][]*sfxpb.DataPoint {
+	byToken := make(map[string][]*sfxpb.DataPoint)
+
+	limit := c.newBatchLimit()
+	providedDims := c.providedDimensions()
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		if rm.IsNil() {
+			c.logNil(nil, dropReasonNilMetric, "resource metrics", 1)
+			continue
+		}
+		token := ""
+		if val, ok := rm.Resource().Attributes().Get(splunk.SFxAccessTokenLabel); ok {
+			token = val.StringVal()
+		}
+		dps, _, _ := c.resourceMetricsToSignalFxV2(rm, limit, nil, providedDims)
+		if len(dps) > 0 {
+			byToken[token] = append(byToken[token], dps...)
+		}
+	}
+	// The build info doesn't belong to any resource, send it with the
+	// default token.
+	if dps := c.appendBuildInfo(nil, limit); len(dps) > 0 {
+		byToken[""] = append(byToken[""], dps...)
+	}
+	c.logBatchLimit(limit)
+	return byToken
+}
+
+func (c *MetricsConverter) metricsToSignalFxV2(md pdata.Metrics, stats *DropStats) ([]*sfxpb.DataPoint, int) {
+	var sfxDatapoints []*sfxpb.DataPoint
+	numDropped := 0
+
+	limit := c.newBatchLimit()
+	providedDims := c.providedDimensions()
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		if rm.IsNil() {
+			numDropped += c.logNil(stats, dropReasonNilMetric, "resource metrics", 1)
+			continue
+		}
+		dps, _, dropped := c.resourceMetricsToSignalFxV2(rm, limit, stats, providedDims)
+		sfxDatapoints = append(sfxDatapoints, dps...)
+		numDropped += dropped
+	}
+	sfxDatapoints = c.appendBuildInfo(sfxDatapoints, limit)
+	c.logBatchLimit(limit)
+	return sfxDatapoints, numDropped
+}
+
+func (c *MetricsConverter) resourceMetricsToSignalFxV2(rm pdata.ResourceMetrics, limit *batchLimit, stats *DropStats,
+	providedDims []sfxpb.Dimension) ([]*sfxpb.DataPoint, DataPointProperties, int) {
 	var sfxDatapoints []*sfxpb.DataPoint
+	var properties DataPointProperties
+	numDropped := 0
 
 	res := rm.Resource()
 
 	var extraDimensions []*sfxpb.Dimension
 	resourceAttribs := res.Attributes()
-	extraDimensions = resourceAttributesToDimensions(resourceAttribs)
+	extraDimensions = c.resourceAttributesToDimensions(resourceAttribs)
+	// Only the dimensions of the resource identify it for the heartbeat.
+	numResourceDimensions := len(extraDimensions)
+	if c.options.CollectorInstanceID != "" {
+		extraDimensions = append(extraDimensions, &sfxpb.Dimension{
+			Key:   collectorIDDimensionKey,
+			Value: c.options.CollectorInstanceID,
+		})
+	}
+	for i := range providedDims {
+		if !hasDimensionKey(extraDimensions, providedDims[i].Key) {
+			// Copy the dimension since keys are sanitized in place.
+			d := providedDims[i]
+			extraDimensions = append(extraDimensions, &d)
+		}
+	}
+	resourceProperties := c.resourceAttributesToProperties(resourceAttribs)
+	namespace := c.metricNamespace(resourceAttribs)
+
+	var dimBuf *dimensionBuffer
+	if c.options.PoolDimensionBuffers {
+		dimBuf = dimensionBufferPool.Get().(*dimensionBuffer)
+		defer dimensionBufferPool.Put(dimBuf)
+	}
+
+	var kindSummary MetricKindSummary
+	if c.options.Observer != nil {
+		kindSummary = make(MetricKindSummary)
+	}
+
+	var startTimestamps map[*sfxpb.DataPoint]int64
+	if c.options.StartTimestampProperty {
+		startTimestamps = make(map[*sfxpb.DataPoint]int64)
+	}
+
+	var statsTimestamp int64
+	if c.options.EmitConversionStats {
+		statsTimestamp = c.timestampToSignalFx(pdata.TimestampUnixNano(c.now().UnixNano()))
+	}
+
+	metricTypes := make(map[string]sfxpb.MetricType)
 
 	for j := 0; j < rm.InstrumentationLibraryMetrics().Len(); j++ {
 		ilm := rm.InstrumentationLibraryMetrics().At(j)
 		if ilm.IsNil() {
+			numDropped += c.logNil(stats, dropReasonNilMetric, "instrumentation library metrics", 1)
 			continue
 		}
 		for k := 0; k < ilm.Metrics().Len(); k++ {
 			m := ilm.Metrics().At(k)
 			if m.IsNil() {
+				numDropped += c.logNil(stats, dropReasonNilMetric, "metrics", 1)
+				continue
+			}
+
+			if kindSummary != nil {
+				kindSummary[metricKindOf(m)]++
+			}
+
+			// Only read the clock if an observer wants the timings.
+			var start time.Time
+			if c.options.Observer != nil {
+				start = time.Now()
+			}
+
+			dps, dropped := c.metricToSfxDataPoints(m, namespace, extraDimensions, dimBuf, stats, startTimestamps)
+
+			var duration time.Duration
+			if c.options.Observer != nil {
+				duration = time.Since(start)
+			}
+
+			if firstType, metricType, ok := c.conflictingMetricType(m, metricTypes); ok {
+				fields := []zap.Field{
+					zap.String("metric_type", metricType.String()),
+					zap.String("first_metric_type", firstType.String()),
+				}
+				if c.options.DropConflictingMetricTypes {
+					c.logDroppedDataPoints(stats, zapcore.WarnLevel, "metric name has conflicting types, dropping datapoints",
+						m.Name(), dropReasonTypeConflict, len(dps), fields...)
+					numDropped += dropped + len(dps)
+					c.notifyMetricConverted(m.Name(), duration, 0)
+					continue
+				}
+				c.logger.Warn("metric name has conflicting types", append([]zap.Field{zap.String("metric", m.Name())}, fields...)...)
+			}
+
+			// The conversion stats datapoint isn't reported to the observer.
+			numConverted := len(dps)
+			if c.options.EmitConversionStats && len(dps) > 0 && m.Name() != conversionStatsMetricName {
+				dps = append(dps, makeConversionStatsDataPoint(m.Name(), len(dps), extraDimensions, statsTimestamp))
+			}
+
+			if !limit.admit(len(dps)) {
+				stats.add(dropReasonBatchLimit, len(dps))
+				c.dropEvents.record(m.Name(), dropReasonBatchLimit, len(dps))
+				numDropped += dropped + len(dps)
+				c.notifyMetricConverted(m.Name(), duration, 0)
 				continue
 			}
 
-			dps := c.metricToSfxDataPoints(m, extraDimensions)
+			metricProperties := c.metricProperties(m)
+			for i, dp := range dps {
+				props := resourceProperties
+				// Metric properties are the same for all datapoints, only
+				// the first one carries them.
+				if i == 0 && len(metricProperties) > 0 {
+					props = append(props[:len(props):len(props)], metricProperties...)
+				}
+				if start, ok := startTimestamps[dp]; ok {
+					props = append(props[:len(props):len(props)], &sfxpb.Property{
+						Key:   startTimestampPropertyKey,
+						Value: &sfxpb.PropertyValue{IntValue: &start},
+					})
+				}
+				if len(props) == 0 {
+					continue
+				}
+				if properties == nil {
+					properties = make(DataPointProperties)
+				}
+				properties[dp] = props
+			}
 
 			sfxDatapoints = append(sfxDatapoints, dps...)
+			numDropped += dropped
+			c.notifyMetricConverted(m.Name(), duration, numConverted)
+		}
+	}
+
+	if c.options.EmitHeartbeat && numResourceDimensions > 0 && limit.admit(1) {
+		now := c.timestampToSignalFx(pdata.TimestampUnixNano(c.now().UnixNano()))
+		sfxDatapoints = append(sfxDatapoints, makeHeartbeatDataPoint(extraDimensions, now))
+	}
+
+	c.finalizeDataPointDimensions(sfxDatapoints, extraDimensions)
+
+	if c.options.DedupLatestGauge {
+		sfxDatapoints = dedupLatestGauges(sfxDatapoints, properties)
+	}
+
+	if c.options.Observer != nil {
+		c.options.Observer.OnMetricKindSummary(kindSummary)
+	}
+	return sfxDatapoints, properties, numDropped
+}
+
+// notifyMetricConverted notifies the Observer, if any, about the conversion of
+// a metric.
+func (c *MetricsConverter) notifyMetricConverted(metric string, duration time.Duration, datapoints int) {
+	if c.options.Observer != nil {
+		c.options.Observer.OnMetricConverted(metric, duration, datapoints)
+	}
+}
+
+// conflictingMetricType returns the SignalFx type of a metric and the type of
+// the first metric of the same name recorded in types, and whether they
+// differ. The type of the metric is recorded otherwise. Metrics without
+// SignalFx type, e.g. without data type, are never in conflict.
+func (c *MetricsConverter) conflictingMetricType(m pdata.Metric, types map[string]sfxpb.MetricType) (sfxpb.MetricType, sfxpb.MetricType, bool) {
+	typ := c.metricType(m)
+	if typ == nil {
+		return 0, 0, false
+	}
+	metricType := *typ
+	firstType, ok := types[m.Name()]
+	if !ok {
+		types[m.Name()] = metricType
+		return metricType, metricType, false
+	}
+	return firstType, metricType, firstType != metricType
+}
+
+// metricNamespace returns the prefix of the names of the metrics of a resource
+// with the given attributes according to the MetricNamespaceAttributes
+// option, including the trailing delimiter, or an empty string.
+func (c *MetricsConverter) metricNamespace(attrs pdata.AttributeMap) string {
+	if len(c.options.MetricNamespaceAttributes) == 0 {
+		return ""
+	}
+	delim := c.options.MetricNamespaceDelimiter
+	if delim == "" {
+		delim = defaultMetricNamespaceDelimiter
+	}
+	var sb strings.Builder
+	for _, key := range c.options.MetricNamespaceAttributes {
+		val, ok := attrs.Get(key)
+		if !ok {
+			continue
+		}
+		if s := c.attributeValueToDimValue(val); s != "" {
+			sb.WriteString(s)
+			sb.WriteString(delim)
+		}
+	}
+	return sb.String()
+}
+
+// providedDimensions returns a copy of the dimensions of the DimensionProvider
+// option, if any.
+func (c *MetricsConverter) providedDimensions() []sfxpb.Dimension {
+	if c.options.DimensionProvider == nil {
+		return nil
+	}
+	provided := c.options.DimensionProvider.Dimensions()
+	dims := make([]sfxpb.Dimension, 0, len(provided))
+	for _, d := range provided {
+		if d != nil {
+			dims = append(dims, sfxpb.Dimension{Key: d.Key, Value: d.Value})
+		}
+	}
+	return dims
+}
+
+// appendBuildInfo appends the build info datapoint to dps with the
+// EmitBuildInfo option if the batch limit admits it.
+func (c *MetricsConverter) appendBuildInfo(dps []*sfxpb.DataPoint, limit *batchLimit) []*sfxpb.DataPoint {
+	if c.options.EmitBuildInfo == "" || !limit.admit(1) {
+		return dps
+	}
+	dims := []*sfxpb.Dimension{{Key: buildInfoVersionDimensionKey, Value: c.options.EmitBuildInfo}}
+	if c.options.CollectorInstanceID != "" {
+		dims = append(dims, &sfxpb.Dimension{Key: collectorIDDimensionKey, Value: c.options.CollectorInstanceID})
+	}
+	val := int64(1)
+	dp := &sfxpb.DataPoint{
+		Metric:     buildInfoMetricName,
+		Timestamp:  c.timestampToSignalFx(pdata.TimestampUnixNano(c.now().UnixNano())),
+		Value:      sfxpb.Datum{IntValue: &val},
+		MetricType: &sfxMetricTypeGauge,
+		Dimensions: dims,
+	}
+	c.finalizeDataPointDimensions([]*sfxpb.DataPoint{dp}, nil)
+	return append(dps, dp)
+}
+
+// batchLimit tracks the datapoints that can still be emitted by a conversion
+// call according to the MaxDatapointsPerBatch option. A nil batchLimit admits
+// all datapoints.
+type batchLimit struct {
+	max       int
+	remaining int
+	reached   bool
+	dropped   int
+}
+
+func (c *MetricsConverter) newBatchLimit() *batchLimit {
+	if c.options.MaxDatapointsPerBatch <= 0 {
+		return nil
+	}
+	return &batchLimit{
+		max:       c.options.MaxDatapointsPerBatch,
+		remaining: c.options.MaxDatapointsPerBatch,
+	}
+}
+
+// admit returns true if n more datapoints can be emitted. Once some
+// datapoints are refused no more are admitted, so that the datapoints kept
+// only depend on the order of the metrics.
+func (l *batchLimit) admit(n int) bool {
+	if l == nil {
+		return true
+	}
+	if l.reached || n > l.remaining {
+		l.reached = true
+		l.dropped += n
+		return false
+	}
+	l.remaining -= n
+	return true
+}
+
+// logBatchLimit logs the datapoints dropped because of the
+// MaxDatapointsPerBatch option, if any.
+func (c *MetricsConverter) logBatchLimit(l *batchLimit) {
+	if l == nil || !l.reached {
+		return
+	}
+	c.logger.Warn("datapoints per batch limit reached, dropping datapoints",
+		zap.Int("max_datapoints_per_batch", l.max),
+		zap.Int("datapoints", l.dropped))
+}
+
+// ConvertMetric converts a single metric to SFx datapoints, adding extraDims
+// to the dimensions of all of them and applying translation rules. Like for
+// MetricDataToSignalFxV2, dimension keys of the returned datapoints are
+// sanitized, this includes the keys of extraDims which are updated in place.
+func (c *MetricsConverter) ConvertMetric(m pdata.Metric, extraDims []*sfxpb.Dimension) []*sfxpb.DataPoint {
+	dps, _ := c.metricToSfxDataPoints(m, "", extraDims, nil, nil, nil)
+	c.finalizeDataPointDimensions(dps, extraDims)
+	return dps
+}
+
+// DatapointCountEstimate returns an estimate of the number of SignalFx
+// datapoints that the conversion of the passed in Metrics produces, e.g. to
+// presize buffers for the converted datapoints. It accounts for the expansion
+// of histograms into count, sum, bucket and quantile estimate datapoints and
+// for the heartbeat, build info and conversion stats datapoints. It is too high
+// if the conversion drops datapoints depending on their dimensions or on
+// state, e.g. with DedupLatestGauge, MaxStaleness or DeltaToCumulative, or if
+// all the attributes of a resource counted for a heartbeat are filtered out.
+// MaxDatapointsPerBatch caps it. It is too low if translation rules add
+// datapoints, e.g. copy_metrics or the reset zeros of delta_metric, which are
+// not counted. The signalfx exporter doesn't use it, it is meant for library
+// users.
+func (c *MetricsConverter) DatapointCountEstimate(md pdata.Metrics) int {
+	count := 0
+	rms := md.ResourceMetrics()
+	for i := 0; i < rms.Len(); i++ {
+		rm := rms.At(i)
+		if rm.IsNil() {
+			continue
+		}
+		// Heartbeats are only emitted for resources with dimensions, assume
+		// that any resource attribute yields one.
+		if c.options.EmitHeartbeat && (c.options.RequireServiceName || rm.Resource().Attributes().Len() > 0) {
+			count++
+		}
+		var metricTypes map[string]sfxpb.MetricType
+		if c.options.DropConflictingMetricTypes {
+			metricTypes = make(map[string]sfxpb.MetricType)
+		}
+		for j := 0; j < rm.InstrumentationLibraryMetrics().Len(); j++ {
+			ilm := rm.InstrumentationLibraryMetrics().At(j)
+			if ilm.IsNil() {
+				continue
+			}
+			for k := 0; k < ilm.Metrics().Len(); k++ {
+				m := ilm.Metrics().At(k)
+				if m.IsNil() {
+					continue
+				}
+				if metricTypes != nil {
+					if _, _, conflict := c.conflictingMetricType(m, metricTypes); conflict {
+						continue
+					}
+				}
+				count += c.estimateMetricDatapointCount(m)
+			}
+		}
+	}
+	if c.options.EmitBuildInfo != "" {
+		count++
+	}
+	if maxDps := c.options.MaxDatapointsPerBatch; maxDps > 0 && count > maxDps {
+		return maxDps
+	}
+	return count
+}
+
+// makeHeartbeatDataPoint returns the heartbeat datapoint of a resource with
+// the passed in dimensions and timestamp.
+func makeHeartbeatDataPoint(resourceDims []*sfxpb.Dimension, ts int64) *sfxpb.DataPoint {
+	dims := make([]*sfxpb.Dimension, len(resourceDims))
+	copy(dims, resourceDims)
+	val := int64(1)
+	return &sfxpb.DataPoint{
+		Metric:     heartbeatMetricName,
+		Timestamp:  ts,
+		Value:      sfxpb.Datum{IntValue: &val},
+		MetricType: &sfxMetricTypeGauge,
+		Dimensions: dims,
+	}
+}
+
+// makeConversionStatsDataPoint returns the conversion stats datapoint of a
+// metric converted to count datapoints with the passed in resource dimensions
+// and timestamp.
+func makeConversionStatsDataPoint(metric string, count int, resourceDims []*sfxpb.Dimension, ts int64) *sfxpb.DataPoint {
+	dims := make([]*sfxpb.Dimension, len(resourceDims), len(resourceDims)+1)
+	copy(dims, resourceDims)
+	dims = append(dims, &sfxpb.Dimension{Key: sourceMetricDimensionKey, Value: metric})
+	val := int64(count)
+	return &sfxpb.DataPoint{
+		Metric:     conversionStatsMetricName,
+		Timestamp:  ts,
+		Value:      sfxpb.Datum{IntValue: &val},
+		MetricType: &sfxMetricTypeGauge,
+		Dimensions: dims,
+	}
+}
+
+func (c *MetricsConverter) estimateMetricDatapointCount(metric pdata.Metric) int {
+	if !c.metricTypeEnabled(metric.DataType()) {
+		return 0
+	}
+	dropZero := c.dropZeroValues(c.metricType(metric))
+	count := 0
+	switch metric.DataType() {
+	case pdata.MetricDataTypeIntGauge:
+		count = countIntDatapoints(metric.IntGauge().DataPoints(), dropZero)
+	case pdata.MetricDataTypeIntSum:
+		count = countIntDatapoints(metric.IntSum().DataPoints(), dropZero)
+	case pdata.MetricDataTypeDoubleGauge:
+		count = countDoubleDatapoints(metric.DoubleGauge().DataPoints(), dropZero)
+	case pdata.MetricDataTypeDoubleSum:
+		count = countDoubleDatapoints(metric.DoubleSum().DataPoints(), dropZero)
+	case pdata.MetricDataTypeIntHistogram:
+		histDPs := metric.IntHistogram().DataPoints()
+		for i := 0; i < histDPs.Len(); i++ {
+			histDP := histDPs.At(i)
+			if histDP.IsNil() || c.omitHistogram(histDP.Count()) {
+				continue
+			}
+			// count and sum datapoints plus one datapoint per bucket.
+			count += c.histogramSummaryCount() + c.histogramBucketCount(histDP.ExplicitBounds(), histDP.BucketCounts()) +
+				c.quantileEstimateCount(histDP.ExplicitBounds(), histDP.BucketCounts())
+		}
+	case pdata.MetricDataTypeDoubleHistogram:
+		histDPs := metric.DoubleHistogram().DataPoints()
+		for i := 0; i < histDPs.Len(); i++ {
+			histDP := histDPs.At(i)
+			if histDP.IsNil() || c.omitHistogram(histDP.Count()) {
+				continue
+			}
+			count += c.histogramSummaryCount() + c.histogramBucketCount(histDP.ExplicitBounds(), histDP.BucketCounts()) +
+				c.quantileEstimateCount(histDP.ExplicitBounds(), histDP.BucketCounts())
+		}
+	}
+	if c.options.EmitConversionStats && count > 0 && metric.Name() != conversionStatsMetricName {
+		count++
+	}
+	return count
+}
+
+// omitHistogram returns whether the datapoints of a histogram datapoint with
+// the given count are skipped.
+func (c *MetricsConverter) omitHistogram(count uint64) bool {
+	return c.options.OmitEmptyHistograms && count == 0
+}
+
+// histogramSummaryCount returns the number of count and sum datapoints emitted
+// for each histogram datapoint.
+func (c *MetricsConverter) histogramSummaryCount() int {
+	if c.options.OmitHistogramSum {
+		return 1
+	}
+	return 2
+}
+
+func countIntDatapoints(in pdata.IntDataPointSlice, dropZero bool) int {
+	count := 0
+	for i := 0; i < in.Len(); i++ {
+		inDp := in.At(i)
+		if inDp.IsNil() || (dropZero && inDp.Value() == 0) {
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+func countDoubleDatapoints(in pdata.DoubleDataPointSlice, dropZero bool) int {
+	count := 0
+	for i := 0; i < in.Len(); i++ {
+		inDp := in.At(i)
+		if inDp.IsNil() || (dropZero && inDp.Value() == 0) {
+			continue
+		}
+		count++
+	}
+	return count
+}
+
+// histogramBucketCount returns the number of bucket datapoints produced for a
+// histogram datapoint with the given bounds and bucket counts.
+func (c *MetricsConverter) histogramBucketCount(bounds []float64, counts []uint64) int {
+	if len(counts) != len(bounds)+1 || hasNaNBound(bounds) || !boundsStrictlyIncreasing(bounds) {
+		return 0
+	}
+	if maxBuckets := c.options.MaxHistogramBuckets; maxBuckets > 0 && len(counts) > maxBuckets {
+		if c.options.HistogramBucketOverflow != HistogramBucketOverflowMerge {
+			return 0
+		}
+		_, counts = mergeHistogramBuckets(bounds, counts, maxBuckets)
+	}
+	if !c.options.SanitizeBucketCounts && !c.options.LargeBucketCountsAsDouble && negativeBucketCountIndex(counts) >= 0 {
+		return 0
+	}
+	return len(counts)
+}
+
+// quantileEstimateCount returns the number of quantile estimate datapoints
+// produced for a histogram datapoint with the given bounds and bucket counts.
+func (c *MetricsConverter) quantileEstimateCount(bounds []float64, counts []uint64) int {
+	if len(c.options.EmitQuantileEstimates) == 0 || len(bounds) == 0 || len(counts) != len(bounds)+1 ||
+		hasNaNBound(bounds) || !boundsStrictlyIncreasing(bounds) {
+		return 0
+	}
+	for _, count := range counts {
+		if count > 0 {
+			return len(c.options.EmitQuantileEstimates)
+		}
+	}
+	return 0
+}
+
+// mergeHistogramBuckets merges runs of adjacent buckets of the same size so
+// that there are at most maxBuckets buckets, returning the merged bounds and
+// counts. The upper bound of a merged bucket is the one of its last bucket.
+func mergeHistogramBuckets(bounds []float64, counts []uint64, maxBuckets int) ([]float64, []uint64) {
+	size := (len(counts) + maxBuckets - 1) / maxBuckets
+	mergedCounts := make([]uint64, 0, (len(counts)+size-1)/size)
+	mergedBounds := make([]float64, 0, cap(mergedCounts)-1)
+	for start := 0; start < len(counts); start += size {
+		end := start + size
+		if end > len(counts) {
+			end = len(counts)
+		}
+		var sum uint64
+		for _, count := range counts[start:end] {
+			sum += count
+		}
+		mergedCounts = append(mergedCounts, sum)
+		// The last run holds the infinity bucket which has no bound.
+		if end <= len(bounds) {
+			mergedBounds = append(mergedBounds, bounds[end-1])
 		}
 	}
-	sanitizeDataPointDimensions(sfxDatapoints)
-	return sfxDatapoints
+	return mergedBounds, mergedCounts
 }
 
-func (c *MetricsConverter) metricToSfxDataPoints(metric pdata.Metric, extraDimensions []*sfxpb.Dimension) []*sfxpb.DataPoint {
+// metricToSfxDataPoints converts the metric to SFx datapoints, recording the
+// start timestamps of counter datapoints in startTimestamps if not nil.
+func (c *MetricsConverter) metricToSfxDataPoints(metric pdata.Metric, namespace string, extraDimensions []*sfxpb.Dimension, dimBuf *dimensionBuffer,
+	stats *DropStats, startTimestamps map[*sfxpb.DataPoint]int64) ([]*sfxpb.DataPoint, int) {
 	// TODO: Figure out some efficient way to know how many datapoints there
 	// will be in the given metric.
 	var dps []*sfxpb.DataPoint
+	numDropped := 0
+	numNil := 0
+
+	if !c.metricTypeEnabled(metric.DataType()) {
+		return nil, 0
+	}
+
+	basePoint := c.makeBaseDataPoint(metric)
+	if c.options.MetricNameDelimiter != "" {
+		extraDimensions = c.splitMetricName(basePoint, extraDimensions)
+	}
+	if c.options.AnnotateOTLPType {
+		extraDimensions = appendOTLPTypeDimension(extraDimensions, metric.DataType())
+	}
+	if namespace != "" {
+		basePoint.Metric = namespace + basePoint.Metric
+	}
+	if c.options.SanitizeMetricNames {
+		basePoint.Metric = filterMetricNameChars(basePoint.Metric)
+	}
 
-	basePoint := makeBaseDataPoint(metric)
+	if !isCounter(basePoint.MetricType) && !isCumulativeCounter(basePoint.MetricType) {
+		startTimestamps = nil
+	}
+
+	if c.options.InferCounterFromName && isGaugeNamedAsCounter(metric) && isCumulativeCounter(basePoint.MetricType) {
+		c.logger.Debug("gauge is named like a counter, converting it to a cumulative counter",
+			zap.String("metric", basePoint.Metric))
+	}
 
 	switch metric.DataType() {
 	case pdata.MetricDataTypeNone:
-		return nil
+		if c.options.DefaultMetricTypeForNone != nil {
+			// pdata keeps no datapoints without data type, there is
+			// nothing to read.
+			c.logger.Debug("metric without data type has no datapoints",
+				zap.String("metric", basePoint.Metric),
+				zap.String("metric_type", basePoint.MetricType.String()))
+			return nil, 0
+		}
+		// The metric was built without a data type, likely a bug in the
+		// receiver or processor that produced it.
+		c.logDroppedDataPoints(stats, zapcore.WarnLevel, "dropping metric without data type",
+			basePoint.Metric, dropReasonNoDataType, 1)
+		return nil, 1
 	case pdata.MetricDataTypeIntGauge:
-		dps = convertIntDatapoints(metric.IntGauge().DataPoints(), basePoint, extraDimensions)
+		dps, numDropped, numNil = c.convertIntDatapoints(metric.IntGauge().DataPoints(), basePoint, extraDimensions, dimBuf, nil)
 	case pdata.MetricDataTypeIntSum:
-		dps = convertIntDatapoints(metric.IntSum().DataPoints(), basePoint, extraDimensions)
+		dps, numDropped, numNil = c.convertIntDatapoints(metric.IntSum().DataPoints(), basePoint, extraDimensions, dimBuf, startTimestamps)
 	case pdata.MetricDataTypeDoubleGauge:
-		dps = convertDoubleDatapoints(metric.DoubleGauge().DataPoints(), basePoint, extraDimensions)
+		dps, numDropped, numNil = c.convertDoubleDatapoints(metric.DoubleGauge().DataPoints(), basePoint, extraDimensions, dimBuf, nil)
 	case pdata.MetricDataTypeDoubleSum:
-		dps = convertDoubleDatapoints(metric.DoubleSum().DataPoints(), basePoint, extraDimensions)
+		dps, numDropped, numNil = c.convertDoubleDatapoints(metric.DoubleSum().DataPoints(), basePoint, extraDimensions, dimBuf, startTimestamps)
 	case pdata.MetricDataTypeIntHistogram:
-		dps = convertIntHistogram(metric.IntHistogram().DataPoints(), basePoint, extraDimensions)
+		dps = c.convertIntHistogram(metric.IntHistogram().DataPoints(), basePoint, extraDimensions, dimBuf, stats)
 	case pdata.MetricDataTypeDoubleHistogram:
-		dps = convertDoubleHistogram(metric.DoubleHistogram().DataPoints(), basePoint, extraDimensions)
+		dps = c.convertDoubleHistogram(metric.DoubleHistogram().DataPoints(), basePoint, extraDimensions, dimBuf, stats)
+	}
+
+	if numDropped > 0 {
+		c.logDroppedDataPoints(stats, zapcore.DebugLevel, "dropping zero value datapoints",
+			basePoint.Metric, dropReasonZeroValue, numDropped)
+	}
+	if numNil > 0 {
+		numDropped += c.logNil(stats, dropReasonNilDataPoint, "datapoints", numNil, zap.String("metric", basePoint.Metric))
+	}
+
+	if c.options.MaxStaleness > 0 {
+		var numStale int
+		dps, numStale = c.dropStaleDataPoints(dps)
+		if numStale > 0 {
+			c.logDroppedDataPoints(stats, zapcore.DebugLevel, "dropping stale datapoints",
+				basePoint.Metric, dropReasonStale, numStale,
+				zap.Duration("max_staleness", c.options.MaxStaleness))
+		}
+		numDropped += numStale
+	}
+
+	if c.cumulativeAccumulator != nil && isDeltaSum(metric) && isCounter(basePoint.MetricType) {
+		var numOverflow int
+		dps, numOverflow = c.cumulativeAccumulator.accumulate(dps)
+		if numOverflow > 0 {
+			c.logDroppedDataPoints(stats, zapcore.WarnLevel, "too many delta series accumulated, dropping datapoints",
+				basePoint.Metric, dropReasonDeltaSeriesLimit, numOverflow,
+				zap.Int("max_series", c.cumulativeAccumulator.maxSeries))
+		}
+		numDropped += numOverflow
 	}
 
 	if c.metricTranslator != nil {
-		dps = c.metricTranslator.TranslateDataPoints(c.logger, dps)
+		var ok bool
+		dps, ok = c.metricTranslator.translateDataPoints(c.logger, dps)
+		if !ok && c.options.Observer != nil {
+			c.options.Observer.OnTranslationFailure(metric.Name())
+		}
 	}
 
-	return dps
+	return dps, numDropped
+}
+
+// Reasons reported in the "reason" field of the log entries of datapoints
+// dropped during conversion.
+const (
+	dropReasonZeroValue           = "zero_value"
+	dropReasonStale               = "stale"
+	dropReasonNaNBounds           = "nan_bounds"
+	dropReasonUnsortedBounds      = "unsorted_bounds"
+	dropReasonNegativeBucketCount = "negative_bucket_count"
+	dropReasonTooManyBuckets      = "too_many_buckets"
+	dropReasonHistogramMismatch   = "histogram_mismatch"
+	dropReasonNilMetric           = "nil_metric"
+	dropReasonNilDataPoint        = "nil_datapoint"
+	dropReasonBatchLimit          = "batch_limit"
+	dropReasonDeltaSeriesLimit    = "delta_series_limit"
+	dropReasonNoDataType          = "no_data_type"
+	dropReasonTypeConflict        = "type_conflict"
+)
+
+// logDroppedDataPoints logs datapoints of a metric dropped during conversion
+// and records them in stats. All such entries carry the "metric", "reason" and
+// "datapoints" fields so they can be aggregated regardless of the message,
+// fields are appended to them.
+func (c *MetricsConverter) logDroppedDataPoints(stats *DropStats, level zapcore.Level, msg string, metric string, reason string, count int, fields ...zap.Field) {
+	stats.add(reason, count)
+	c.dropEvents.record(metric, reason, count)
+	ce := c.logger.Check(level, msg)
+	if ce == nil {
+		return
+	}
+	ce.Write(append([]zap.Field{
+		zap.String("metric", metric),
+		zap.String("reason", reason),
+		zap.Int("datapoints", count),
+	}, fields...)...)
+}
+
+// logNil reports count nil elements of the given kind skipped by the
+// conversion according to the NilHandling option and records them in stats,
+// returning the number of them counted as dropped datapoints.
+func (c *MetricsConverter) logNil(stats *DropStats, reason string, kind string, count int, fields ...zap.Field) int {
+	stats.add(reason, count)
+	switch c.options.NilHandling {
+	case NilHandlingWarn:
+		c.logger.Warn("skipping nil "+kind, append(fields, zap.Int("count", count))...)
+	case NilHandlingErrorCount:
+		c.logger.Error("skipping nil "+kind, append(fields, zap.Int("count", count))...)
+		return count
+	}
+	return 0
 }
 
-func labelsToDimensions(labels pdata.StringMap, extraDims []*sfxpb.Dimension) []*sfxpb.Dimension {
-	dimensions := make([]*sfxpb.Dimension, len(extraDims), labels.Len()+len(extraDims))
+// now returns the current time according to the Clock option.
+func (c *MetricsConverter) now() time.Time {
+	if c.options.Clock != nil {
+		return c.options.Clock()
+	}
+	return time.Now()
+}
+
+// dropStaleDataPoints removes the datapoints older than the MaxStaleness option
+// in place, returning the remaining datapoints and the number of dropped ones.
+func (c *MetricsConverter) dropStaleDataPoints(dps []*sfxpb.DataPoint) ([]*sfxpb.DataPoint, int) {
+	cutoff := c.timestampToSignalFx(pdata.TimestampUnixNano(c.now().Add(-c.options.MaxStaleness).UnixNano()))
+	kept := dps[:0]
+	for _, dp := range dps {
+		if dp.Timestamp >= cutoff {
+			kept = append(kept, dp)
+		}
+	}
+	return kept, len(dps) - len(kept)
+}
+
+// metricTypeEnabled returns true if metrics of the passed in data type must be
+// converted according to the IncludeMetricTypes and ExcludeMetricTypes options.
+func (c *MetricsConverter) metricTypeEnabled(dataType pdata.MetricDataType) bool {
+	if len(c.options.IncludeMetricTypes) > 0 && !metricDataTypeIn(dataType, c.options.IncludeMetricTypes) {
+		return false
+	}
+	return !metricDataTypeIn(dataType, c.options.ExcludeMetricTypes)
+}
+
+func metricDataTypeIn(dataType pdata.MetricDataType, dataTypes []pdata.MetricDataType) bool {
+	for _, t := range dataTypes {
+		if t == dataType {
+			return true
+		}
+	}
+	return false
+}
+
+// labelsToDimensions returns the extra dimensions followed by the labels as
+// dimensions. The dimensions are taken from dimBuf if it isn't nil.
+func (c *MetricsConverter) labelsToDimensions(labels pdata.StringMap, extraDims []*sfxpb.Dimension, dimBuf *dimensionBuffer) []*sfxpb.Dimension {
+	var dimensions []*sfxpb.Dimension
+	if dimBuf != nil {
+		dimensions = dimBuf.pointers(labels.Len() + len(extraDims))[:len(extraDims)]
+	} else {
+		dimensions = make([]*sfxpb.Dimension, len(extraDims), labels.Len()+len(extraDims))
+	}
 	copy(dimensions, extraDims)
 	if labels.Len() == 0 {
 		return dimensions
 	}
-	dimensionsValue := make([]sfxpb.Dimension, labels.Len())
+	var dimensionsValue []sfxpb.Dimension
+	if dimBuf != nil {
+		dimensionsValue = dimBuf.values(labels.Len())
+	} else {
+		dimensionsValue = make([]sfxpb.Dimension, labels.Len())
+	}
 	pos := 0
 	labels.ForEach(func(k string, v string) {
+		if v == "" && c.options.DropEmptyDimensions {
+			return
+		}
+		if c.isBlockedDimensionKey(k) {
+			return
+		}
+		if c.options.SampleRateLabel != "" && k == c.options.SampleRateLabel {
+			k = sampleRateDimensionKey
+		}
+		if len(c.options.DimensionKeyMapping) > 0 {
+			if k = c.mapDimensionKey(k, dimensions); k == "" {
+				return
+			}
+		}
 		dimensionsValue[pos].Key = k
 		dimensionsValue[pos].Value = v
 		dimensions = append(dimensions, &dimensionsValue[pos])
 		pos++
 	})
+	if c.options.DimensionOrder == DimensionOrderLabelsFirst {
+		rotateDimensions(dimensions, len(extraDims))
+	}
 	return dimensions
 }
 
-func convertIntDatapoints(in pdata.IntDataPointSlice, basePoint *sfxpb.DataPoint, extraDims []*sfxpb.Dimension) []*sfxpb.DataPoint {
-	out := make([]*sfxpb.DataPoint, 0, in.Len())
+// rotateDimensions moves the first n dimensions to the end in place, keeping
+// the order of both parts.
+func rotateDimensions(dims []*sfxpb.Dimension, n int) {
+	if n == 0 || n == len(dims) {
+		return
+	}
+	reverseDimensions(dims[:n])
+	reverseDimensions(dims[n:])
+	reverseDimensions(dims)
+}
+
+func reverseDimensions(dims []*sfxpb.Dimension) {
+	for i, j := 0, len(dims)-1; i < j; i, j = i+1, j-1 {
+		dims[i], dims[j] = dims[j], dims[i]
+	}
+}
+
+// mapDimensionKey returns the key mapped with the DimensionKeyMapping option,
+// or an empty string if the mapped key is already present in dims.
+func (c *MetricsConverter) mapDimensionKey(key string, dims []*sfxpb.Dimension) string {
+	mapped, ok := c.options.DimensionKeyMapping[key]
+	if !ok {
+		mapped = key
+	}
+	if hasDimensionKey(dims, mapped) {
+		c.logger.Debug("dimension key collides with an existing dimension, dropping dimension",
+			zap.String("key", key),
+			zap.String("mapped_key", mapped))
+		return ""
+	}
+	return mapped
+}
+
+// finalizeDataPointDimensions sanitizes the dimensions of converted datapoints,
+// drops the blocked ones and caps the remaining ones to MaxDimensions, in that
+// order so that blocked dimensions don't count against the limit. resourceDims
+// are the dimensions shared by all datapoints, see capDataPointDimensions.
+func (c *MetricsConverter) finalizeDataPointDimensions(dps []*sfxpb.DataPoint, resourceDims []*sfxpb.Dimension) {
+	c.sanitizeDataPointDimensions(dps)
+	c.dropBlockedDimensions(dps)
+	c.capDataPointDimensions(dps, resourceDims)
+}
+
+// dropBlockedDimensions drops the dimensions of the datapoints whose key is
+// listed in the BlockedDimensionKeys option. It runs once dimensions are
+// final, i.e. after translation and sanitization, so that dimensions added by
+// any option or translation rule are dropped too.
+func (c *MetricsConverter) dropBlockedDimensions(dps []*sfxpb.DataPoint) {
+	if len(c.blockedDimensionKeys) == 0 {
+		return
+	}
+	for _, dp := range dps {
+		for i, d := range dp.Dimensions {
+			if !c.blockedDimensionKeys[d.Key] {
+				continue
+			}
+			// Dimension slices can be shared between datapoints, filter
+			// into a new one.
+			kept := make([]*sfxpb.Dimension, i, len(dp.Dimensions)-1)
+			copy(kept, dp.Dimensions[:i])
+			for _, d := range dp.Dimensions[i:] {
+				if !c.isBlockedDimensionKey(d.Key) {
+					kept = append(kept, d)
+				}
+			}
+			dp.Dimensions = kept
+			break
+		}
+	}
+}
+
+// isBlockedDimensionKey returns true, logging it, if the key is listed in the
+// BlockedDimensionKeys option.
+func (c *MetricsConverter) isBlockedDimensionKey(key string) bool {
+	if !c.blockedDimensionKeys[key] {
+		return false
+	}
+	c.logger.Debug("dimension key is blocked, dropping dimension", zap.String("key", key))
+	return true
+}
+
+func hasDimensionKey(dims []*sfxpb.Dimension, key string) bool {
+	for _, d := range dims {
+		if d.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// dimensionBufferChunkSize is the number of dimensions allocated at once by
+// dimensionBuffer.
+const dimensionBufferChunkSize = 256
+
+var dimensionBufferPool = sync.Pool{
+	New: func() interface{} { return &dimensionBuffer{} },
+}
+
+// dimensionBuffer hands out dimensions from chunks allocated at once. Parts of
+// a chunk are never handed out twice, so datapoints can keep the dimensions
+// after the buffer is returned to the pool.
+type dimensionBuffer struct {
+	ptrs []*sfxpb.Dimension
+	vals []sfxpb.Dimension
+}
+
+// pointers returns a slice of n dimension pointers.
+func (b *dimensionBuffer) pointers(n int) []*sfxpb.Dimension {
+	if n > len(b.ptrs) {
+		if n > dimensionBufferChunkSize {
+			return make([]*sfxpb.Dimension, n)
+		}
+		b.ptrs = make([]*sfxpb.Dimension, dimensionBufferChunkSize)
+	}
+	out := b.ptrs[:n:n]
+	b.ptrs = b.ptrs[n:]
+	return out
+}
+
+// values returns a slice of n dimensions.
+func (b *dimensionBuffer) values(n int) []sfxpb.Dimension {
+	if n > len(b.vals) {
+		if n > dimensionBufferChunkSize {
+			return make([]sfxpb.Dimension, n)
+		}
+		b.vals = make([]sfxpb.Dimension, dimensionBufferChunkSize)
+	}
+	out := b.vals[:n:n]
+	b.vals = b.vals[n:]
+	return out
+}
+
+// convertIntDatapoints converts int datapoints, returning the converted
+// datapoints, the number of dropped datapoints and the number of nil
+// datapoints.
+func (c *MetricsConverter) convertIntDatapoints(in pdata.IntDataPointSlice, basePoint *sfxpb.DataPoint, extraDims []*sfxpb.Dimension, dimBuf *dimensionBuffer,
+	startTimestamps map[*sfxpb.DataPoint]int64) ([]*sfxpb.DataPoint, int, int) {
+	var out []*sfxpb.DataPoint
+	if in.Len() > 1 {
+		out = make([]*sfxpb.DataPoint, 0, in.Len())
+	}
+	numDropped := 0
+	numNil := 0
+	dropZero := c.dropZeroValues(basePoint.MetricType)
+	unsignedStrings := c.unsignedCounterStrings(basePoint.MetricType)
+	scale := c.scaleBySampleRate(basePoint.MetricType)
 
 	for i := 0; i < in.Len(); i++ {
 		inDp := in.At(i)
 		if inDp.IsNil() {
+			numNil++
+			continue
+		}
+		if dropZero && inDp.Value() == 0 {
+			numDropped++
 			continue
 		}
 
-		dp := *basePoint
-		dp.Timestamp = timestampToSignalFx(inDp.Timestamp())
-		dp.Dimensions = labelsToDimensions(inDp.LabelsMap(), extraDims)
+		var dp *sfxpb.DataPoint
+		var val *int64
+		if in.Len() == 1 {
+			// Common case of metrics with a single datapoint, allocate the
+			// output slice, the datapoint and its value at once.
+			single := &singleIntDataPoint{}
+			out = single.out[:0]
+			dp, val = &single.dp, &single.val
+		} else {
+			dp, val = new(sfxpb.DataPoint), new(int64)
+		}
 
-		val := inDp.Value()
-		dp.Value.IntValue = &val
+		*dp = *basePoint
+		dp.Timestamp = c.timestampToSignalFx(inDp.Timestamp())
+		dp.Dimensions = c.labelsToDimensions(inDp.LabelsMap(), extraDims, dimBuf)
 
-		out = append(out, &dp)
+		*val = inDp.Value()
+		dp.Value.IntValue = val
+		if c.options.ValueTransformer != nil {
+			c.transformIntValue(dp.Metric, &dp.Value)
+		}
+		if scale {
+			c.applySampleRate(inDp.LabelsMap(), &dp.Value)
+		}
+		if unsignedStrings {
+			c.unsignedCounterString(&dp.Value)
+		}
+		recordStartTimestamp(startTimestamps, dp, inDp.StartTime())
+
+		out = append(out, dp)
 	}
-	return out
+	return out, numDropped, numNil
 }
 
-func convertDoubleDatapoints(in pdata.DoubleDataPointSlice, basePoint *sfxpb.DataPoint, extraDims []*sfxpb.Dimension) []*sfxpb.DataPoint {
-	out := make([]*sfxpb.DataPoint, 0, in.Len())
+// convertDoubleDatapoints converts double datapoints, returning the converted
+// datapoints, the number of dropped datapoints and the number of nil
+// datapoints.
+func (c *MetricsConverter) convertDoubleDatapoints(in pdata.DoubleDataPointSlice, basePoint *sfxpb.DataPoint, extraDims []*sfxpb.Dimension, dimBuf *dimensionBuffer,
+	startTimestamps map[*sfxpb.DataPoint]int64) ([]*sfxpb.DataPoint, int, int) {
+	var out []*sfxpb.DataPoint
+	if in.Len() > 1 {
+		out = make([]*sfxpb.DataPoint, 0, in.Len())
+	}
+	numDropped := 0
+	numNil := 0
+	dropZero := c.dropZeroValues(basePoint.MetricType)
+	scale := c.scaleBySampleRate(basePoint.MetricType)
 
 	for i := 0; i < in.Len(); i++ {
 		inDp := in.At(i)
 		if inDp.IsNil() {
+			numNil++
+			continue
+		}
+		if dropZero && inDp.Value() == 0 {
+			numDropped++
 			continue
 		}
 
-		dp := *basePoint
-		dp.Timestamp = timestampToSignalFx(inDp.Timestamp())
-		dp.Dimensions = labelsToDimensions(inDp.LabelsMap(), extraDims)
+		var dp *sfxpb.DataPoint
+		var val *float64
+		if in.Len() == 1 {
+			// Common case of metrics with a single datapoint, allocate the
+			// output slice, the datapoint and its value at once.
+			single := &singleDoubleDataPoint{}
+			out = single.out[:0]
+			dp, val = &single.dp, &single.val
+		} else {
+			dp, val = new(sfxpb.DataPoint), new(float64)
+		}
 
-		val := inDp.Value()
-		dp.Value.DoubleValue = &val
+		*dp = *basePoint
+		dp.Timestamp = c.timestampToSignalFx(inDp.Timestamp())
+		dp.Dimensions = c.labelsToDimensions(inDp.LabelsMap(), extraDims, dimBuf)
 
-		out = append(out, &dp)
+		*val = inDp.Value()
+		dp.Value.DoubleValue = val
+		if c.options.ValueTransformer != nil {
+			c.transformDoubleValue(dp.Metric, &dp.Value)
+		}
+		if scale {
+			c.applySampleRate(inDp.LabelsMap(), &dp.Value)
+		}
+		if c.options.DoubleValuePrecision > 0 {
+			*dp.Value.DoubleValue = roundDecimals(*dp.Value.DoubleValue, c.options.DoubleValuePrecision)
+		}
+		recordStartTimestamp(startTimestamps, dp, inDp.StartTime())
+
+		out = append(out, dp)
 	}
-	return out
+	return out, numDropped, numNil
+}
+
+// singleIntDataPoint groups the output slice, datapoint and value of the
+// conversion of a single int datapoint so they are allocated at once.
+type singleIntDataPoint struct {
+	out [1]*sfxpb.DataPoint
+	dp  sfxpb.DataPoint
+	val int64
+}
+
+// singleDoubleDataPoint groups the output slice, datapoint and value of the
+// conversion of a single double datapoint so they are allocated at once.
+type singleDoubleDataPoint struct {
+	out [1]*sfxpb.DataPoint
+	dp  sfxpb.DataPoint
+	val float64
+}
+
+// recordStartTimestamp records the start timestamp of the source datapoint of
+// dp in milliseconds in startTimestamps, if not nil. Zero start timestamps are
+// skipped.
+func recordStartTimestamp(startTimestamps map[*sfxpb.DataPoint]int64, dp *sfxpb.DataPoint, start pdata.TimestampUnixNano) {
+	if startTimestamps == nil || start == 0 {
+		return
+	}
+	startTimestamps[dp] = int64(start) / int64(time.Millisecond)
+}
+
+// scaleBySampleRate returns true if values of datapoints of the given type
+// must be scaled by their sample rate.
+func (c *MetricsConverter) scaleBySampleRate(metricType *sfxpb.MetricType) bool {
+	if !c.options.ScaleCountersBySampleRate || c.options.SampleRateLabel == "" {
+		return false
+	}
+	return isCounter(metricType) || isCumulativeCounter(metricType)
+}
+
+// applySampleRate divides the value of the datum by the sample rate held by
+// the SampleRateLabel label, replacing an int value with a double value if the
+// result isn't integral. Missing, unparsable and out of range rates are logged
+// at debug level and leave the value as is.
+func (c *MetricsConverter) applySampleRate(labels pdata.StringMap, datum *sfxpb.Datum) {
+	v, ok := labels.Get(c.options.SampleRateLabel)
+	if !ok {
+		return
+	}
+	rate, err := strconv.ParseFloat(v, 64)
+	if err != nil || !(rate > 0 && rate <= 1) {
+		c.logger.Debug("invalid sample rate, not scaling value", zap.String("sample_rate", v))
+		return
+	}
+	if datum.DoubleValue != nil {
+		*datum.DoubleValue /= rate
+		return
+	}
+	scaled := float64(*datum.IntValue) / rate
+	if scaled == math.Trunc(scaled) && scaled >= math.MinInt64 && scaled < math.MaxInt64 {
+		*datum.IntValue = int64(scaled)
+		return
+	}
+	datum.IntValue = nil
+	datum.DoubleValue = &scaled
+}
+
+// transformIntValue applies the ValueTransformer option to the int value of
+// the datum, replacing it with a double value if the result isn't integral.
+func (c *MetricsConverter) transformIntValue(metric string, datum *sfxpb.Datum) {
+	v := c.options.ValueTransformer(metric, float64(*datum.IntValue))
+	if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+		*datum.IntValue = int64(v)
+		return
+	}
+	datum.IntValue = nil
+	datum.DoubleValue = &v
+}
+
+// transformDoubleValue applies the ValueTransformer option to the double value
+// of the datum.
+func (c *MetricsConverter) transformDoubleValue(metric string, datum *sfxpb.Datum) {
+	*datum.DoubleValue = c.options.ValueTransformer(metric, *datum.DoubleValue)
+}
+
+// unsignedCounterStrings returns true if int values of datapoints of the given
+// type must be checked against the UnsignedCounterStringThreshold option.
+func (c *MetricsConverter) unsignedCounterStrings(metricType *sfxpb.MetricType) bool {
+	if c.options.UnsignedCounterStringThreshold == 0 || metricType == nil {
+		return false
+	}
+	return *metricType == sfxpb.MetricType_COUNTER || *metricType == sfxpb.MetricType_CUMULATIVE_COUNTER
+}
+
+// unsignedCounterString replaces the int value of the datum with a string
+// holding the value read as an unsigned integer if it reaches the
+// UnsignedCounterStringThreshold option.
+func (c *MetricsConverter) unsignedCounterString(datum *sfxpb.Datum) {
+	if datum.IntValue == nil {
+		return
+	}
+	v := uint64(*datum.IntValue)
+	if v < c.options.UnsignedCounterStringThreshold {
+		return
+	}
+	s := strconv.FormatUint(v, 10)
+	datum.IntValue = nil
+	datum.StrValue = &s
+}
+
+// appendOTLPTypeDimension returns a copy of the extra dimensions with the
+// otlp_type dimension of the given data type appended.
+func appendOTLPTypeDimension(extraDims []*sfxpb.Dimension, dataType pdata.MetricDataType) []*sfxpb.Dimension {
+	var value string
+	switch dataType {
+	case pdata.MetricDataTypeIntGauge:
+		value = "int_gauge"
+	case pdata.MetricDataTypeDoubleGauge:
+		value = "double_gauge"
+	case pdata.MetricDataTypeIntSum:
+		value = "int_sum"
+	case pdata.MetricDataTypeDoubleSum:
+		value = "double_sum"
+	case pdata.MetricDataTypeIntHistogram:
+		value = "int_histogram"
+	case pdata.MetricDataTypeDoubleHistogram:
+		value = "double_histogram"
+	default:
+		return extraDims
+	}
+	dims := make([]*sfxpb.Dimension, len(extraDims), len(extraDims)+1)
+	copy(dims, extraDims)
+	return append(dims, &sfxpb.Dimension{Key: otlpTypeDimensionKey, Value: value})
+}
+
+// isGaugeNamedAsCounter returns true if the metric is a gauge whose name ends
+// in counterNameSuffix.
+func isGaugeNamedAsCounter(metric pdata.Metric) bool {
+	switch metric.DataType() {
+	case pdata.MetricDataTypeIntGauge, pdata.MetricDataTypeDoubleGauge:
+		return strings.HasSuffix(metric.Name(), counterNameSuffix)
+	}
+	return false
+}
+
+// isDeltaSum returns true if the metric is a sum with delta temporality.
+func isDeltaSum(metric pdata.Metric) bool {
+	switch metric.DataType() {
+	case pdata.MetricDataTypeIntSum:
+		return metric.IntSum().AggregationTemporality() == pdata.AggregationTemporalityDelta
+	case pdata.MetricDataTypeDoubleSum:
+		return metric.DoubleSum().AggregationTemporality() == pdata.AggregationTemporalityDelta
+	}
+	return false
+}
+
+// isCounter returns true for the COUNTER type.
+func isCounter(metricType *sfxpb.MetricType) bool {
+	return metricType != nil && *metricType == sfxpb.MetricType_COUNTER
+}
+
+// isCumulativeCounter returns true for the CUMULATIVE_COUNTER type.
+func isCumulativeCounter(metricType *sfxpb.MetricType) bool {
+	return metricType != nil && *metricType == sfxpb.MetricType_CUMULATIVE_COUNTER
+}
+
+// roundDecimals rounds v to the given number of decimal places. Values too
+// large to be scaled are returned as is, they have no decimals anyway.
+func roundDecimals(v float64, decimals int) float64 {
+	scale := math.Pow10(decimals)
+	scaled := v * scale
+	if math.IsInf(scaled, 0) {
+		return v
+	}
+	return math.Round(scaled) / scale
+}
+
+// dropZeroValues returns true if datapoints of the given type with a zero value
+// must be dropped.
+func (c *MetricsConverter) dropZeroValues(metricType *sfxpb.MetricType) bool {
+	if !c.options.DropZeroValueCounters || metricType == nil {
+		return false
+	}
+	return *metricType == sfxpb.MetricType_COUNTER || *metricType == sfxpb.MetricType_CUMULATIVE_COUNTER
+}
+
+// splitMetricName sets the metric name of the base point to the last segment
+// of its hierarchical name and returns the extra dimensions with the context
+// dimension built from the prior segments. The extra dimensions are returned
+// as is for names with a single segment.
+func (c *MetricsConverter) splitMetricName(basePoint *sfxpb.DataPoint, extraDims []*sfxpb.Dimension) []*sfxpb.Dimension {
+	delim := c.options.MetricNameDelimiter
+	name := basePoint.Metric
+	idx := strings.LastIndex(name, delim)
+	if idx <= 0 || idx+len(delim) == len(name) {
+		return extraDims
+	}
+	basePoint.Metric = name[idx+len(delim):]
+
+	key := c.options.MetricNameContextDimension
+	if key == "" {
+		key = defaultMetricNameContextDimension
+	}
+	dims := make([]*sfxpb.Dimension, len(extraDims), len(extraDims)+1)
+	copy(dims, extraDims)
+	return append(dims, &sfxpb.Dimension{
+		Key:   key,
+		Value: strings.ReplaceAll(name[:idx], delim, "_"),
+	})
 }
 
-func makeBaseDataPoint(m pdata.Metric) *sfxpb.DataPoint {
+func (c *MetricsConverter) makeBaseDataPoint(m pdata.Metric) *sfxpb.DataPoint {
+	name := m.Name()
+	if c.options.LowercaseMetricNames {
+		name = strings.ToLower(name)
+	}
 	return &sfxpb.DataPoint{
-		Metric:     m.Name(),
-		MetricType: fromMetricDataTypeToMetricType(m),
+		Metric:     name,
+		MetricType: c.metricType(m),
+	}
+}
+
+// metricType returns the SignalFx type of the datapoints of the metric, taking
+// the MetricTypeOverrides, DefaultMetricTypeForNone and InferCounterFromName
+// options into account.
+func (c *MetricsConverter) metricType(m pdata.Metric) *sfxpb.MetricType {
+	for i := range c.metricTypeOverrides {
+		if c.metricTypeOverrides[i].pattern.MatchString(m.Name()) {
+			return &c.metricTypeOverrides[i].metricType
+		}
+	}
+	if m.DataType() == pdata.MetricDataTypeNone && c.options.DefaultMetricTypeForNone != nil {
+		return c.options.DefaultMetricTypeForNone
+	}
+	if c.options.InferCounterFromName && isGaugeNamedAsCounter(m) {
+		return &sfxMetricTypeCumulativeCounter
 	}
+	return fromMetricDataTypeToMetricType(m, c.options.NonMonotonicSumAs)
 }
 
-func fromMetricDataTypeToMetricType(metric pdata.Metric) *sfxpb.MetricType {
+func fromMetricDataTypeToMetricType(metric pdata.Metric, nonMonotonicSumAs NonMonotonicSumAs) *sfxpb.MetricType {
 	switch metric.DataType() {
 
 	case pdata.MetricDataTypeIntGauge:
@@ -199,7 +2515,7 @@ func fromMetricDataTypeToMetricType(metric pdata.Metric) *sfxpb.MetricType {
 		return &sfxMetricTypeGauge
 
 	case pdata.MetricDataTypeIntSum:
-		if !metric.IntSum().IsMonotonic() {
+		if !metric.IntSum().IsMonotonic() && nonMonotonicSumAs != NonMonotonicSumAsCounter {
 			return &sfxMetricTypeGauge
 		}
 		if metric.IntSum().AggregationTemporality() == pdata.AggregationTemporalityDelta {
@@ -208,7 +2524,7 @@ func fromMetricDataTypeToMetricType(metric pdata.Metric) *sfxpb.MetricType {
 		return &sfxMetricTypeCumulativeCounter
 
 	case pdata.MetricDataTypeDoubleSum:
-		if !metric.DoubleSum().IsMonotonic() {
+		if !metric.DoubleSum().IsMonotonic() && nonMonotonicSumAs != NonMonotonicSumAsCounter {
 			return &sfxMetricTypeGauge
 		}
 		if metric.DoubleSum().AggregationTemporality() == pdata.AggregationTemporalityDelta {
@@ -222,41 +2538,203 @@ func fromMetricDataTypeToMetricType(metric pdata.Metric) *sfxpb.MetricType {
 		}
 		return &sfxMetricTypeCumulativeCounter
 
-	case pdata.MetricDataTypeDoubleHistogram:
-		if metric.DoubleHistogram().AggregationTemporality() == pdata.AggregationTemporalityDelta {
-			return &sfxMetricTypeCounter
+	case pdata.MetricDataTypeDoubleHistogram:
+		if metric.DoubleHistogram().AggregationTemporality() == pdata.AggregationTemporalityDelta {
+			return &sfxMetricTypeCounter
+		}
+		return &sfxMetricTypeCumulativeCounter
+	}
+
+	return nil
+}
+
+func (c *MetricsConverter) convertIntHistogram(histDPs pdata.IntHistogramDataPointSlice, basePoint *sfxpb.DataPoint, extraDims []*sfxpb.Dimension, dimBuf *dimensionBuffer, stats *DropStats) []*sfxpb.DataPoint {
+	var out []*sfxpb.DataPoint
+
+	for i := 0; i < histDPs.Len(); i++ {
+		histDP := histDPs.At(i)
+		if histDP.IsNil() || c.omitHistogram(histDP.Count()) {
+			continue
+		}
+
+		ts := c.timestampToSignalFx(histDP.Timestamp())
+
+		countDP := *basePoint
+		countDP.Metric = c.histogramCountMetricName(basePoint.Metric)
+		countDP.Timestamp = ts
+		countDP.Dimensions = c.labelsToDimensions(histDP.LabelsMap(), extraDims, dimBuf)
+		countDP.Value = c.histogramCountValue(basePoint.Metric, histDP.Count())
+
+		out = append(out, &countDP)
+
+		if !c.options.OmitHistogramSum {
+			sumDP := *basePoint
+			sumDP.Metric = c.histogramSumMetricName(basePoint.Metric)
+			sumDP.Timestamp = ts
+			if c.options.HistogramSumAsGauge {
+				sumDP.MetricType = &sfxMetricTypeGauge
+			}
+			sumDP.Dimensions = c.labelsToDimensions(histDP.LabelsMap(), extraDims, dimBuf)
+			sum := histDP.Sum()
+			sumDP.Value.IntValue = &sum
+			out = append(out, &sumDP)
+		}
+
+		bounds := histDP.ExplicitBounds()
+		counts := histDP.BucketCounts()
+
+		// Spec says counts is optional but if present it must have one more
+		// element than the bounds array.
+		if len(counts) > 0 && len(counts) != len(bounds)+1 {
+			stats.add(dropReasonHistogramMismatch, len(counts))
+			continue
+		}
+
+		// NaN bounds would produce unusable "NaN" upper_bound dimensions,
+		// keep count and sum but drop the buckets.
+		if hasNaNBound(bounds) {
+			c.logDroppedDataPoints(stats, zapcore.WarnLevel, "histogram explicit bounds contain NaN, dropping buckets",
+				basePoint.Metric, dropReasonNaNBounds, len(counts))
+			continue
+		}
+
+		// Bucket counts can only be mapped to their bounds if the bounds are
+		// sorted, keep count and sum but drop the buckets otherwise.
+		if !boundsStrictlyIncreasing(bounds) {
+			c.logDroppedDataPoints(stats, zapcore.WarnLevel, "histogram explicit bounds are not strictly increasing, dropping buckets",
+				basePoint.Metric, dropReasonUnsortedBounds, len(counts),
+				zap.Float64s("bounds", bounds))
+			continue
+		}
+
+		if len(c.options.EmitQuantileEstimates) > 0 {
+			out = c.appendQuantileEstimates(out, basePoint, ts, bounds, counts, histDP.LabelsMap(), extraDims, dimBuf)
+		}
+
+		// Each bucket becomes a datapoint, merge or drop the buckets of
+		// histograms with more than MaxHistogramBuckets buckets.
+		if maxBuckets := c.options.MaxHistogramBuckets; maxBuckets > 0 && len(counts) > maxBuckets {
+			if c.options.HistogramBucketOverflow != HistogramBucketOverflowMerge {
+				c.logDroppedDataPoints(stats, zapcore.WarnLevel, "histogram has too many buckets, dropping buckets",
+					basePoint.Metric, dropReasonTooManyBuckets, len(counts),
+					zap.Int("max_histogram_buckets", maxBuckets))
+				continue
+			}
+			c.logger.Warn("histogram has too many buckets, merging buckets",
+				zap.String("metric", basePoint.Metric),
+				zap.Int("buckets", len(counts)),
+				zap.Int("max_histogram_buckets", maxBuckets))
+			bounds, counts = mergeHistogramBuckets(bounds, counts, maxBuckets)
+		}
+
+		// Bucket counts above math.MaxInt64 would become negative once
+		// converted to SignalFx int values, drop the buckets in that case
+		// unless they are sanitized or emitted as doubles.
+		sanitize := false
+		asDouble := false
+		if idx := negativeBucketCountIndex(counts); idx >= 0 && c.options.LargeBucketCountsAsDouble {
+			asDouble = true
+		} else if idx >= 0 {
+			if !c.options.SanitizeBucketCounts {
+				c.logDroppedDataPoints(stats, zapcore.ErrorLevel, "histogram bucket count is negative when converted to int64, dropping buckets",
+					basePoint.Metric, dropReasonNegativeBucketCount, len(counts),
+					zap.Int("bucket_index", idx))
+				continue
+			}
+			c.logger.Warn("histogram bucket count is negative when converted to int64, emitting zero",
+				zap.String("metric", basePoint.Metric),
+				zap.Int("bucket_index", idx))
+			sanitize = true
+		}
+
+		// OTLP bucket counts are per bucket, not cumulative across buckets,
+		// and are emitted as is unless PrometheusCumulativeBuckets is set.
+		// Buckets of delta histograms are therefore per bucket deltas and
+		// have the COUNTER type of the base point, unless
+		// HistogramBucketsAsGauge is set.
+		infinityBound := c.infinityBoundDimValue()
+		cumulativeBuckets := c.options.PrometheusCumulativeBuckets
+		var total uint64
+		for j, bucketCount := range counts {
+			bound := infinityBound
+			if j < len(bounds) {
+				bound = c.bucketBoundToDimValue(bounds[j])
+			}
+			if sanitize && int64(bucketCount) < 0 {
+				bucketCount = 0
+			}
+			if cumulativeBuckets {
+				total += bucketCount
+				bucketCount = total
+			}
+
+			dp := *basePoint
+			dp.Metric = basePoint.Metric + "_bucket"
+			dp.Timestamp = ts
+			if c.options.HistogramBucketsAsGauge {
+				dp.MetricType = &sfxMetricTypeGauge
+			}
+			dp.Dimensions = c.labelsToDimensions(histDP.LabelsMap(), extraDims, dimBuf)
+			if j == len(bounds) && c.options.InfinityBucketMetricSuffix != "" {
+				dp.Metric = basePoint.Metric + c.options.InfinityBucketMetricSuffix
+			} else {
+				dp.Dimensions = append(dp.Dimensions, &sfxpb.Dimension{
+					Key:   upperBoundDimensionKey,
+					Value: bound,
+				})
+			}
+			if c.options.IncludeBucketIndex {
+				dp.Dimensions = append(dp.Dimensions, &sfxpb.Dimension{
+					Key:   bucketIndexDimensionKey,
+					Value: strconv.Itoa(j),
+				})
+			}
+			if asDouble {
+				cDouble := float64(bucketCount)
+				dp.Value.DoubleValue = &cDouble
+			} else {
+				cInt := int64(bucketCount)
+				dp.Value.IntValue = &cInt
+			}
+
+			out = append(out, &dp)
 		}
-		return &sfxMetricTypeCumulativeCounter
 	}
 
-	return nil
+	return out
 }
 
-func convertIntHistogram(histDPs pdata.IntHistogramDataPointSlice, basePoint *sfxpb.DataPoint, extraDims []*sfxpb.Dimension) []*sfxpb.DataPoint {
+func (c *MetricsConverter) convertDoubleHistogram(histDPs pdata.DoubleHistogramDataPointSlice, basePoint *sfxpb.DataPoint, extraDims []*sfxpb.Dimension, dimBuf *dimensionBuffer, stats *DropStats) []*sfxpb.DataPoint {
 	var out []*sfxpb.DataPoint
 
 	for i := 0; i < histDPs.Len(); i++ {
 		histDP := histDPs.At(i)
-		if histDP.IsNil() {
+		if histDP.IsNil() || c.omitHistogram(histDP.Count()) {
 			continue
 		}
 
-		ts := timestampToSignalFx(histDP.Timestamp())
+		ts := c.timestampToSignalFx(histDP.Timestamp())
 
 		countDP := *basePoint
-		countDP.Metric = basePoint.Metric + "_count"
+		countDP.Metric = c.histogramCountMetricName(basePoint.Metric)
 		countDP.Timestamp = ts
-		countDP.Dimensions = labelsToDimensions(histDP.LabelsMap(), extraDims)
-		count := int64(histDP.Count())
-		countDP.Value.IntValue = &count
+		countDP.Dimensions = c.labelsToDimensions(histDP.LabelsMap(), extraDims, dimBuf)
+		countDP.Value = c.histogramCountValue(basePoint.Metric, histDP.Count())
 
-		sumDP := *basePoint
-		sumDP.Timestamp = ts
-		sumDP.Dimensions = labelsToDimensions(histDP.LabelsMap(), extraDims)
-		sum := histDP.Sum()
-		sumDP.Value.IntValue = &sum
+		out = append(out, &countDP)
 
-		out = append(out, &countDP, &sumDP)
+		if !c.options.OmitHistogramSum {
+			sumDP := *basePoint
+			sumDP.Metric = c.histogramSumMetricName(basePoint.Metric)
+			sumDP.Timestamp = ts
+			if c.options.HistogramSumAsGauge {
+				sumDP.MetricType = &sfxMetricTypeGauge
+			}
+			sumDP.Dimensions = c.labelsToDimensions(histDP.LabelsMap(), extraDims, dimBuf)
+			sum := histDP.Sum()
+			sumDP.Value.DoubleValue = &sum
+			out = append(out, &sumDP)
+		}
 
 		bounds := histDP.ExplicitBounds()
 		counts := histDP.BucketCounts()
@@ -264,25 +2742,116 @@ func convertIntHistogram(histDPs pdata.IntHistogramDataPointSlice, basePoint *sf
 		// Spec says counts is optional but if present it must have one more
 		// element than the bounds array.
 		if len(counts) > 0 && len(counts) != len(bounds)+1 {
+			stats.add(dropReasonHistogramMismatch, len(counts))
+			continue
+		}
+
+		// NaN bounds would produce unusable "NaN" upper_bound dimensions,
+		// keep count and sum but drop the buckets.
+		if hasNaNBound(bounds) {
+			c.logDroppedDataPoints(stats, zapcore.WarnLevel, "histogram explicit bounds contain NaN, dropping buckets",
+				basePoint.Metric, dropReasonNaNBounds, len(counts))
 			continue
 		}
 
-		for j, c := range counts {
-			bound := infinityBoundSFxDimValue
+		// Bucket counts can only be mapped to their bounds if the bounds are
+		// sorted, keep count and sum but drop the buckets otherwise.
+		if !boundsStrictlyIncreasing(bounds) {
+			c.logDroppedDataPoints(stats, zapcore.WarnLevel, "histogram explicit bounds are not strictly increasing, dropping buckets",
+				basePoint.Metric, dropReasonUnsortedBounds, len(counts),
+				zap.Float64s("bounds", bounds))
+			continue
+		}
+
+		if len(c.options.EmitQuantileEstimates) > 0 {
+			out = c.appendQuantileEstimates(out, basePoint, ts, bounds, counts, histDP.LabelsMap(), extraDims, dimBuf)
+		}
+
+		// Each bucket becomes a datapoint, merge or drop the buckets of
+		// histograms with more than MaxHistogramBuckets buckets.
+		if maxBuckets := c.options.MaxHistogramBuckets; maxBuckets > 0 && len(counts) > maxBuckets {
+			if c.options.HistogramBucketOverflow != HistogramBucketOverflowMerge {
+				c.logDroppedDataPoints(stats, zapcore.WarnLevel, "histogram has too many buckets, dropping buckets",
+					basePoint.Metric, dropReasonTooManyBuckets, len(counts),
+					zap.Int("max_histogram_buckets", maxBuckets))
+				continue
+			}
+			c.logger.Warn("histogram has too many buckets, merging buckets",
+				zap.String("metric", basePoint.Metric),
+				zap.Int("buckets", len(counts)),
+				zap.Int("max_histogram_buckets", maxBuckets))
+			bounds, counts = mergeHistogramBuckets(bounds, counts, maxBuckets)
+		}
+
+		// Bucket counts above math.MaxInt64 would become negative once
+		// converted to SignalFx int values, drop the buckets in that case
+		// unless they are sanitized or emitted as doubles.
+		sanitize := false
+		asDouble := false
+		if idx := negativeBucketCountIndex(counts); idx >= 0 && c.options.LargeBucketCountsAsDouble {
+			asDouble = true
+		} else if idx >= 0 {
+			if !c.options.SanitizeBucketCounts {
+				c.logDroppedDataPoints(stats, zapcore.ErrorLevel, "histogram bucket count is negative when converted to int64, dropping buckets",
+					basePoint.Metric, dropReasonNegativeBucketCount, len(counts),
+					zap.Int("bucket_index", idx))
+				continue
+			}
+			c.logger.Warn("histogram bucket count is negative when converted to int64, emitting zero",
+				zap.String("metric", basePoint.Metric),
+				zap.Int("bucket_index", idx))
+			sanitize = true
+		}
+
+		// OTLP bucket counts are per bucket, not cumulative across buckets,
+		// and are emitted as is unless PrometheusCumulativeBuckets is set.
+		// Buckets of delta histograms are therefore per bucket deltas and
+		// have the COUNTER type of the base point, unless
+		// HistogramBucketsAsGauge is set.
+		infinityBound := c.infinityBoundDimValue()
+		cumulativeBuckets := c.options.PrometheusCumulativeBuckets
+		var total uint64
+		for j, bucketCount := range counts {
+			bound := infinityBound
 			if j < len(bounds) {
-				bound = float64ToDimValue(bounds[j])
+				bound = c.bucketBoundToDimValue(bounds[j])
+			}
+			if sanitize && int64(bucketCount) < 0 {
+				bucketCount = 0
+			}
+			if cumulativeBuckets {
+				total += bucketCount
+				bucketCount = total
 			}
 
 			dp := *basePoint
 			dp.Metric = basePoint.Metric + "_bucket"
 			dp.Timestamp = ts
-			dp.Dimensions = labelsToDimensions(histDP.LabelsMap(), extraDims)
-			dp.Dimensions = append(dp.Dimensions, &sfxpb.Dimension{
-				Key:   upperBoundDimensionKey,
-				Value: bound,
-			})
-			cInt := int64(c)
-			dp.Value.IntValue = &cInt
+			if c.options.HistogramBucketsAsGauge {
+				dp.MetricType = &sfxMetricTypeGauge
+			}
+			dp.Dimensions = c.labelsToDimensions(histDP.LabelsMap(), extraDims, dimBuf)
+			if j == len(bounds) && c.options.InfinityBucketMetricSuffix != "" {
+				dp.Metric = basePoint.Metric + c.options.InfinityBucketMetricSuffix
+			} else {
+				dp.Dimensions = append(dp.Dimensions, &sfxpb.Dimension{
+					Key:   upperBoundDimensionKey,
+					Value: bound,
+				})
+			}
+			if c.options.IncludeBucketIndex {
+				dp.Dimensions = append(dp.Dimensions, &sfxpb.Dimension{
+					Key:   bucketIndexDimensionKey,
+					Value: strconv.Itoa(j),
+				})
+			}
+			if asDouble {
+				cDouble := float64(bucketCount)
+				dp.Value.DoubleValue = &cDouble
+			} else {
+				cInt := int64(bucketCount)
+				dp.Value.IntValue = &cInt
+			}
 
 			out = append(out, &dp)
 		}
@@ -291,75 +2860,373 @@ func convertIntHistogram(histDPs pdata.IntHistogramDataPointSlice, basePoint *sf
 	return out
 }
 
-func convertDoubleHistogram(histDPs pdata.DoubleHistogramDataPointSlice, basePoint *sfxpb.DataPoint, extraDims []*sfxpb.Dimension) []*sfxpb.DataPoint {
-	var out []*sfxpb.DataPoint
+// appendQuantileEstimates appends the gauges of the EmitQuantileEstimates
+// option estimated from the buckets of a histogram datapoint to out.
+func (c *MetricsConverter) appendQuantileEstimates(out []*sfxpb.DataPoint, basePoint *sfxpb.DataPoint, ts int64,
+	bounds []float64, counts []uint64, labels pdata.StringMap, extraDims []*sfxpb.Dimension, dimBuf *dimensionBuffer) []*sfxpb.DataPoint {
+	for _, q := range c.options.EmitQuantileEstimates {
+		v, ok := estimateQuantile(q, bounds, counts)
+		if !ok {
+			return out
+		}
+		dp := *basePoint
+		dp.Metric = basePoint.Metric + ".p" + quantilePercentile(q)
+		dp.Timestamp = ts
+		dp.MetricType = &sfxMetricTypeGauge
+		dp.Dimensions = c.labelsToDimensions(labels, extraDims, dimBuf)
+		dp.Value.DoubleValue = &v
+		out = append(out, &dp)
+	}
+	return out
+}
 
-	for i := 0; i < histDPs.Len(); i++ {
-		histDP := histDPs.At(i)
-		if histDP.IsNil() {
+// quantilePercentile returns the percentile of the q-quantile as used in the
+// name of the EmitQuantileEstimates gauges, e.g. "99.9" for 0.999. It moves
+// the decimal point of the formatted quantile instead of multiplying it by
+// 100, which would add floating point noise to the names, e.g. for 0.57.
+func quantilePercentile(q float64) string {
+	s := strconv.FormatFloat(q, 'f', -1, 64)
+	whole, fraction := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, fraction = s[:i], s[i+1:]
+	}
+	for len(fraction) < 2 {
+		fraction += "0"
+	}
+	percentile := strings.TrimLeft(whole+fraction[:2], "0")
+	if percentile == "" {
+		percentile = "0"
+	}
+	if len(fraction) > 2 {
+		percentile += "." + fraction[2:]
+	}
+	return percentile
+}
+
+// estimateQuantile estimates the q-quantile of the observations of a histogram
+// with sorted bounds as described in EmitQuantileEstimates, returning false if
+// the histogram has no observations or no bounds.
+func estimateQuantile(q float64, bounds []float64, counts []uint64) (float64, bool) {
+	if len(bounds) == 0 {
+		return 0, false
+	}
+	var total uint64
+	for _, count := range counts {
+		total += count
+	}
+	if total == 0 {
+		return 0, false
+	}
+
+	rank := q * float64(total)
+	var below uint64
+	for i, count := range counts {
+		if count == 0 || float64(below+count) < rank {
+			below += count
 			continue
 		}
+		if i == len(bounds) {
+			// The infinity bucket has no upper bound to interpolate to.
+			return bounds[len(bounds)-1], true
+		}
+		upper := bounds[i]
+		lower := 0.0
+		if i > 0 {
+			lower = bounds[i-1]
+		} else if upper <= 0 {
+			return upper, true
+		}
+		return lower + (upper-lower)*(rank-float64(below))/float64(count), true
+	}
+	return bounds[len(bounds)-1], true
+}
 
-		ts := timestampToSignalFx(histDP.Timestamp())
+// histogramCountMetricName returns the name of the count datapoint of the
+// histogram with the given name according to the HistogramCountAsBase option.
+func (c *MetricsConverter) histogramCountMetricName(metric string) string {
+	if c.options.HistogramCountAsBase {
+		return metric
+	}
+	return metric + "_count"
+}
 
-		countDP := *basePoint
-		countDP.Metric = basePoint.Metric + "_count"
-		countDP.Timestamp = ts
-		countDP.Dimensions = labelsToDimensions(histDP.LabelsMap(), extraDims)
-		count := int64(histDP.Count())
-		countDP.Value.IntValue = &count
+// histogramSumMetricName returns the name of the sum datapoint of the
+// histogram with the given name according to the HistogramCountAsBase option.
+func (c *MetricsConverter) histogramSumMetricName(metric string) string {
+	if c.options.HistogramCountAsBase {
+		return metric + "_sum"
+	}
+	return metric
+}
 
-		sumDP := *basePoint
-		sumDP.Timestamp = ts
-		sumDP.Dimensions = labelsToDimensions(histDP.LabelsMap(), extraDims)
-		sum := histDP.Sum()
-		sumDP.Value.DoubleValue = &sum
+// infinityBoundDimValue returns the upper_bound dimension value used for the
+// infinity bucket of histograms.
+func (c *MetricsConverter) infinityBoundDimValue() string {
+	if c.options.InfinityBoundDimensionValue != "" {
+		return c.options.InfinityBoundDimensionValue
+	}
+	return infinityBoundSFxDimValue
+}
 
-		out = append(out, &countDP, &sumDP)
+// hasNaNBound returns true if any of the histogram explicit bounds is NaN.
+func hasNaNBound(bounds []float64) bool {
+	for _, b := range bounds {
+		if math.IsNaN(b) {
+			return true
+		}
+	}
+	return false
+}
 
-		bounds := histDP.ExplicitBounds()
-		counts := histDP.BucketCounts()
+// boundsStrictlyIncreasing checks that histogram explicit bounds are sorted in
+// ascending order without duplicates, as required by the OTLP spec.
+func boundsStrictlyIncreasing(bounds []float64) bool {
+	for i := 1; i < len(bounds); i++ {
+		if !(bounds[i] > bounds[i-1]) {
+			return false
+		}
+	}
+	return true
+}
 
-		// Spec says counts is optional but if present it must have one more
-		// element than the bounds array.
-		if len(counts) > 0 && len(counts) != len(bounds)+1 {
+// histogramCountValue converts the count of a histogram datapoint to an int64
+// value. Counts above math.MaxInt64 are negative once converted, they are
+// emitted as double values with the LargeBucketCountsAsDouble option or
+// replaced by zero with the SanitizeBucketCounts option.
+func (c *MetricsConverter) histogramCountValue(metric string, count uint64) sfxpb.Datum {
+	v := int64(count)
+	if v < 0 && c.options.LargeBucketCountsAsDouble {
+		d := float64(count)
+		return sfxpb.Datum{DoubleValue: &d}
+	}
+	if v < 0 && c.options.SanitizeBucketCounts {
+		c.logger.Warn("histogram count is negative when converted to int64, emitting zero",
+			zap.String("metric", metric))
+		v = 0
+	}
+	return sfxpb.Datum{IntValue: &v}
+}
+
+// negativeBucketCountIndex returns the index of the first bucket count that
+// is negative when converted to int64, or -1 if there is none.
+func negativeBucketCountIndex(counts []uint64) int {
+	for i, c := range counts {
+		if int64(c) < 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// dedupLatestGauges removes gauge datapoints superseded by a datapoint of the
+// same time series with a newer timestamp, along with their properties.
+func dedupLatestGauges(dps []*sfxpb.DataPoint, properties DataPointProperties) []*sfxpb.DataPoint {
+	// Time series keys of gauge datapoints, empty for other datapoints.
+	keys := make([]string, len(dps))
+	latest := make(map[string]int)
+	for i, dp := range dps {
+		if dp.MetricType == nil || *dp.MetricType != sfxpb.MetricType_GAUGE {
+			continue
+		}
+		keys[i] = dp.Metric + ":" + stringifyDimensions(dp.Dimensions, nil)
+		if j, ok := latest[keys[i]]; !ok || dp.Timestamp >= dps[j].Timestamp {
+			latest[keys[i]] = i
+		}
+	}
+	if len(latest) == 0 {
+		return dps
+	}
+
+	out := make([]*sfxpb.DataPoint, 0, len(dps))
+	for i, dp := range dps {
+		if keys[i] != "" && latest[keys[i]] != i {
+			delete(properties, dp)
 			continue
 		}
+		out = append(out, dp)
+	}
+	return out
+}
 
-		for j, c := range counts {
-			bound := infinityBoundSFxDimValue
-			if j < len(bounds) {
-				bound = float64ToDimValue(bounds[j])
+// capDataPointDimensions drops dimensions of datapoints having more than
+// MaxDimensions dimensions. resourceDims are the dimensions built from resource
+// attributes, shared by all datapoints. It must run after blocked dimensions are
+// dropped so that they don't count against the limit.
+func (c *MetricsConverter) capDataPointDimensions(dps []*sfxpb.DataPoint, resourceDims []*sfxpb.Dimension) {
+	maxDims := c.options.MaxDimensions
+	if maxDims <= 0 {
+		return
+	}
+
+	var isResourceDim map[*sfxpb.Dimension]bool
+	var cappedMetrics []string
+	numCapped := 0
+	for _, dp := range dps {
+		if len(dp.Dimensions) <= maxDims {
+			continue
+		}
+		if isResourceDim == nil {
+			isResourceDim = make(map[*sfxpb.Dimension]bool, len(resourceDims))
+			for _, d := range resourceDims {
+				isResourceDim[d] = true
 			}
+		}
+		if numCapped == 0 || cappedMetrics[len(cappedMetrics)-1] != dp.Metric {
+			cappedMetrics = append(cappedMetrics, dp.Metric)
+		}
+		numCapped++
+		dp.Dimensions = c.capDimensions(dp.Dimensions, isResourceDim)
+	}
 
-			dp := *basePoint
-			dp.Metric = basePoint.Metric + "_bucket"
-			dp.Timestamp = ts
-			dp.Dimensions = labelsToDimensions(histDP.LabelsMap(), extraDims)
-			dp.Dimensions = append(dp.Dimensions, &sfxpb.Dimension{
-				Key:   upperBoundDimensionKey,
-				Value: bound,
-			})
-			cInt := int64(c)
-			dp.Value.IntValue = &cInt
+	if numCapped > 0 {
+		c.logger.Warn("datapoints have too many dimensions, dropping dimensions",
+			zap.Int("max_dimensions", maxDims),
+			zap.Int("datapoints", numCapped),
+			zap.Strings("metrics", cappedMetrics))
+	}
+}
 
-			out = append(out, &dp)
+// capDimensions returns the MaxDimensions dimensions with the highest priority,
+// keeping their order.
+func (c *MetricsConverter) capDimensions(dims []*sfxpb.Dimension, isResourceDim map[*sfxpb.Dimension]bool) []*sfxpb.Dimension {
+	preferResource := c.options.DimensionPriority != DimensionPriorityLabels
+	priority := func(d *sfxpb.Dimension) int {
+		switch {
+		case d.Key == upperBoundDimensionKey || d.Key == bucketIndexDimensionKey:
+			return 0
+		case isResourceDim[d] == preferResource:
+			return 1
+		default:
+			return 2
+		}
+	}
+
+	maxDims := c.options.MaxDimensions
+	keep := make([]bool, len(dims))
+	numKept := 0
+	for p := 0; p <= 2 && numKept < maxDims; p++ {
+		for i, d := range dims {
+			if numKept < maxDims && !keep[i] && priority(d) == p {
+				keep[i] = true
+				numKept++
+			}
 		}
 	}
 
+	out := make([]*sfxpb.Dimension, 0, maxDims)
+	for i, d := range dims {
+		if keep[i] {
+			out = append(out, d)
+		}
+	}
 	return out
 }
 
 // sanitizeDataPointLabels replaces all characters unsupported by SignalFx backend
-// in metric label keys and with "_"
-func sanitizeDataPointDimensions(dps []*sfxpb.DataPoint) {
+// in metric label keys and with "_", trims values with the TrimDimensionValues
+// option, fills empty values with the DimensionValueDefaults option, normalizes
+// the values of dimensions listed in the DimensionValueCase option and
+// truncates keys longer than the MaxDimensionKeyLength option.
+func (c *MetricsConverter) sanitizeDataPointDimensions(dps []*sfxpb.DataPoint) {
+	var interner *dimensionInterner
+	if c.options.InternDimensionStrings {
+		interner = newDimensionInterner()
+	}
 	for _, dp := range dps {
+		truncated := false
 		for _, d := range dp.Dimensions {
-			d.Key = filterKeyChars(d.Key)
+			if c.options.TrimDimensionValues {
+				d.Value = strings.TrimSpace(d.Value)
+			}
+			if d.Value == "" {
+				if v, ok := c.options.DimensionValueDefaults[d.Key]; ok {
+					d.Value = v
+				}
+			}
+			if valueCase, ok := c.options.DimensionValueCase[d.Key]; ok {
+				d.Value = valueCase.apply(d.Value)
+			}
+			if interner != nil {
+				d.Key = interner.sanitizedKey(d.Key)
+				d.Value = interner.intern(d.Value)
+			} else {
+				d.Key = filterKeyChars(d.Key)
+			}
+			if maxLen := c.options.MaxDimensionKeyLength; maxLen > 0 && len(d.Key) > maxLen {
+				c.logger.Debug("dimension key is too long, truncating it",
+					zap.String("key", d.Key),
+					zap.Int("max_dimension_key_length", maxLen))
+				d.Key = truncateString(d.Key, maxLen)
+				truncated = true
+			}
+		}
+		if truncated {
+			c.logCollidingDimensionKeys(dp)
+		}
+	}
+}
+
+// truncateString truncates the string to at most maxLen bytes without splitting
+// multi-byte characters.
+func truncateString(s string, maxLen int) string {
+	end := maxLen
+	for end > 0 && !utf8.RuneStart(s[end]) {
+		end--
+	}
+	return s[:end]
+}
+
+// logCollidingDimensionKeys logs the dimension keys of the datapoint that are
+// present more than once, which truncation of keys can cause.
+func (c *MetricsConverter) logCollidingDimensionKeys(dp *sfxpb.DataPoint) {
+	seen := make(map[string]bool, len(dp.Dimensions))
+	for _, d := range dp.Dimensions {
+		if seen[d.Key] {
+			c.logger.Warn("dimension keys collide after truncation",
+				zap.String("metric", dp.Metric),
+				zap.String("key", d.Key))
 		}
+		seen[d.Key] = true
+	}
+}
+
+// dimensionInterner interns the dimension keys and values of the datapoints of
+// a conversion. It is not safe for concurrent use and must not be shared
+// between conversions.
+type dimensionInterner struct {
+	// Canonical copy of each interned string.
+	strs map[string]string
+	// Sanitized keys by original key.
+	keys map[string]string
+}
+
+func newDimensionInterner() *dimensionInterner {
+	return &dimensionInterner{
+		strs: make(map[string]string),
+		keys: make(map[string]string),
 	}
 }
 
+// intern returns the canonical copy of s.
+func (di *dimensionInterner) intern(s string) string {
+	if interned, ok := di.strs[s]; ok {
+		return interned
+	}
+	di.strs[s] = s
+	return s
+}
+
+// sanitizedKey returns the canonical copy of the key with the characters not
+// allowed in dimension keys replaced, sanitizing each distinct key once.
+func (di *dimensionInterner) sanitizedKey(key string) string {
+	if sanitized, ok := di.keys[key]; ok {
+		return sanitized
+	}
+	sanitized := di.intern(filterKeyChars(key))
+	di.keys[key] = sanitized
+	return sanitized
+}
+
 func filterKeyChars(str string) string {
 	filterMap := func(r rune) rune {
 		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' {
@@ -371,6 +3238,36 @@ func filterKeyChars(str string) string {
 	return strings.Map(filterMap, str)
 }
 
+func filterMetricNameChars(str string) string {
+	filterMap := func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}
+
+	return strings.Map(filterMap, str)
+}
+
+// bucketBoundToDimValue returns the upper_bound dimension value of a histogram
+// bucket bound, rounded according to the BucketBoundPrecision option or with
+// all digits with the FullPrecisionBucketBounds option.
+func (c *MetricsConverter) bucketBoundToDimValue(bound float64) string {
+	if c.options.FullPrecisionBucketBounds {
+		// 17 significant digits are enough to represent any float64.
+		return strconv.FormatFloat(bound, 'g', 17, 64)
+	}
+	if c.options.BucketBoundPrecision > 0 {
+		// Round to the precision, then format the rounded value without
+		// exponent for values that don't need it.
+		rounded, err := strconv.ParseFloat(strconv.FormatFloat(bound, 'g', c.options.BucketBoundPrecision, 64), 64)
+		if err == nil {
+			bound = rounded
+		}
+	}
+	return float64ToDimValue(bound)
+}
+
 func float64ToDimValue(f float64) string {
 	// Parameters below are the same used by Prometheus
 	// see https://github.com/prometheus/common/blob/b5fe7d854c42dc7842e48d1ca58f60feae09d77b/expfmt/text_create.go#L450
@@ -381,19 +3278,71 @@ func float64ToDimValue(f float64) string {
 	return strconv.FormatFloat(f, 'g', -1, 64)
 }
 
+// appendAttributeDimension appends the dimension converted from the attribute
+// with the given key and value to dims, along with its type dimension with the
+// AttributeTypeDimensions option.
+func (c *MetricsConverter) appendAttributeDimension(dims []*sfxpb.Dimension, k string, val pdata.AttributeValue) []*sfxpb.Dimension {
+	if c.isBlockedDimensionKey(k) {
+		return dims
+	}
+	value := c.attributeValueToDimValue(val)
+	if value == "" && c.options.DropEmptyDimensions {
+		return dims
+	}
+
+	if len(c.options.DimensionKeyMapping) > 0 {
+		if k = c.mapDimensionKey(k, dims); k == "" {
+			return dims
+		}
+	}
+
+	dims = append(dims, &sfxpb.Dimension{
+		Key:   k,
+		Value: value,
+	})
+
+	if c.options.AttributeTypeDimensions {
+		if typ := attributeTypeDimValue(val.Type()); typ != "" {
+			dims = append(dims, &sfxpb.Dimension{
+				Key:   k + attributeTypeDimensionKeySuffix,
+				Value: typ,
+			})
+		}
+	}
+	return dims
+}
+
+// appendFlattenedMapDimensions appends a dimension for each value of the map
+// attribute to dims, keyed by the prefix and the key of the value joined with
+// ".", flattening nested maps recursively.
+func (c *MetricsConverter) appendFlattenedMapDimensions(dims []*sfxpb.Dimension, prefix string, m pdata.AttributeMap) []*sfxpb.Dimension {
+	m.ForEach(func(k string, val pdata.AttributeValue) {
+		key := prefix + "." + k
+		if val.Type() == pdata.AttributeValueMAP {
+			dims = c.appendFlattenedMapDimensions(dims, key, val.MapVal())
+			return
+		}
+		dims = c.appendAttributeDimension(dims, key, val)
+	})
+	return dims
+}
+
 // resourceAttributesToDimensions will return a set of dimension from the
 // resource attributes, including a cloud host id (AWSUniqueId, gcp_id, etc.)
 // if it can be constructed from the provided metadata.
-func resourceAttributesToDimensions(resourceAttr pdata.AttributeMap) []*sfxpb.Dimension {
+func (c *MetricsConverter) resourceAttributesToDimensions(resourceAttr pdata.AttributeMap) []*sfxpb.Dimension {
 	var dims []*sfxpb.Dimension
 
 	// TODO: Replace with internal/splunk/hostid.go once signalfxexporter is converted to pdata.
-	accountID := getStringAttr(resourceAttr, conventions.AttributeCloudAccount)
-	region := getStringAttr(resourceAttr, conventions.AttributeCloudRegion)
-	instanceID := getStringAttr(resourceAttr, conventions.AttributeHostID)
-	provider := getStringAttr(resourceAttr, conventions.AttributeCloudProvider)
+	keys := c.options.HostIDAttributes.withDefaults()
+	accountID := getStringAttr(resourceAttr, keys.CloudAccount)
+	region := getStringAttr(resourceAttr, keys.CloudRegion)
+	instanceID := getStringAttr(resourceAttr, keys.HostID)
+	provider := getStringAttr(resourceAttr, keys.CloudProvider)
 
 	filter := func(k string) bool { return true }
+	allowList := c.dimensionAllowList(resourceAttr)
+	hasHostID := false
 
 	switch provider {
 	case conventions.AttributeCloudProviderAWS:
@@ -401,48 +3350,258 @@ func resourceAttributesToDimensions(resourceAttr pdata.AttributeMap) []*sfxpb.Di
 			break
 		}
 		filter = func(k string) bool {
-			return k != conventions.AttributeCloudAccount &&
-				k != conventions.AttributeCloudRegion &&
-				k != conventions.AttributeHostID &&
-				k != conventions.AttributeCloudProvider
+			return k != keys.CloudAccount &&
+				k != keys.CloudRegion &&
+				k != keys.HostID &&
+				k != keys.CloudProvider
 		}
 		dims = append(dims, &sfxpb.Dimension{
 			Key:   "AWSUniqueId",
 			Value: fmt.Sprintf("%s_%s_%s", instanceID, region, accountID),
 		})
+		hasHostID = true
 	case conventions.AttributeCloudProviderGCP:
-		if accountID == "" || instanceID == "" {
+		// SignalFx expects the numeric project number in gcp_id, prefer it
+		// over the project ID from cloud.account.id when both are available.
+		projectNumber := getNumericAttr(resourceAttr, keys.GCPProjectNumber)
+		if projectNumber == "" {
+			projectNumber = accountID
+		}
+		if projectNumber == "" || instanceID == "" {
 			break
 		}
+		if !isNumeric(projectNumber) {
+			if c.firstGCPProjectIDWarning(projectNumber) {
+				c.logger.Warn("GCP project number is not available, using project ID to build gcp_id",
+					zap.String("project_id", projectNumber))
+			}
+		}
 		filter = func(k string) bool {
-			return k != conventions.AttributeCloudAccount &&
-				k != conventions.AttributeHostID &&
-				k != conventions.AttributeCloudProvider
+			return k != keys.CloudAccount &&
+				k != keys.GCPProjectNumber &&
+				k != keys.HostID &&
+				k != keys.CloudProvider
 		}
 		dims = append(dims, &sfxpb.Dimension{
 			Key:   "gcp_id",
-			Value: fmt.Sprintf("%s_%s", accountID, instanceID),
+			Value: fmt.Sprintf("%s_%s", projectNumber, instanceID),
 		})
+		hasHostID = true
 	default:
 	}
 
 	resourceAttr.ForEach(func(k string, val pdata.AttributeValue) {
 		// Never send the SignalFX token
 		if k == splunk.SFxAccessTokenLabel {
+			if c.options.AccessTokenHandler != nil {
+				c.options.AccessTokenHandler(val.StringVal())
+			}
+			return
+		}
+
+		if !filter(k) || c.propertyAttributes[k] {
+			return
+		}
+
+		if c.options.AttributeFilter != nil && !c.options.AttributeFilter(k) {
+			return
+		}
+
+		if allowList != nil && !allowList[k] {
 			return
 		}
 
-		if !filter(k) {
+		if c.options.FlattenMapAttributes && val.Type() == pdata.AttributeValueMAP {
+			dims = c.appendFlattenedMapDimensions(dims, k, val.MapVal())
 			return
 		}
+		dims = c.appendAttributeDimension(dims, k, val)
+	})
+
+	if c.options.HostNameFallback && !hasHostID && !hasDimensionKey(dims, hostDimensionKey) {
+		if hostName := getStringAttr(resourceAttr, conventions.AttributeHostName); hostName != "" {
+			dims = append(dims, &sfxpb.Dimension{
+				Key:   hostDimensionKey,
+				Value: hostName,
+			})
+		}
+	}
 
+	if c.options.RequireServiceName && !hasDimensionKey(dims, serviceDimensionKey) {
 		dims = append(dims, &sfxpb.Dimension{
+			Key:   serviceDimensionKey,
+			Value: c.serviceName(resourceAttr),
+		})
+	}
+
+	return dims
+}
+
+// dimensionAllowList returns the set of the resource attributes allowed as
+// dimensions by the DimensionAllowLists options, or nil if all resource
+// attributes are allowed.
+func (c *MetricsConverter) dimensionAllowList(resourceAttr pdata.AttributeMap) map[string]bool {
+	if c.options.DimensionAllowListAttribute == "" {
+		return nil
+	}
+	if allowList, ok := c.dimensionAllowLists[getStringAttr(resourceAttr, c.options.DimensionAllowListAttribute)]; ok {
+		return allowList
+	}
+	return c.defaultDimensionAllowList
+}
+
+// firstGCPProjectIDWarning returns true if the missing project number of the
+// GCP project hasn't been warned about yet, recording it.
+func (c *MetricsConverter) firstGCPProjectIDWarning(projectID string) bool {
+	c.warnedGCPProjectIDsMu.Lock()
+	defer c.warnedGCPProjectIDsMu.Unlock()
+	if c.warnedGCPProjectIDs[projectID] {
+		return false
+	}
+	if c.warnedGCPProjectIDs == nil || len(c.warnedGCPProjectIDs) >= maxWarnedGCPProjectIDs {
+		c.warnedGCPProjectIDs = make(map[string]bool)
+	}
+	c.warnedGCPProjectIDs[projectID] = true
+	return true
+}
+
+// serviceName returns the service.name resource attribute, or the service name
+// fallback if it is missing or empty.
+func (c *MetricsConverter) serviceName(resourceAttr pdata.AttributeMap) string {
+	if name := getStringAttr(resourceAttr, conventions.AttributeServiceName); name != "" {
+		return name
+	}
+	if c.options.ServiceNameFallback != "" {
+		return c.options.ServiceNameFallback
+	}
+	return defaultServiceNameFallback
+}
+
+// resourceAttributesToProperties returns the resource attributes designated as
+// properties by the PropertyAttributes option.
+func (c *MetricsConverter) resourceAttributesToProperties(resourceAttr pdata.AttributeMap) []*sfxpb.Property {
+	if len(c.propertyAttributes) == 0 {
+		return nil
+	}
+
+	var props []*sfxpb.Property
+	resourceAttr.ForEach(func(k string, val pdata.AttributeValue) {
+		if k == splunk.SFxAccessTokenLabel || !c.propertyAttributes[k] {
+			return
+		}
+		props = append(props, &sfxpb.Property{
 			Key:   k,
-			Value: tracetranslator.AttributeValueToString(val, false),
+			Value: c.attributeValueToPropertyValue(k, val),
 		})
 	})
+	return props
+}
 
-	return dims
+// metricProperties returns the properties of the first datapoint of the
+// passed in metric.
+func (c *MetricsConverter) metricProperties(metric pdata.Metric) []*sfxpb.Property {
+	var props []*sfxpb.Property
+	for _, field := range c.metadataProperties {
+		value := field.value(metric)
+		if value == "" {
+			continue
+		}
+		value = c.truncatePropertyValue(string(field), value)
+		props = append(props, &sfxpb.Property{
+			Key:   string(field),
+			Value: &sfxpb.PropertyValue{StrValue: &value},
+		})
+	}
+	return props
+}
+
+// attributeValueToPropertyValue converts an attribute value to the value of
+// the property with the given key, keeping scalar types and rendering other
+// values as strings.
+func (c *MetricsConverter) attributeValueToPropertyValue(key string, val pdata.AttributeValue) *sfxpb.PropertyValue {
+	switch val.Type() {
+	case pdata.AttributeValueINT:
+		v := val.IntVal()
+		return &sfxpb.PropertyValue{IntValue: &v}
+	case pdata.AttributeValueDOUBLE:
+		v := val.DoubleVal()
+		return &sfxpb.PropertyValue{DoubleValue: &v}
+	case pdata.AttributeValueBOOL:
+		v := val.BoolVal()
+		return &sfxpb.PropertyValue{BoolValue: &v}
+	default:
+		v := c.truncatePropertyValue(key, c.attributeValueToDimValue(val))
+		return &sfxpb.PropertyValue{StrValue: &v}
+	}
+}
+
+// truncatePropertyValue truncates the value of the property with the given key
+// to the MaxPropertyValueLength option.
+func (c *MetricsConverter) truncatePropertyValue(key string, value string) string {
+	maxLen := c.options.MaxPropertyValueLength
+	if maxLen <= 0 || len(value) <= maxLen {
+		return value
+	}
+	c.logger.Debug("property value is too long, truncating it",
+		zap.String("key", key),
+		zap.Int("max_property_value_length", maxLen))
+	return truncateString(value, maxLen)
+}
+
+// attributeTypeDimValue returns the type dimension value of attributes of the
+// passed in type, empty for string attributes which don't get a type dimension.
+func attributeTypeDimValue(typ pdata.AttributeValueType) string {
+	switch typ {
+	case pdata.AttributeValueBOOL:
+		return "bool"
+	case pdata.AttributeValueINT:
+		return "int"
+	case pdata.AttributeValueDOUBLE:
+		return "double"
+	case pdata.AttributeValueMAP:
+		return "map"
+	case pdata.AttributeValueARRAY:
+		return "array"
+	}
+	return ""
+}
+
+// attributeValueToDimValue renders an attribute value to a dimension value,
+// rendering arrays according to the ArrayAttributeRendering option.
+func (c *MetricsConverter) attributeValueToDimValue(val pdata.AttributeValue) string {
+	if val.Type() != pdata.AttributeValueARRAY {
+		return c.scalarAttributeValueToDimValue(val)
+	}
+
+	arr := val.ArrayVal()
+	switch c.options.ArrayAttributeRendering {
+	case ArrayAttributeRenderingFirst:
+		if arr.Len() == 0 {
+			return ""
+		}
+		return c.scalarAttributeValueToDimValue(arr.At(0))
+	case ArrayAttributeRenderingJoin:
+		elems := make([]string, arr.Len())
+		for i := 0; i < arr.Len(); i++ {
+			elems[i] = c.scalarAttributeValueToDimValue(arr.At(i))
+		}
+		return strings.Join(elems, c.options.ArrayAttributeSeparator)
+	default:
+		return tracetranslator.AttributeValueToString(val, false)
+	}
+}
+
+// scalarAttributeValueToDimValue renders a non array attribute value to a
+// dimension value, encoding booleans according to the BooleanDimensionEncoding
+// option.
+func (c *MetricsConverter) scalarAttributeValueToDimValue(val pdata.AttributeValue) string {
+	if val.Type() == pdata.AttributeValueBOOL && c.options.BooleanDimensionEncoding == BooleanDimensionEncodingOneZero {
+		if val.BoolVal() {
+			return "1"
+		}
+		return "0"
+	}
+	return tracetranslator.AttributeValueToString(val, false)
 }
 
 func getStringAttr(attrs pdata.AttributeMap, key string) string {
@@ -452,7 +3611,32 @@ func getStringAttr(attrs pdata.AttributeMap, key string) string {
 	return ""
 }
 
-func timestampToSignalFx(ts pdata.TimestampUnixNano) int64 {
-	// Convert nanosecs to millisecs.
-	return int64(ts) / 1e6
+// getNumericAttr returns the value of an int attribute, or of a string
+// attribute holding a number, as a string. It returns an empty string
+// otherwise.
+func getNumericAttr(attrs pdata.AttributeMap, key string) string {
+	a, ok := attrs.Get(key)
+	if !ok {
+		return ""
+	}
+	switch a.Type() {
+	case pdata.AttributeValueINT:
+		return strconv.FormatInt(a.IntVal(), 10)
+	case pdata.AttributeValueSTRING:
+		if isNumeric(a.StringVal()) {
+			return a.StringVal()
+		}
+	}
+	return ""
+}
+
+func isNumeric(s string) bool {
+	_, err := strconv.ParseUint(s, 10, 64)
+	return err == nil
+}
+
+// timestampToSignalFx converts the nanosecond timestamp to the configured
+// timestamp resolution, milliseconds by default.
+func (c *MetricsConverter) timestampToSignalFx(ts pdata.TimestampUnixNano) int64 {
+	return int64(ts) / c.options.TimestampResolution.nanosPerUnit()
 }
@@ -0,0 +1,297 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package translation
+
+import (
+	"fmt"
+	"time"
+
+	sfxpb "github.com/signalfx/com_signalfx_metrics_protobuf/model"
+	"go.opentelemetry.io/collector/consumer/pdata"
+)
+
+// convertibleMetricDataTypes are the metric data types that can be listed in
+// MetricsConverterConfig.IncludeMetricTypes and ExcludeMetricTypes.
+var convertibleMetricDataTypes = []pdata.MetricDataType{
+	pdata.MetricDataTypeIntGauge,
+	pdata.MetricDataTypeDoubleGauge,
+	pdata.MetricDataTypeIntSum,
+	pdata.MetricDataTypeDoubleSum,
+	pdata.MetricDataTypeIntHistogram,
+	pdata.MetricDataTypeDoubleHistogram,
+}
+
+// MetricsConverterConfig is the configuration counterpart of
+// MetricsConverterOptions, decoded from the "metrics_conversion" section of
+// the signalfx exporter config. Each field has the same meaning as the
+// option of the same name, metric data types are given by name, e.g.
+// "IntSum", and SignalFx metric types by their protobuf name, e.g.
+// "CUMULATIVE_COUNTER".
+//
+// Options that take functions or interfaces, the property options and the
+// drop events options have no config counterpart since the exporter sends
+// neither properties nor events with datapoints.
+type MetricsConverterConfig struct {
+	DropZeroValueCounters bool `mapstructure:"drop_zero_value_counters"`
+
+	ArrayAttributeRendering ArrayAttributeRendering `mapstructure:"array_attribute_rendering"`
+	ArrayAttributeSeparator string                  `mapstructure:"array_attribute_separator"`
+
+	BooleanDimensionEncoding BooleanDimensionEncoding `mapstructure:"boolean_dimension_encoding"`
+
+	InfinityBoundDimensionValue string `mapstructure:"infinity_bound_dimension_value"`
+	InfinityBucketMetricSuffix  string `mapstructure:"infinity_bucket_metric_suffix"`
+
+	PoolDimensionBuffers bool `mapstructure:"pool_dimension_buffers"`
+
+	EmitHeartbeat bool `mapstructure:"emit_heartbeat"`
+
+	EmitBuildInfo string `mapstructure:"emit_build_info"`
+
+	DimensionAllowListAttribute string              `mapstructure:"dimension_allow_list_attribute"`
+	DimensionAllowLists         map[string][]string `mapstructure:"dimension_allow_lists"`
+	DefaultDimensionAllowList   []string            `mapstructure:"default_dimension_allow_list"`
+
+	BlockedDimensionKeys []string `mapstructure:"blocked_dimension_keys"`
+
+	NonMonotonicSumAs NonMonotonicSumAs `mapstructure:"non_monotonic_sum_as"`
+
+	InferCounterFromName bool `mapstructure:"infer_counter_from_name"`
+
+	AnnotateOTLPType bool `mapstructure:"annotate_otlp_type"`
+
+	DropConflictingMetricTypes bool `mapstructure:"drop_conflicting_metric_types"`
+
+	SampleRateLabel           string `mapstructure:"sample_rate_label"`
+	ScaleCountersBySampleRate bool   `mapstructure:"scale_counters_by_sample_rate"`
+
+	DeltaToCumulative          bool          `mapstructure:"delta_to_cumulative"`
+	DeltaToCumulativeTTL       time.Duration `mapstructure:"delta_to_cumulative_ttl"`
+	DeltaToCumulativeMaxSeries int           `mapstructure:"delta_to_cumulative_max_series"`
+
+	NilHandling NilHandling `mapstructure:"nil_handling"`
+
+	IncludeMetricTypes []string `mapstructure:"include_metric_types"`
+	ExcludeMetricTypes []string `mapstructure:"exclude_metric_types"`
+
+	DimensionValueCase map[string]DimensionValueCase `mapstructure:"dimension_value_case"`
+
+	TrimDimensionValues bool `mapstructure:"trim_dimension_values"`
+
+	DimensionValueDefaults map[string]string `mapstructure:"dimension_value_defaults"`
+
+	PrometheusCumulativeBuckets bool `mapstructure:"prometheus_cumulative_buckets"`
+
+	MaxDimensions     int               `mapstructure:"max_dimensions"`
+	DimensionPriority DimensionPriority `mapstructure:"dimension_priority"`
+
+	DimensionOrder DimensionOrder `mapstructure:"dimension_order"`
+
+	DedupLatestGauge bool `mapstructure:"dedup_latest_gauge"`
+
+	RequireServiceName  bool   `mapstructure:"require_service_name"`
+	ServiceNameFallback string `mapstructure:"service_name_fallback"`
+
+	TimestampResolution TimestampResolution `mapstructure:"timestamp_resolution"`
+
+	AttributeTypeDimensions bool `mapstructure:"attribute_type_dimensions"`
+
+	FlattenMapAttributes bool `mapstructure:"flatten_map_attributes"`
+
+	HistogramSumAsGauge     bool `mapstructure:"histogram_sum_as_gauge"`
+	HistogramCountAsBase    bool `mapstructure:"histogram_count_as_base"`
+	HistogramBucketsAsGauge bool `mapstructure:"histogram_buckets_as_gauge"`
+	IncludeBucketIndex      bool `mapstructure:"include_bucket_index"`
+
+	EmitQuantileEstimates []float64 `mapstructure:"emit_quantile_estimates"`
+
+	MaxHistogramBuckets     int                     `mapstructure:"max_histogram_buckets"`
+	HistogramBucketOverflow HistogramBucketOverflow `mapstructure:"histogram_bucket_overflow"`
+
+	DropEmptyDimensions bool `mapstructure:"drop_empty_dimensions"`
+
+	DimensionKeyMapping map[string]string `mapstructure:"dimension_key_mapping"`
+
+	OmitHistogramSum    bool `mapstructure:"omit_histogram_sum"`
+	OmitEmptyHistograms bool `mapstructure:"omit_empty_histograms"`
+
+	MetricNameDelimiter        string `mapstructure:"metric_name_delimiter"`
+	MetricNameContextDimension string `mapstructure:"metric_name_context_dimension"`
+
+	SanitizeMetricNames  bool `mapstructure:"sanitize_metric_names"`
+	LowercaseMetricNames bool `mapstructure:"lowercase_metric_names"`
+
+	MetricNamespaceAttributes []string `mapstructure:"metric_namespace_attributes"`
+	MetricNamespaceDelimiter  string   `mapstructure:"metric_namespace_delimiter"`
+
+	CollectorInstanceID string `mapstructure:"collector_instance_id"`
+
+	HostIDAttributes HostIDAttributes `mapstructure:"host_id_attributes"`
+	HostNameFallback bool             `mapstructure:"host_name_fallback"`
+
+	MaxDimensionKeyLength int `mapstructure:"max_dimension_key_length"`
+
+	UnsignedCounterStringThreshold uint64 `mapstructure:"unsigned_counter_string_threshold"`
+
+	DoubleValuePrecision      int  `mapstructure:"double_value_precision"`
+	BucketBoundPrecision      int  `mapstructure:"bucket_bound_precision"`
+	FullPrecisionBucketBounds bool `mapstructure:"full_precision_bucket_bounds"`
+
+	MaxStaleness time.Duration `mapstructure:"max_staleness"`
+
+	MetricTypeOverrides      map[string]string `mapstructure:"metric_type_overrides"`
+	DefaultMetricTypeForNone string            `mapstructure:"default_metric_type_for_none"`
+
+	EmitConversionStats bool `mapstructure:"emit_conversion_stats"`
+
+	MaxDatapointsPerBatch int `mapstructure:"max_datapoints_per_batch"`
+
+	SanitizeBucketCounts      bool `mapstructure:"sanitize_bucket_counts"`
+	LargeBucketCountsAsDouble bool `mapstructure:"large_bucket_counts_as_double"`
+	InternDimensionStrings    bool `mapstructure:"intern_dimension_strings"`
+}
+
+// Options returns the MetricsConverterOptions set by the config, or an error
+// if a value is invalid.
+func (cfg MetricsConverterConfig) Options() (MetricsConverterOptions, error) {
+	options := MetricsConverterOptions{
+		DropZeroValueCounters:          cfg.DropZeroValueCounters,
+		ArrayAttributeRendering:        cfg.ArrayAttributeRendering,
+		ArrayAttributeSeparator:        cfg.ArrayAttributeSeparator,
+		BooleanDimensionEncoding:       cfg.BooleanDimensionEncoding,
+		InfinityBoundDimensionValue:    cfg.InfinityBoundDimensionValue,
+		InfinityBucketMetricSuffix:     cfg.InfinityBucketMetricSuffix,
+		PoolDimensionBuffers:           cfg.PoolDimensionBuffers,
+		EmitHeartbeat:                  cfg.EmitHeartbeat,
+		EmitBuildInfo:                  cfg.EmitBuildInfo,
+		DimensionAllowListAttribute:    cfg.DimensionAllowListAttribute,
+		DimensionAllowLists:            cfg.DimensionAllowLists,
+		DefaultDimensionAllowList:      cfg.DefaultDimensionAllowList,
+		BlockedDimensionKeys:           cfg.BlockedDimensionKeys,
+		NonMonotonicSumAs:              cfg.NonMonotonicSumAs,
+		InferCounterFromName:           cfg.InferCounterFromName,
+		AnnotateOTLPType:               cfg.AnnotateOTLPType,
+		DropConflictingMetricTypes:     cfg.DropConflictingMetricTypes,
+		SampleRateLabel:                cfg.SampleRateLabel,
+		ScaleCountersBySampleRate:      cfg.ScaleCountersBySampleRate,
+		DeltaToCumulative:              cfg.DeltaToCumulative,
+		DeltaToCumulativeTTL:           cfg.DeltaToCumulativeTTL,
+		DeltaToCumulativeMaxSeries:     cfg.DeltaToCumulativeMaxSeries,
+		NilHandling:                    cfg.NilHandling,
+		DimensionValueCase:             cfg.DimensionValueCase,
+		TrimDimensionValues:            cfg.TrimDimensionValues,
+		DimensionValueDefaults:         cfg.DimensionValueDefaults,
+		PrometheusCumulativeBuckets:    cfg.PrometheusCumulativeBuckets,
+		MaxDimensions:                  cfg.MaxDimensions,
+		DimensionPriority:              cfg.DimensionPriority,
+		DimensionOrder:                 cfg.DimensionOrder,
+		DedupLatestGauge:               cfg.DedupLatestGauge,
+		RequireServiceName:             cfg.RequireServiceName,
+		ServiceNameFallback:            cfg.ServiceNameFallback,
+		TimestampResolution:            cfg.TimestampResolution,
+		AttributeTypeDimensions:        cfg.AttributeTypeDimensions,
+		FlattenMapAttributes:           cfg.FlattenMapAttributes,
+		HistogramSumAsGauge:            cfg.HistogramSumAsGauge,
+		HistogramCountAsBase:           cfg.HistogramCountAsBase,
+		HistogramBucketsAsGauge:        cfg.HistogramBucketsAsGauge,
+		IncludeBucketIndex:             cfg.IncludeBucketIndex,
+		EmitQuantileEstimates:          cfg.EmitQuantileEstimates,
+		MaxHistogramBuckets:            cfg.MaxHistogramBuckets,
+		HistogramBucketOverflow:        cfg.HistogramBucketOverflow,
+		DropEmptyDimensions:            cfg.DropEmptyDimensions,
+		DimensionKeyMapping:            cfg.DimensionKeyMapping,
+		OmitHistogramSum:               cfg.OmitHistogramSum,
+		OmitEmptyHistograms:            cfg.OmitEmptyHistograms,
+		MetricNameDelimiter:            cfg.MetricNameDelimiter,
+		MetricNameContextDimension:     cfg.MetricNameContextDimension,
+		SanitizeMetricNames:            cfg.SanitizeMetricNames,
+		LowercaseMetricNames:           cfg.LowercaseMetricNames,
+		MetricNamespaceAttributes:      cfg.MetricNamespaceAttributes,
+		MetricNamespaceDelimiter:       cfg.MetricNamespaceDelimiter,
+		CollectorInstanceID:            cfg.CollectorInstanceID,
+		HostIDAttributes:               cfg.HostIDAttributes,
+		HostNameFallback:               cfg.HostNameFallback,
+		MaxDimensionKeyLength:          cfg.MaxDimensionKeyLength,
+		UnsignedCounterStringThreshold: cfg.UnsignedCounterStringThreshold,
+		DoubleValuePrecision:           cfg.DoubleValuePrecision,
+		BucketBoundPrecision:           cfg.BucketBoundPrecision,
+		FullPrecisionBucketBounds:      cfg.FullPrecisionBucketBounds,
+		MaxStaleness:                   cfg.MaxStaleness,
+		EmitConversionStats:            cfg.EmitConversionStats,
+		MaxDatapointsPerBatch:          cfg.MaxDatapointsPerBatch,
+		SanitizeBucketCounts:           cfg.SanitizeBucketCounts,
+		LargeBucketCountsAsDouble:      cfg.LargeBucketCountsAsDouble,
+		InternDimensionStrings:         cfg.InternDimensionStrings,
+	}
+
+	var err error
+	if options.IncludeMetricTypes, err = parseMetricDataTypes(cfg.IncludeMetricTypes); err != nil {
+		return MetricsConverterOptions{}, err
+	}
+	if options.ExcludeMetricTypes, err = parseMetricDataTypes(cfg.ExcludeMetricTypes); err != nil {
+		return MetricsConverterOptions{}, err
+	}
+	if len(cfg.MetricTypeOverrides) > 0 {
+		options.MetricTypeOverrides = make(map[string]sfxpb.MetricType, len(cfg.MetricTypeOverrides))
+		for pattern, name := range cfg.MetricTypeOverrides {
+			metricType, err := parseMetricType(name)
+			if err != nil {
+				return MetricsConverterOptions{}, err
+			}
+			options.MetricTypeOverrides[pattern] = metricType
+		}
+	}
+	if cfg.DefaultMetricTypeForNone != "" {
+		metricType, err := parseMetricType(cfg.DefaultMetricTypeForNone)
+		if err != nil {
+			return MetricsConverterOptions{}, err
+		}
+		options.DefaultMetricTypeForNone = &metricType
+	}
+
+	if err := options.validate(); err != nil {
+		return MetricsConverterOptions{}, err
+	}
+	return options, nil
+}
+
+func parseMetricDataTypes(names []string) ([]pdata.MetricDataType, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	types := make([]pdata.MetricDataType, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, t := range convertibleMetricDataTypes {
+			if t.String() == name {
+				types = append(types, t)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid metric data type: %q", name)
+		}
+	}
+	return types, nil
+}
+
+func parseMetricType(name string) (sfxpb.MetricType, error) {
+	v, ok := sfxpb.MetricType_value[name]
+	if !ok {
+		return 0, fmt.Errorf("invalid metric type: %q", name)
+	}
+	return sfxpb.MetricType(v), nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package translation
+
+import (
+	"testing"
+	"time"
+
+	sfxpb "github.com/signalfx/com_signalfx_metrics_protobuf/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/consumer/pdata"
+)
+
+func TestMetricsConverterConfigOptions(t *testing.T) {
+	cumulativeCounter := sfxpb.MetricType_CUMULATIVE_COUNTER
+	tests := []struct {
+		name    string
+		cfg     MetricsConverterConfig
+		want    MetricsConverterOptions
+		wantErr string
+	}{
+		{
+			name: "empty",
+			cfg:  MetricsConverterConfig{},
+			want: MetricsConverterOptions{},
+		},
+		{
+			name: "valid",
+			cfg: MetricsConverterConfig{
+				DropZeroValueCounters:    true,
+				ArrayAttributeRendering:  ArrayAttributeRenderingJoin,
+				DeltaToCumulative:        true,
+				DeltaToCumulativeTTL:     time.Minute,
+				IncludeMetricTypes:       []string{"IntSum", "DoubleHistogram"},
+				ExcludeMetricTypes:       []string{"IntGauge"},
+				DimensionValueCase:       map[string]DimensionValueCase{"env": DimensionValueCaseLower},
+				HostIDAttributes:         HostIDAttributes{GCPProjectNumber: "gcp.project_number"},
+				EmitQuantileEstimates:    []float64{0.5, 0.99},
+				MetricTypeOverrides:      map[string]string{"^requests$": "COUNTER"},
+				DefaultMetricTypeForNone: "CUMULATIVE_COUNTER",
+			},
+			want: MetricsConverterOptions{
+				DropZeroValueCounters:    true,
+				ArrayAttributeRendering:  ArrayAttributeRenderingJoin,
+				DeltaToCumulative:        true,
+				DeltaToCumulativeTTL:     time.Minute,
+				IncludeMetricTypes:       []pdata.MetricDataType{pdata.MetricDataTypeIntSum, pdata.MetricDataTypeDoubleHistogram},
+				ExcludeMetricTypes:       []pdata.MetricDataType{pdata.MetricDataTypeIntGauge},
+				DimensionValueCase:       map[string]DimensionValueCase{"env": DimensionValueCaseLower},
+				HostIDAttributes:         HostIDAttributes{GCPProjectNumber: "gcp.project_number"},
+				EmitQuantileEstimates:    []float64{0.5, 0.99},
+				MetricTypeOverrides:      map[string]sfxpb.MetricType{"^requests$": sfxpb.MetricType_COUNTER},
+				DefaultMetricTypeForNone: &cumulativeCounter,
+			},
+		},
+		{
+			name:    "invalid_include_metric_type",
+			cfg:     MetricsConverterConfig{IncludeMetricTypes: []string{"Summary"}},
+			wantErr: `invalid metric data type: "Summary"`,
+		},
+		{
+			name:    "none_metric_type",
+			cfg:     MetricsConverterConfig{ExcludeMetricTypes: []string{"None"}},
+			wantErr: `invalid metric data type: "None"`,
+		},
+		{
+			name:    "invalid_metric_type_override",
+			cfg:     MetricsConverterConfig{MetricTypeOverrides: map[string]string{"^requests$": "counter"}},
+			wantErr: `invalid metric type: "counter"`,
+		},
+		{
+			name:    "invalid_metric_type_override_pattern",
+			cfg:     MetricsConverterConfig{MetricTypeOverrides: map[string]string{"(": "COUNTER"}},
+			wantErr: `invalid metric type override pattern "("`,
+		},
+		{
+			name:    "invalid_default_metric_type_for_none",
+			cfg:     MetricsConverterConfig{DefaultMetricTypeForNone: "HISTOGRAM"},
+			wantErr: `invalid metric type: "HISTOGRAM"`,
+		},
+		{
+			name:    "invalid_enum",
+			cfg:     MetricsConverterConfig{NonMonotonicSumAs: "histogram"},
+			wantErr: `invalid non-monotonic sum conversion: "histogram"`,
+		},
+		{
+			name:    "invalid_range",
+			cfg:     MetricsConverterConfig{DeltaToCumulativeTTL: time.Millisecond},
+			wantErr: "invalid delta to cumulative ttl: 1ms",
+		},
+		{
+			name:    "invalid_quantile",
+			cfg:     MetricsConverterConfig{EmitQuantileEstimates: []float64{1.5}},
+			wantErr: "invalid quantile estimate: 1.5",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cfg.Options()
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
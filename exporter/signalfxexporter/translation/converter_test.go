@@ -15,8 +15,12 @@
 package translation
 
 import (
+	"fmt"
 	"math"
+	"regexp"
 	"sort"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -26,10 +30,222 @@ import (
 	"go.opentelemetry.io/collector/consumer/pdata"
 	"go.opentelemetry.io/collector/translator/conventions"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 
 	"github.com/open-telemetry/opentelemetry-collector-contrib/internal/common/testing/util"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/internal/splunk"
 )
 
+func TestNewMetricsConverter(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []Rule
+		modify  func(rules []Rule)
+		options MetricsConverterOptions
+		wantErr string
+	}{
+		{
+			name: "no_translator",
+		},
+		{
+			name: "valid_rules",
+			rules: []Rule{
+				{
+					Action:  ActionRenameMetrics,
+					Mapping: map[string]string{"metric": "new_metric"},
+				},
+				{
+					Action:      ActionDropMetrics,
+					MetricNames: map[string]bool{"other_metric": true},
+				},
+			},
+		},
+		{
+			name: "rule_missing_mapping",
+			rules: []Rule{
+				{
+					Action:  ActionRenameMetrics,
+					Mapping: map[string]string{"metric": "new_metric"},
+				},
+			},
+			modify: func(rules []Rule) {
+				rules[0].Mapping = nil
+			},
+			wantErr: `invalid translation rules: field "mapping" is required for "rename_metrics" translation rule`,
+		},
+		{
+			name: "rule_unknown_action",
+			rules: []Rule{
+				{
+					Action:      ActionDropMetrics,
+					MetricNames: map[string]bool{"metric": true},
+				},
+			},
+			modify: func(rules []Rule) {
+				rules[0].Action = "invalid_rule"
+			},
+			wantErr: `invalid translation rules: unknown "action" value: "invalid_rule"`,
+		},
+		{
+			name: "rule_invalid_operator",
+			rules: []Rule{
+				{
+					Action:         ActionCalculateNewMetric,
+					MetricName:     "metric",
+					Operand1Metric: "op1_metric",
+					Operand2Metric: "op2_metric",
+					Operator:       MetricOperatorDivision,
+				},
+			},
+			modify: func(rules []Rule) {
+				rules[0].Operator = "*"
+			},
+			wantErr: `invalid translation rules: invalid operator "*" for "calculate_new_metric" translation rule`,
+		},
+		{
+			name:    "invalid_array_attribute_rendering",
+			options: MetricsConverterOptions{ArrayAttributeRendering: "csv"},
+			wantErr: `invalid array attribute rendering: "csv"`,
+		},
+		{
+			name:    "invalid_boolean_dimension_encoding",
+			options: MetricsConverterOptions{BooleanDimensionEncoding: "yesno"},
+			wantErr: `invalid boolean dimension encoding: "yesno"`,
+		},
+		{
+			name:    "invalid_dimension_priority",
+			options: MetricsConverterOptions{DimensionPriority: "random"},
+			wantErr: `invalid dimension priority: "random"`,
+		},
+		{
+			name:    "invalid_dimension_order",
+			options: MetricsConverterOptions{DimensionOrder: "random"},
+			wantErr: `invalid dimension order: "random"`,
+		},
+		{
+			name: "invalid_dimension_value_case",
+			options: MetricsConverterOptions{
+				DimensionValueCase: map[string]DimensionValueCase{"region": "title"},
+			},
+			wantErr: `invalid dimension value case "title" for dimension "region"`,
+		},
+		{
+			name:    "invalid_timestamp_resolution",
+			options: MetricsConverterOptions{TimestampResolution: "seconds"},
+			wantErr: `invalid timestamp resolution: "seconds"`,
+		},
+		{
+			name:    "invalid_nil_handling",
+			options: MetricsConverterOptions{NilHandling: "panic"},
+			wantErr: `invalid nil handling: "panic"`,
+		},
+		{
+			name:    "invalid_delta_to_cumulative_ttl",
+			options: MetricsConverterOptions{DeltaToCumulativeTTL: time.Millisecond},
+			wantErr: "invalid delta to cumulative ttl: 1ms",
+		},
+		{
+			name:    "invalid_delta_to_cumulative_max_series",
+			options: MetricsConverterOptions{DeltaToCumulativeMaxSeries: -1},
+			wantErr: "invalid delta to cumulative max series: -1",
+		},
+		{
+			name:    "invalid_drop_events_interval",
+			options: MetricsConverterOptions{DropEventsInterval: -time.Second},
+			wantErr: "invalid drop events interval: -1s",
+		},
+		{
+			name:    "invalid_max_drop_events_per_interval",
+			options: MetricsConverterOptions{MaxDropEventsPerInterval: -1},
+			wantErr: "invalid max drop events per interval: -1",
+		},
+		{
+			name:    "invalid_quantile_estimate",
+			options: MetricsConverterOptions{EmitQuantileEstimates: []float64{0.5, 99}},
+			wantErr: "invalid quantile estimate: 99",
+		},
+		{
+			name:    "invalid_double_value_precision",
+			options: MetricsConverterOptions{DoubleValuePrecision: -2},
+			wantErr: "invalid double value precision: -2",
+		},
+		{
+			name:    "invalid_max_histogram_buckets",
+			options: MetricsConverterOptions{MaxHistogramBuckets: -1},
+			wantErr: "invalid max histogram buckets: -1",
+		},
+		{
+			name:    "invalid_histogram_bucket_overflow",
+			options: MetricsConverterOptions{HistogramBucketOverflow: "truncate"},
+			wantErr: `invalid histogram bucket overflow: "truncate"`,
+		},
+		{
+			name:    "invalid_bucket_bound_precision",
+			options: MetricsConverterOptions{BucketBoundPrecision: -1},
+			wantErr: "invalid bucket bound precision: -1",
+		},
+		{
+			name: "invalid_bucket_bound_precision_with_full_precision",
+			options: MetricsConverterOptions{
+				BucketBoundPrecision:      15,
+				FullPrecisionBucketBounds: true,
+			},
+			wantErr: "invalid bucket bound precision with full precision bucket bounds: 15",
+		},
+		{
+			name: "invalid_metric_metadata_property",
+			options: MetricsConverterOptions{
+				MetricMetadataProperties: []MetricMetadataField{MetricMetadataFieldUnit, "name"},
+			},
+			wantErr: `invalid metric metadata property: "name"`,
+		},
+		{
+			name: "invalid_non_monotonic_sum_as",
+			options: MetricsConverterOptions{
+				NonMonotonicSumAs: "histogram",
+			},
+			wantErr: `invalid non-monotonic sum conversion: "histogram"`,
+		},
+		{
+			name: "invalid_metric_type_override_pattern",
+			options: MetricsConverterOptions{
+				MetricTypeOverrides: map[string]sfxpb.MetricType{"req(": sfxpb.MetricType_COUNTER},
+			},
+			wantErr: "invalid metric type override pattern \"req(\": error parsing regexp: missing closing ): `req(`",
+		},
+		{
+			name: "invalid_metric_type_override",
+			options: MetricsConverterOptions{
+				MetricTypeOverrides: map[string]sfxpb.MetricType{"^requests": 42},
+			},
+			wantErr: `invalid metric type override 42 for pattern "^requests"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tr *MetricTranslator
+			if tt.rules != nil {
+				var err error
+				tr, err = NewMetricTranslator(tt.rules, 1)
+				require.NoError(t, err)
+				if tt.modify != nil {
+					tt.modify(tt.rules)
+				}
+			}
+
+			c, err := NewMetricsConverter(zap.NewNop(), tr, tt.options)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				assert.NotNil(t, c)
+			} else {
+				require.EqualError(t, err, tt.wantErr)
+				assert.Nil(t, c)
+			}
+		})
+	}
+}
+
 func Test_MetricDataToSignalFxV2(t *testing.T) {
 	logger := zap.NewNop()
 
@@ -467,6 +683,45 @@ func Test_MetricDataToSignalFxV2(t *testing.T) {
 					doubleVal),
 			},
 		},
+		{
+			name: "with_resources_cloud_gcp_dim_project_number",
+			metricsDataFn: func() pdata.ResourceMetrics {
+				out := pdata.NewResourceMetrics()
+				out.InitEmpty()
+
+				res := out.Resource()
+				res.Attributes().InsertString("k/r0", "vr0")
+				res.Attributes().InsertString("cloud.provider", conventions.AttributeCloudProviderGCP)
+				res.Attributes().InsertString("host.id", "abcd")
+				res.Attributes().InsertString("cloud.account.id", "efgh")
+				res.Attributes().InsertInt("gcp.project.number", 1234)
+
+				out.InstrumentationLibraryMetrics().Resize(1)
+				ilm := out.InstrumentationLibraryMetrics().At(0)
+
+				{
+					m := pdata.NewMetric()
+					m.InitEmpty()
+					m.SetName("gauge_double_with_dims")
+					m.SetDataType(pdata.MetricDataTypeDoubleGauge)
+					m.DoubleGauge().DataPoints().Append(doublePtWithLabels)
+					ilm.Metrics().Append(m)
+				}
+
+				return out
+			},
+			wantSfxDataPoints: []*sfxpb.DataPoint{
+				doubleSFxDataPoint(
+					"gauge_double_with_dims",
+					tsMSecs,
+					&sfxMetricTypeGauge,
+					util.MergeStringMaps(labelMap, map[string]string{
+						"gcp_id": "1234_abcd",
+						"k_r0":   "vr0",
+					}),
+					doubleVal),
+			},
+		},
 		{
 			name: "histograms",
 			metricsDataFn: func() pdata.ResourceMetrics {
@@ -544,10 +799,12 @@ func Test_MetricDataToSignalFxV2(t *testing.T) {
 			wantSfxDataPoints: expectedFromIntHistogram("no_bucket_histo", tsMSecs, labelMap, histDPNoBuckets, false),
 		},
 	}
-	c := NewMetricsConverter(logger, nil)
+	c, err := NewMetricsConverter(logger, nil, MetricsConverterOptions{})
+	require.NoError(t, err)
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotSfxDataPoints := c.MetricDataToSignalFxV2(tt.metricsDataFn())
+			gotSfxDataPoints, numDropped := c.MetricDataToSignalFxV2(tt.metricsDataFn())
+			assert.Equal(t, 0, numDropped)
 			// Sort SFx dimensions since they are built from maps and the order
 			// of those is not deterministic.
 			sortDimensions(tt.wantSfxDataPoints)
@@ -557,6 +814,126 @@ func Test_MetricDataToSignalFxV2(t *testing.T) {
 	}
 }
 
+func TestConvertMetric(t *testing.T) {
+	tests := []struct {
+		name        string
+		fill        func(m pdata.Metric)
+		wantMetrics []string
+		wantType    *sfxpb.MetricType
+	}{
+		{
+			name: "int_gauge",
+			fill: func(m pdata.Metric) {
+				m.SetDataType(pdata.MetricDataTypeIntGauge)
+				m.IntGauge().DataPoints().Resize(2)
+			},
+			wantMetrics: []string{"metric", "metric"},
+			wantType:    &sfxMetricTypeGauge,
+		},
+		{
+			name: "double_gauge",
+			fill: func(m pdata.Metric) {
+				m.SetDataType(pdata.MetricDataTypeDoubleGauge)
+				m.DoubleGauge().DataPoints().Resize(1)
+			},
+			wantMetrics: []string{"metric"},
+			wantType:    &sfxMetricTypeGauge,
+		},
+		{
+			name: "int_sum",
+			fill: func(m pdata.Metric) {
+				m.SetDataType(pdata.MetricDataTypeIntSum)
+				m.IntSum().SetIsMonotonic(true)
+				m.IntSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+				m.IntSum().DataPoints().Resize(1)
+			},
+			wantMetrics: []string{"metric"},
+			wantType:    &sfxMetricTypeCumulativeCounter,
+		},
+		{
+			name: "double_sum",
+			fill: func(m pdata.Metric) {
+				m.SetDataType(pdata.MetricDataTypeDoubleSum)
+				m.DoubleSum().SetIsMonotonic(true)
+				m.DoubleSum().SetAggregationTemporality(pdata.AggregationTemporalityDelta)
+				m.DoubleSum().DataPoints().Resize(1)
+			},
+			wantMetrics: []string{"metric"},
+			wantType:    &sfxMetricTypeCounter,
+		},
+		{
+			name: "int_histogram",
+			fill: func(m pdata.Metric) {
+				m.SetDataType(pdata.MetricDataTypeIntHistogram)
+				m.IntHistogram().DataPoints().Resize(1)
+				m.IntHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1})
+				m.IntHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, 2})
+			},
+			wantMetrics: []string{"metric_count", "metric", "metric_bucket", "metric_bucket"},
+			wantType:    &sfxMetricTypeCumulativeCounter,
+		},
+		{
+			name: "double_histogram",
+			fill: func(m pdata.Metric) {
+				m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+				m.DoubleHistogram().SetAggregationTemporality(pdata.AggregationTemporalityDelta)
+				m.DoubleHistogram().DataPoints().Resize(1)
+			},
+			wantMetrics: []string{"metric_count", "metric"},
+			wantType:    &sfxMetricTypeCounter,
+		},
+		{
+			name: "none",
+			fill: func(m pdata.Metric) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := pdata.NewMetric()
+			m.InitEmpty()
+			m.SetName("metric")
+			tt.fill(m)
+
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{})
+			require.NoError(t, err)
+			dps := c.ConvertMetric(m, []*sfxpb.Dimension{{Key: "k.0", Value: "v0"}})
+
+			var gotMetrics []string
+			for _, dp := range dps {
+				gotMetrics = append(gotMetrics, dp.Metric)
+				assert.Equal(t, tt.wantType, dp.MetricType)
+				// Extra dimensions come first and are sanitized.
+				assert.Equal(t, &sfxpb.Dimension{Key: "k_0", Value: "v0"}, dp.Dimensions[0])
+			}
+			assert.Equal(t, tt.wantMetrics, gotMetrics)
+		})
+	}
+}
+
+func TestConvertMetricWithTranslation(t *testing.T) {
+	translator, err := NewMetricTranslator([]Rule{
+		{
+			Action:  ActionRenameMetrics,
+			Mapping: map[string]string{"metric": "new_metric"},
+		},
+	}, 1)
+	require.NoError(t, err)
+	c, err := NewMetricsConverter(zap.NewNop(), translator, MetricsConverterOptions{})
+	require.NoError(t, err)
+
+	m := pdata.NewMetric()
+	m.InitEmpty()
+	m.SetName("metric")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+	m.IntGauge().DataPoints().At(0).SetValue(3)
+
+	dps := c.ConvertMetric(m, nil)
+	assert.Equal(t, []*sfxpb.DataPoint{
+		int64SFxDataPoint("new_metric", 0, &sfxMetricTypeGauge, map[string]string{}, 3),
+	}, dps)
+}
+
 func TestMetricDataToSignalFxV2WithTranslation(t *testing.T) {
 	translator, err := NewMetricTranslator([]Rule{
 		{
@@ -595,8 +972,6043 @@ func TestMetricDataToSignalFxV2WithTranslation(t *testing.T) {
 			},
 		},
 	}
-	c := NewMetricsConverter(zap.NewNop(), translator)
-	assert.EqualValues(t, expected, c.MetricDataToSignalFxV2(wrapMetric(md)))
+	c, err := NewMetricsConverter(zap.NewNop(), translator, MetricsConverterOptions{})
+	require.NoError(t, err)
+	got, _ := c.MetricDataToSignalFxV2(wrapMetric(md))
+	assert.EqualValues(t, expected, got)
+}
+
+func TestMetricDataToSignalFxV2TranslationFailure(t *testing.T) {
+	divisors := map[string]int64{"divided": 10}
+	translator, err := NewMetricTranslator([]Rule{
+		{
+			Action:          ActionDivideInt,
+			ScaleFactorsInt: divisors,
+		},
+	}, 1)
+	require.NoError(t, err)
+
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(2)
+	for i, name := range []string{"divided", "other"} {
+		m := ilm.Metrics().At(i)
+		m.SetName(name)
+		m.SetDataType(pdata.MetricDataTypeIntGauge)
+		m.IntGauge().DataPoints().Resize(1)
+		m.IntGauge().DataPoints().At(0).SetValue(20)
+	}
+
+	obs := &recordingObserver{}
+	c, err := NewMetricsConverter(zap.NewNop(), translator, MetricsConverterOptions{Observer: obs})
+	require.NoError(t, err)
+	// Rules are validated when creating the converter, break the rule
+	// afterwards to make it panic with an integer division by zero.
+	divisors["divided"] = 0
+	got, _ := c.MetricDataToSignalFxV2(rm)
+
+	want := []*sfxpb.DataPoint{
+		int64SFxDataPoint("divided", 0, &sfxMetricTypeGauge, nil, 20),
+		int64SFxDataPoint("other", 0, &sfxMetricTypeGauge, nil, 20),
+	}
+	assert.Equal(t, want, got)
+	assert.Equal(t, []string{"divided"}, obs.translationFailures)
+}
+
+func TestMetricDataToSignalFxV2UnsortedHistogramBounds(t *testing.T) {
+	intHistDP := pdata.NewIntHistogramDataPoint()
+	intHistDP.InitEmpty()
+	intHistDP.SetCount(16)
+	intHistDP.SetSum(100)
+	intHistDP.SetExplicitBounds([]float64{4, 1, 2})
+	intHistDP.SetBucketCounts([]uint64{4, 2, 3, 7})
+
+	doubleHistDP := pdata.NewDoubleHistogramDataPoint()
+	doubleHistDP.InitEmpty()
+	doubleHistDP.SetCount(16)
+	doubleHistDP.SetSum(100.0)
+	doubleHistDP.SetExplicitBounds([]float64{1, 2, 2})
+	doubleHistDP.SetBucketCounts([]uint64{4, 2, 3, 7})
+
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	{
+		m := pdata.NewMetric()
+		m.InitEmpty()
+		m.SetName("int_histo")
+		m.SetDataType(pdata.MetricDataTypeIntHistogram)
+		m.IntHistogram().DataPoints().Append(intHistDP)
+		ilm.Metrics().Append(m)
+	}
+	{
+		m := pdata.NewMetric()
+		m.InitEmpty()
+		m.SetName("double_histo")
+		m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+		m.DoubleHistogram().DataPoints().Append(doubleHistDP)
+		ilm.Metrics().Append(m)
+	}
+
+	core, observedLogs := observer.New(zap.WarnLevel)
+	c, err := NewMetricsConverter(zap.New(core), nil, MetricsConverterOptions{})
+	require.NoError(t, err)
+	got, _ := c.MetricDataToSignalFxV2(rm)
+
+	// Only count and sum are expected for histograms with unsorted bounds.
+	want := []*sfxpb.DataPoint{
+		int64SFxDataPoint("int_histo_count", 0, &sfxMetricTypeCumulativeCounter, nil, 16),
+		int64SFxDataPoint("int_histo", 0, &sfxMetricTypeCumulativeCounter, nil, 100),
+		int64SFxDataPoint("double_histo_count", 0, &sfxMetricTypeCumulativeCounter, nil, 16),
+		doubleSFxDataPoint("double_histo", 0, &sfxMetricTypeCumulativeCounter, nil, 100.0),
+	}
+	assert.Equal(t, want, got)
+
+	require.Equal(t, 2, observedLogs.Len())
+	for i, name := range []string{"int_histo", "double_histo"} {
+		entry := observedLogs.All()[i]
+		assert.Equal(t, "histogram explicit bounds are not strictly increasing, dropping buckets", entry.Message)
+		assert.Equal(t, name, entry.ContextMap()["metric"])
+	}
+}
+
+func TestMetricDataToSignalFxV2NaNHistogramBounds(t *testing.T) {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(1)
+	rm := md.ResourceMetrics().At(0)
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(2)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("int_histo")
+	m.SetDataType(pdata.MetricDataTypeIntHistogram)
+	m.IntHistogram().DataPoints().Resize(1)
+	m.IntHistogram().DataPoints().At(0).SetCount(6)
+	m.IntHistogram().DataPoints().At(0).SetSum(10)
+	m.IntHistogram().DataPoints().At(0).SetExplicitBounds([]float64{math.NaN()})
+	m.IntHistogram().DataPoints().At(0).SetBucketCounts([]uint64{4, 2})
+
+	m = ilm.Metrics().At(1)
+	m.SetName("double_histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).SetCount(6)
+	m.DoubleHistogram().DataPoints().At(0).SetSum(10)
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1, math.NaN()})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{4, 0, 2})
+
+	core, observedLogs := observer.New(zap.WarnLevel)
+	c, err := NewMetricsConverter(zap.New(core), nil, MetricsConverterOptions{})
+	require.NoError(t, err)
+	got, _ := c.MetricDataToSignalFxV2(rm)
+
+	// Only count and sum are expected for histograms with NaN bounds.
+	want := []*sfxpb.DataPoint{
+		int64SFxDataPoint("int_histo_count", 0, &sfxMetricTypeCumulativeCounter, nil, 6),
+		int64SFxDataPoint("int_histo", 0, &sfxMetricTypeCumulativeCounter, nil, 10),
+		int64SFxDataPoint("double_histo_count", 0, &sfxMetricTypeCumulativeCounter, nil, 6),
+		doubleSFxDataPoint("double_histo", 0, &sfxMetricTypeCumulativeCounter, nil, 10),
+	}
+	assert.Equal(t, want, got)
+	assert.Equal(t, len(got), c.DatapointCountEstimate(md))
+
+	require.Equal(t, 2, observedLogs.Len())
+	for i, name := range []string{"int_histo", "double_histo"} {
+		entry := observedLogs.All()[i]
+		assert.Equal(t, "histogram explicit bounds contain NaN, dropping buckets", entry.Message)
+		assert.Equal(t, name, entry.ContextMap()["metric"])
+	}
+}
+
+func TestMetricDataToSignalFxV2DroppedDataPointsLogFields(t *testing.T) {
+	now := time.Unix(1600000100, 0)
+	nowTs := pdata.TimestampUnixNano(now.UnixNano())
+
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(4)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("zero_counter")
+	m.SetDataType(pdata.MetricDataTypeIntSum)
+	m.IntSum().SetIsMonotonic(true)
+	m.IntSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.IntSum().DataPoints().Resize(2)
+	m.IntSum().DataPoints().At(0).SetTimestamp(nowTs)
+	m.IntSum().DataPoints().At(1).SetTimestamp(nowTs)
+
+	m = ilm.Metrics().At(1)
+	m.SetName("stale_gauge")
+	m.SetDataType(pdata.MetricDataTypeDoubleGauge)
+	m.DoubleGauge().DataPoints().Resize(1)
+	m.DoubleGauge().DataPoints().At(0).SetTimestamp(pdata.TimestampUnixNano(now.Add(-time.Hour).UnixNano()))
+
+	m = ilm.Metrics().At(2)
+	m.SetName("nan_histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).SetTimestamp(nowTs)
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{math.NaN()})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, 2})
+
+	m = ilm.Metrics().At(3)
+	m.SetName("unsorted_histo")
+	m.SetDataType(pdata.MetricDataTypeIntHistogram)
+	m.IntHistogram().DataPoints().Resize(1)
+	m.IntHistogram().DataPoints().At(0).SetTimestamp(nowTs)
+	m.IntHistogram().DataPoints().At(0).SetExplicitBounds([]float64{2, 1})
+	m.IntHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, 2, 3})
+
+	core, observedLogs := observer.New(zap.DebugLevel)
+	c, err := NewMetricsConverter(zap.New(core), nil, MetricsConverterOptions{
+		DropZeroValueCounters: true,
+		MaxStaleness:          time.Minute,
+		Clock:                 func() time.Time { return now },
+	})
+	require.NoError(t, err)
+	c.MetricDataToSignalFxV2(rm)
+
+	type dropLog struct {
+		metric     string
+		datapoints int64
+	}
+	got := map[string]dropLog{}
+	for _, entry := range observedLogs.All() {
+		fields := entry.ContextMap()
+		reason, ok := fields["reason"].(string)
+		if !ok {
+			continue
+		}
+		got[reason] = dropLog{metric: fields["metric"].(string), datapoints: fields["datapoints"].(int64)}
+	}
+	assert.Equal(t, map[string]dropLog{
+		dropReasonZeroValue:      {metric: "zero_counter", datapoints: 2},
+		dropReasonStale:          {metric: "stale_gauge", datapoints: 1},
+		dropReasonNaNBounds:      {metric: "nan_histo", datapoints: 2},
+		dropReasonUnsortedBounds: {metric: "unsorted_histo", datapoints: 3},
+	}, got)
+}
+
+func TestMetricDataToSignalFxV2MetricTypesFilter(t *testing.T) {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(1)
+	rm := md.ResourceMetrics().At(0)
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(4)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("int_gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+
+	m = ilm.Metrics().At(1)
+	m.SetName("double_sum")
+	m.SetDataType(pdata.MetricDataTypeDoubleSum)
+	m.DoubleSum().DataPoints().Resize(1)
+
+	m = ilm.Metrics().At(2)
+	m.SetName("int_histo")
+	m.SetDataType(pdata.MetricDataTypeIntHistogram)
+	m.IntHistogram().DataPoints().Resize(1)
+
+	m = ilm.Metrics().At(3)
+	m.SetName("double_histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().DataPoints().Resize(1)
+
+	tests := []struct {
+		name        string
+		options     MetricsConverterOptions
+		wantMetrics []string
+	}{
+		{
+			name:        "all",
+			wantMetrics: []string{"int_gauge", "double_sum", "int_histo_count", "int_histo", "double_histo_count", "double_histo"},
+		},
+		{
+			name: "exclude_histograms",
+			options: MetricsConverterOptions{
+				ExcludeMetricTypes: []pdata.MetricDataType{pdata.MetricDataTypeIntHistogram, pdata.MetricDataTypeDoubleHistogram},
+			},
+			wantMetrics: []string{"int_gauge", "double_sum"},
+		},
+		{
+			name: "include_histograms",
+			options: MetricsConverterOptions{
+				IncludeMetricTypes: []pdata.MetricDataType{pdata.MetricDataTypeIntHistogram, pdata.MetricDataTypeDoubleHistogram},
+			},
+			wantMetrics: []string{"int_histo_count", "int_histo", "double_histo_count", "double_histo"},
+		},
+		{
+			name: "include_and_exclude",
+			options: MetricsConverterOptions{
+				IncludeMetricTypes: []pdata.MetricDataType{pdata.MetricDataTypeIntGauge, pdata.MetricDataTypeIntHistogram},
+				ExcludeMetricTypes: []pdata.MetricDataType{pdata.MetricDataTypeIntHistogram},
+			},
+			wantMetrics: []string{"int_gauge"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewMetricsConverter(zap.NewNop(), nil, tt.options)
+			require.NoError(t, err)
+			dps, numDropped := c.MetricDataToSignalFxV2(rm)
+			assert.Equal(t, 0, numDropped)
+			var gotMetrics []string
+			for _, dp := range dps {
+				gotMetrics = append(gotMetrics, dp.Metric)
+			}
+			assert.Equal(t, tt.wantMetrics, gotMetrics)
+			assert.Equal(t, len(dps), c.DatapointCountEstimate(md))
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2MetricTypeOverrides(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(4)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("requests.handled")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+
+	m = ilm.Metrics().At(1)
+	m.SetName("requests.failed")
+	m.SetDataType(pdata.MetricDataTypeDoubleGauge)
+	m.DoubleGauge().DataPoints().Resize(1)
+
+	m = ilm.Metrics().At(2)
+	m.SetName("queue.size")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+
+	m = ilm.Metrics().At(3)
+	m.SetName("memory.total")
+	m.SetDataType(pdata.MetricDataTypeIntSum)
+	m.IntSum().SetIsMonotonic(true)
+	m.IntSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.IntSum().DataPoints().Resize(1)
+
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+		MetricTypeOverrides: map[string]sfxpb.MetricType{
+			`^requests\.`: sfxpb.MetricType_COUNTER,
+			`^memory\.`:   sfxpb.MetricType_GAUGE,
+		},
+	})
+	require.NoError(t, err)
+	dps, _ := c.MetricDataToSignalFxV2(rm)
+
+	gotTypes := map[string]sfxpb.MetricType{}
+	for _, dp := range dps {
+		gotTypes[dp.Metric] = *dp.MetricType
+	}
+	assert.Equal(t, map[string]sfxpb.MetricType{
+		"requests.handled": sfxpb.MetricType_COUNTER,
+		"requests.failed":  sfxpb.MetricType_COUNTER,
+		"queue.size":       sfxpb.MetricType_GAUGE,
+		"memory.total":     sfxpb.MetricType_GAUGE,
+	}, gotTypes)
+}
+
+func TestMetricDataToSignalFxV2InferCounterFromName(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(5)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("requests_total")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+
+	m = ilm.Metrics().At(1)
+	m.SetName("bytes_total")
+	m.SetDataType(pdata.MetricDataTypeDoubleGauge)
+	m.DoubleGauge().DataPoints().Resize(1)
+
+	m = ilm.Metrics().At(2)
+	m.SetName("total_memory")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+
+	m = ilm.Metrics().At(3)
+	m.SetName("connections_total")
+	m.SetDataType(pdata.MetricDataTypeIntSum)
+	m.IntSum().SetIsMonotonic(false)
+	m.IntSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.IntSum().DataPoints().Resize(1)
+
+	m = ilm.Metrics().At(4)
+	m.SetName("queue.size_total")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+
+	tests := []struct {
+		name      string
+		infer     bool
+		wantTypes map[string]sfxpb.MetricType
+		wantLogs  int
+	}{
+		{
+			name: "disabled",
+			wantTypes: map[string]sfxpb.MetricType{
+				"requests_total":    sfxpb.MetricType_GAUGE,
+				"bytes_total":       sfxpb.MetricType_GAUGE,
+				"total_memory":      sfxpb.MetricType_GAUGE,
+				"connections_total": sfxpb.MetricType_GAUGE,
+				"queue.size_total":  sfxpb.MetricType_GAUGE,
+			},
+		},
+		{
+			name:  "enabled",
+			infer: true,
+			wantTypes: map[string]sfxpb.MetricType{
+				"requests_total":    sfxpb.MetricType_CUMULATIVE_COUNTER,
+				"bytes_total":       sfxpb.MetricType_CUMULATIVE_COUNTER,
+				"total_memory":      sfxpb.MetricType_GAUGE,
+				"connections_total": sfxpb.MetricType_GAUGE,
+				"queue.size_total":  sfxpb.MetricType_GAUGE,
+			},
+			wantLogs: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, observedLogs := observer.New(zap.DebugLevel)
+			c, err := NewMetricsConverter(zap.New(core), nil, MetricsConverterOptions{
+				InferCounterFromName: tt.infer,
+				MetricTypeOverrides: map[string]sfxpb.MetricType{
+					`^queue\.`: sfxpb.MetricType_GAUGE,
+				},
+			})
+			require.NoError(t, err)
+			dps, _ := c.MetricDataToSignalFxV2(rm)
+
+			gotTypes := map[string]sfxpb.MetricType{}
+			for _, dp := range dps {
+				gotTypes[dp.Metric] = *dp.MetricType
+			}
+			assert.Equal(t, tt.wantTypes, gotTypes)
+			assert.Equal(t, tt.wantLogs, observedLogs.FilterMessage("gauge is named like a counter, converting it to a cumulative counter").Len())
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2AnnotateOTLPType(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.Resource().Attributes().InsertString("host.name", "host0")
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(6)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("int_gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+
+	m = ilm.Metrics().At(1)
+	m.SetName("double_gauge")
+	m.SetDataType(pdata.MetricDataTypeDoubleGauge)
+	m.DoubleGauge().DataPoints().Resize(1)
+
+	m = ilm.Metrics().At(2)
+	m.SetName("int_sum")
+	m.SetDataType(pdata.MetricDataTypeIntSum)
+	m.IntSum().SetIsMonotonic(false)
+	m.IntSum().DataPoints().Resize(1)
+
+	m = ilm.Metrics().At(3)
+	m.SetName("double_sum")
+	m.SetDataType(pdata.MetricDataTypeDoubleSum)
+	m.DoubleSum().SetIsMonotonic(true)
+	m.DoubleSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.DoubleSum().DataPoints().Resize(1)
+
+	m = ilm.Metrics().At(4)
+	m.SetName("int_histo")
+	m.SetDataType(pdata.MetricDataTypeIntHistogram)
+	m.IntHistogram().DataPoints().Resize(1)
+	m.IntHistogram().DataPoints().At(0).SetCount(1)
+
+	m = ilm.Metrics().At(5)
+	m.SetName("double_histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).SetCount(1)
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, 0})
+
+	tests := []struct {
+		name      string
+		annotate  bool
+		wantTypes map[string]string
+	}{
+		{
+			name: "disabled",
+			wantTypes: map[string]string{
+				"int_gauge":           "",
+				"double_gauge":        "",
+				"int_sum":             "",
+				"double_sum":          "",
+				"int_histo_count":     "",
+				"int_histo":           "",
+				"double_histo_count":  "",
+				"double_histo":        "",
+				"double_histo_bucket": "",
+			},
+		},
+		{
+			name:     "enabled",
+			annotate: true,
+			wantTypes: map[string]string{
+				"int_gauge":           "int_gauge",
+				"double_gauge":        "double_gauge",
+				"int_sum":             "int_sum",
+				"double_sum":          "double_sum",
+				"int_histo_count":     "int_histogram",
+				"int_histo":           "int_histogram",
+				"double_histo_count":  "double_histogram",
+				"double_histo":        "double_histogram",
+				"double_histo_bucket": "double_histogram",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{AnnotateOTLPType: tt.annotate})
+			require.NoError(t, err)
+			dps, _ := c.MetricDataToSignalFxV2(rm)
+
+			gotTypes := map[string]string{}
+			for _, dp := range dps {
+				gotTypes[dp.Metric] = ""
+				for _, d := range dp.Dimensions {
+					if d.Key == "otlp_type" {
+						gotTypes[dp.Metric] = d.Value
+					}
+				}
+				assert.Equal(t, "host_name", dp.Dimensions[0].Key, dp.Metric)
+			}
+			assert.Equal(t, tt.wantTypes, gotTypes)
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2NonMonotonicSumAs(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(3)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("int_updown")
+	m.SetDataType(pdata.MetricDataTypeIntSum)
+	m.IntSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.IntSum().DataPoints().Resize(1)
+
+	m = ilm.Metrics().At(1)
+	m.SetName("double_updown")
+	m.SetDataType(pdata.MetricDataTypeDoubleSum)
+	m.DoubleSum().SetAggregationTemporality(pdata.AggregationTemporalityDelta)
+	m.DoubleSum().DataPoints().Resize(1)
+
+	m = ilm.Metrics().At(2)
+	m.SetName("int_counter")
+	m.SetDataType(pdata.MetricDataTypeIntSum)
+	m.IntSum().SetIsMonotonic(true)
+	m.IntSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.IntSum().DataPoints().Resize(1)
+
+	tests := []struct {
+		name      string
+		sumAs     NonMonotonicSumAs
+		wantTypes map[string]sfxpb.MetricType
+	}{
+		{
+			name: "default",
+			wantTypes: map[string]sfxpb.MetricType{
+				"int_updown":    sfxpb.MetricType_GAUGE,
+				"double_updown": sfxpb.MetricType_GAUGE,
+				"int_counter":   sfxpb.MetricType_CUMULATIVE_COUNTER,
+			},
+		},
+		{
+			name:  "gauge",
+			sumAs: NonMonotonicSumAsGauge,
+			wantTypes: map[string]sfxpb.MetricType{
+				"int_updown":    sfxpb.MetricType_GAUGE,
+				"double_updown": sfxpb.MetricType_GAUGE,
+				"int_counter":   sfxpb.MetricType_CUMULATIVE_COUNTER,
+			},
+		},
+		{
+			name:  "counter",
+			sumAs: NonMonotonicSumAsCounter,
+			wantTypes: map[string]sfxpb.MetricType{
+				"int_updown":    sfxpb.MetricType_CUMULATIVE_COUNTER,
+				"double_updown": sfxpb.MetricType_COUNTER,
+				"int_counter":   sfxpb.MetricType_CUMULATIVE_COUNTER,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{NonMonotonicSumAs: tt.sumAs})
+			require.NoError(t, err)
+			dps, _ := c.MetricDataToSignalFxV2(rm)
+
+			gotTypes := map[string]sfxpb.MetricType{}
+			for _, dp := range dps {
+				gotTypes[dp.Metric] = *dp.MetricType
+			}
+			assert.Equal(t, tt.wantTypes, gotTypes)
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2InternDimensionStrings(t *testing.T) {
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+		DimensionValueCase: map[string]DimensionValueCase{"http.method": DimensionValueCaseLower},
+	})
+	require.NoError(t, err)
+	want, _ := c.MetricDataToSignalFxV2(newRepetitiveLabeledGaugesResourceMetrics(10))
+
+	c, err = NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+		DimensionValueCase:     map[string]DimensionValueCase{"http.method": DimensionValueCaseLower},
+		InternDimensionStrings: true,
+	})
+	require.NoError(t, err)
+	got, _ := c.MetricDataToSignalFxV2(newRepetitiveLabeledGaugesResourceMetrics(10))
+
+	// Dimension order follows labels map order, compare sorted dimensions.
+	for _, dps := range [][]*sfxpb.DataPoint{want, got} {
+		for _, dp := range dps {
+			sort.Slice(dp.Dimensions, func(i, j int) bool {
+				return dp.Dimensions[i].Key < dp.Dimensions[j].Key
+			})
+		}
+	}
+	assert.Equal(t, want, got)
+	assert.Equal(t, []*sfxpb.Dimension{
+		{Key: "host_name", Value: "host0"},
+		{Key: "http_method", Value: "get"},
+		{Key: "http_status_code", Value: "201"},
+		{Key: "k8s_namespace_name", Value: "default"},
+		{Key: "k8s_pod_name", Value: "pod1"},
+	}, got[1].Dimensions)
+}
+
+func TestMetricDataToSignalFxV2DropEmptyDimensions(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.Resource().Attributes().InsertString("host.name", "host0")
+	rm.Resource().Attributes().InsertString("k8s.namespace.name", "")
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(1)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+	m.IntGauge().DataPoints().At(0).LabelsMap().InitFromMap(map[string]string{"method": "GET", "route": ""})
+
+	tests := []struct {
+		name     string
+		drop     bool
+		wantDims []*sfxpb.Dimension
+	}{
+		{
+			name: "disabled",
+			wantDims: []*sfxpb.Dimension{
+				{Key: "host_name", Value: "host0"},
+				{Key: "k8s_namespace_name", Value: ""},
+				{Key: "method", Value: "GET"},
+				{Key: "route", Value: ""},
+			},
+		},
+		{
+			name: "enabled",
+			drop: true,
+			wantDims: []*sfxpb.Dimension{
+				{Key: "host_name", Value: "host0"},
+				{Key: "method", Value: "GET"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{DropEmptyDimensions: tt.drop})
+			require.NoError(t, err)
+			dps, _ := c.MetricDataToSignalFxV2(rm)
+			require.Len(t, dps, 1)
+			dims := dps[0].Dimensions
+			sort.Slice(dims, func(i, j int) bool {
+				return dims[i].Key < dims[j].Key
+			})
+			assert.Equal(t, tt.wantDims, dims)
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2DimensionValueCase(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.Resource().Attributes().InsertString("cloud.region", "US-East-1")
+	rm.Resource().Attributes().InsertString("host.name", "Host0")
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(1)
+	m := ilm.Metrics().At(0)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(2)
+	m.IntGauge().DataPoints().At(0).LabelsMap().InitFromMap(map[string]string{"env": "Prod", "team": "Core"})
+	m.IntGauge().DataPoints().At(1).LabelsMap().InitFromMap(map[string]string{"env": "Dev", "team": "Edge"})
+
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+		DimensionValueCase: map[string]DimensionValueCase{
+			"cloud.region": DimensionValueCaseLower,
+			"env":          DimensionValueCaseUpper,
+		},
+	})
+	require.NoError(t, err)
+	got, _ := c.MetricDataToSignalFxV2(rm)
+
+	want := []*sfxpb.DataPoint{
+		int64SFxDataPoint("gauge", 0, &sfxMetricTypeGauge, map[string]string{
+			"cloud_region": "us-east-1",
+			"host_name":    "Host0",
+			"env":          "PROD",
+			"team":         "Core",
+		}, 0),
+		int64SFxDataPoint("gauge", 0, &sfxMetricTypeGauge, map[string]string{
+			"cloud_region": "us-east-1",
+			"host_name":    "Host0",
+			"env":          "DEV",
+			"team":         "Edge",
+		}, 0),
+	}
+	sortDimensions(want)
+	sortDimensions(got)
+	assert.Equal(t, want, got)
+}
+
+func TestMetricDataToSignalFxV2TrimDimensionValues(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.Resource().Attributes().InsertString("host.name", " host0\t")
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(1)
+	m := ilm.Metrics().At(0)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(3)
+	m.IntGauge().DataPoints().At(0).LabelsMap().InitFromMap(map[string]string{"env": " prod "})
+	m.IntGauge().DataPoints().At(1).LabelsMap().InitFromMap(map[string]string{"env": "prod"})
+	m.IntGauge().DataPoints().At(2).LabelsMap().InitFromMap(map[string]string{"env": "pre prod "})
+
+	tests := []struct {
+		name     string
+		trim     bool
+		wantEnvs []string
+		wantHost string
+	}{
+		{
+			name:     "disabled",
+			wantEnvs: []string{" prod ", "prod", "pre prod "},
+			wantHost: " host0\t",
+		},
+		{
+			name:     "enabled",
+			trim:     true,
+			wantEnvs: []string{"prod", "prod", "pre prod"},
+			wantHost: "host0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{TrimDimensionValues: tt.trim})
+			require.NoError(t, err)
+			dps, _ := c.MetricDataToSignalFxV2(rm)
+			require.Len(t, dps, 3)
+
+			var gotEnvs []string
+			for _, dp := range dps {
+				for _, d := range dp.Dimensions {
+					switch d.Key {
+					case "env":
+						gotEnvs = append(gotEnvs, d.Value)
+					case "host_name":
+						assert.Equal(t, tt.wantHost, d.Value)
+					}
+				}
+			}
+			assert.Equal(t, tt.wantEnvs, gotEnvs)
+			// Padded and unpadded values only belong to the same series if
+			// trimmed.
+			assert.Equal(t, tt.trim, stringifyDimensions(dps[0].Dimensions, nil) == stringifyDimensions(dps[1].Dimensions, nil))
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2DimensionValueDefaults(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.Resource().Attributes().InsertString("region", "")
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(1)
+	m := ilm.Metrics().At(0)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(3)
+	m.IntGauge().DataPoints().At(0).LabelsMap().InitFromMap(map[string]string{"environment": "", "team": ""})
+	m.IntGauge().DataPoints().At(1).LabelsMap().InitFromMap(map[string]string{"environment": "prod", "team": ""})
+	m.IntGauge().DataPoints().At(2).LabelsMap().InitFromMap(map[string]string{"environment": " ", "team": "core"})
+
+	tests := []struct {
+		name     string
+		options  MetricsConverterOptions
+		wantDims []map[string]string
+	}{
+		{
+			name: "no_defaults",
+			wantDims: []map[string]string{
+				{"region": "", "environment": "", "team": ""},
+				{"region": "", "environment": "prod", "team": ""},
+				{"region": "", "environment": " ", "team": "core"},
+			},
+		},
+		{
+			name: "defaults",
+			options: MetricsConverterOptions{
+				DimensionValueDefaults: map[string]string{"environment": "unknown", "region": "global"},
+			},
+			wantDims: []map[string]string{
+				{"region": "global", "environment": "unknown", "team": ""},
+				{"region": "global", "environment": "prod", "team": ""},
+				{"region": "global", "environment": " ", "team": "core"},
+			},
+		},
+		{
+			name: "defaults_trimmed",
+			options: MetricsConverterOptions{
+				DimensionValueDefaults: map[string]string{"environment": "unknown"},
+				TrimDimensionValues:    true,
+			},
+			wantDims: []map[string]string{
+				{"region": "", "environment": "unknown", "team": ""},
+				{"region": "", "environment": "prod", "team": ""},
+				{"region": "", "environment": "unknown", "team": "core"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewMetricsConverter(zap.NewNop(), nil, tt.options)
+			require.NoError(t, err)
+			dps, _ := c.MetricDataToSignalFxV2(rm)
+			require.Len(t, dps, len(tt.wantDims))
+			for i, dp := range dps {
+				gotDims := make(map[string]string)
+				for _, d := range dp.Dimensions {
+					gotDims[d.Key] = d.Value
+				}
+				assert.Equal(t, tt.wantDims[i], gotDims)
+			}
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2DimensionOrder(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.Resource().Attributes().InsertString("host.name", "host0")
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(2)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+	m.IntGauge().DataPoints().At(0).LabelsMap().Insert("k0", "v0")
+	m.IntGauge().DataPoints().At(0).LabelsMap().Insert("k1", "v1")
+
+	m = ilm.Metrics().At(1)
+	m.SetName("histo")
+	m.SetDataType(pdata.MetricDataTypeIntHistogram)
+	m.IntHistogram().DataPoints().Resize(1)
+	m.IntHistogram().DataPoints().At(0).LabelsMap().Insert("k0", "v0")
+	m.IntHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1})
+
+	dimKeys := func(dp *sfxpb.DataPoint) []string {
+		var keys []string
+		for _, d := range dp.Dimensions {
+			keys = append(keys, d.Key)
+		}
+		return keys
+	}
+
+	tests := []struct {
+		name          string
+		order         DimensionOrder
+		wantGaugeKeys []string
+		wantCountKeys []string
+	}{
+		{
+			name:          "default",
+			wantGaugeKeys: []string{"host_name", "collector_id", "k0", "k1"},
+			wantCountKeys: []string{"host_name", "collector_id", "k0"},
+		},
+		{
+			name:          "resource_first",
+			order:         DimensionOrderResourceFirst,
+			wantGaugeKeys: []string{"host_name", "collector_id", "k0", "k1"},
+			wantCountKeys: []string{"host_name", "collector_id", "k0"},
+		},
+		{
+			name:          "labels_first",
+			order:         DimensionOrderLabelsFirst,
+			wantGaugeKeys: []string{"k0", "k1", "host_name", "collector_id"},
+			wantCountKeys: []string{"k0", "host_name", "collector_id"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+				DimensionOrder:      tt.order,
+				CollectorInstanceID: "collector0",
+			})
+			require.NoError(t, err)
+			dps, _ := c.MetricDataToSignalFxV2(rm)
+			require.Len(t, dps, 4)
+
+			assert.Equal(t, tt.wantGaugeKeys, dimKeys(dps[0]))
+			assert.Equal(t, tt.wantCountKeys, dimKeys(dps[1]))
+			assert.Equal(t, tt.wantCountKeys, dimKeys(dps[2]))
+			assert.Equal(t, append(tt.wantCountKeys, upperBoundDimensionKey), dimKeys(dps[3]))
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2DimensionKeyMapping(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.Resource().Attributes().InsertString("service.name", "checkout")
+	rm.Resource().Attributes().InsertString("host.name", "host0")
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(1)
+	m := ilm.Metrics().At(0)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+	labels := m.IntGauge().DataPoints().At(0).LabelsMap()
+	labels.Insert("http.status_code", "200")
+	// Collides with the mapped http.status_code label.
+	labels.Insert("status", "ok")
+	// Collides with the mapped service.name resource attribute.
+	labels.Insert("service", "other")
+	labels.Insert("http.method", "GET")
+
+	core, observedLogs := observer.New(zap.DebugLevel)
+	c, err := NewMetricsConverter(zap.New(core), nil, MetricsConverterOptions{
+		DimensionKeyMapping: map[string]string{
+			"service.name":     "service",
+			"http.status_code": "status",
+		},
+	})
+	require.NoError(t, err)
+	got, _ := c.MetricDataToSignalFxV2(rm)
+
+	want := []*sfxpb.DataPoint{
+		int64SFxDataPoint("gauge", 0, &sfxMetricTypeGauge, map[string]string{
+			"service":     "checkout",
+			"host_name":   "host0",
+			"status":      "200",
+			"http_method": "GET",
+		}, 0),
+	}
+	sortDimensions(want)
+	sortDimensions(got)
+	assert.Equal(t, want, got)
+
+	logs := observedLogs.FilterMessage("dimension key collides with an existing dimension, dropping dimension").All()
+	require.Len(t, logs, 2)
+	assert.Equal(t, "status", logs[0].ContextMap()["key"])
+	assert.Equal(t, "service", logs[1].ContextMap()["key"])
+}
+
+func TestMetricDataToSignalFxV2MetricNameDelimiter(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     MetricsConverterOptions
+		metricName  string
+		histogram   bool
+		wantMetrics []string
+		wantDims    map[string]string
+	}{
+		{
+			name:        "multi_segment_histogram",
+			options:     MetricsConverterOptions{MetricNameDelimiter: "."},
+			metricName:  "http.server.duration",
+			histogram:   true,
+			wantMetrics: []string{"duration_count", "duration", "duration_bucket", "duration_bucket"},
+			wantDims:    map[string]string{"host_name": "host0", "context": "http_server"},
+		},
+		{
+			name:        "multi_segment_gauge",
+			options:     MetricsConverterOptions{MetricNameDelimiter: "."},
+			metricName:  "http.server.active_requests",
+			wantMetrics: []string{"active_requests"},
+			wantDims:    map[string]string{"host_name": "host0", "context": "http_server"},
+		},
+		{
+			name: "custom_context_dimension",
+			options: MetricsConverterOptions{
+				MetricNameDelimiter:        "/",
+				MetricNameContextDimension: "namespace",
+			},
+			metricName:  "app/db/queries",
+			wantMetrics: []string{"queries"},
+			wantDims:    map[string]string{"host_name": "host0", "namespace": "app_db"},
+		},
+		{
+			name:        "single_segment",
+			options:     MetricsConverterOptions{MetricNameDelimiter: "."},
+			metricName:  "requests",
+			histogram:   true,
+			wantMetrics: []string{"requests_count", "requests", "requests_bucket", "requests_bucket"},
+			wantDims:    map[string]string{"host_name": "host0"},
+		},
+		{
+			name:        "trailing_delimiter",
+			options:     MetricsConverterOptions{MetricNameDelimiter: "."},
+			metricName:  "requests.",
+			wantMetrics: []string{"requests."},
+			wantDims:    map[string]string{"host_name": "host0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rm := pdata.NewResourceMetrics()
+			rm.InitEmpty()
+			rm.Resource().Attributes().InsertString("host.name", "host0")
+			rm.InstrumentationLibraryMetrics().Resize(1)
+			ilm := rm.InstrumentationLibraryMetrics().At(0)
+			ilm.Metrics().Resize(1)
+			m := ilm.Metrics().At(0)
+			m.SetName(tt.metricName)
+			if tt.histogram {
+				m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+				m.DoubleHistogram().DataPoints().Resize(1)
+				m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1})
+				m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, 2})
+			} else {
+				m.SetDataType(pdata.MetricDataTypeIntGauge)
+				m.IntGauge().DataPoints().Resize(1)
+			}
+
+			c, err := NewMetricsConverter(zap.NewNop(), nil, tt.options)
+			require.NoError(t, err)
+			dps, _ := c.MetricDataToSignalFxV2(rm)
+
+			var gotMetrics []string
+			for _, dp := range dps {
+				gotMetrics = append(gotMetrics, dp.Metric)
+				gotDims := map[string]string{}
+				for _, d := range dp.Dimensions {
+					if d.Key != upperBoundDimensionKey {
+						gotDims[d.Key] = d.Value
+					}
+				}
+				assert.Equal(t, tt.wantDims, gotDims)
+			}
+			assert.Equal(t, tt.wantMetrics, gotMetrics)
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2SanitizeMetricNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		metric   string
+		options  MetricsConverterOptions
+		wantName string
+	}{
+		{
+			name:     "disabled",
+			metric:   "http server/duration:ms",
+			wantName: "http server/duration:ms",
+		},
+		{
+			name:     "valid",
+			metric:   "http.server_duration-ms",
+			options:  MetricsConverterOptions{SanitizeMetricNames: true},
+			wantName: "http.server_duration-ms",
+		},
+		{
+			name:     "disallowed_chars",
+			metric:   "http server/duration:ms{p99}",
+			options:  MetricsConverterOptions{SanitizeMetricNames: true},
+			wantName: "http_server_duration_ms_p99_",
+		},
+		{
+			name:     "unicode_letters",
+			metric:   "température°c",
+			options:  MetricsConverterOptions{SanitizeMetricNames: true},
+			wantName: "température_c",
+		},
+		{
+			name:   "after_delimiter_split",
+			metric: "http/server duration",
+			options: MetricsConverterOptions{
+				SanitizeMetricNames: true,
+				MetricNameDelimiter: "/",
+			},
+			wantName: "server_duration",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rm := pdata.NewResourceMetrics()
+			rm.InitEmpty()
+			rm.InstrumentationLibraryMetrics().Resize(1)
+			ilm := rm.InstrumentationLibraryMetrics().At(0)
+			ilm.Metrics().Resize(1)
+			m := ilm.Metrics().At(0)
+			m.SetName(tt.metric)
+			m.SetDataType(pdata.MetricDataTypeIntGauge)
+			m.IntGauge().DataPoints().Resize(1)
+
+			c, err := NewMetricsConverter(zap.NewNop(), nil, tt.options)
+			require.NoError(t, err)
+			dps, _ := c.MetricDataToSignalFxV2(rm)
+			require.Len(t, dps, 1)
+			assert.Equal(t, tt.wantName, dps[0].Metric)
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2LowercaseMetricNames(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(2)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("Queue.Length")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+
+	m = ilm.Metrics().At(1)
+	m.SetName("HTTP.Server.Duration")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, 2})
+
+	tests := []struct {
+		name        string
+		lowercase   bool
+		wantMetrics []string
+	}{
+		{
+			name: "disabled",
+			wantMetrics: []string{
+				"Queue.Length",
+				"HTTP.Server.Duration_count", "HTTP.Server.Duration",
+				"HTTP.Server.Duration_bucket", "HTTP.Server.Duration_bucket",
+			},
+		},
+		{
+			name:      "enabled",
+			lowercase: true,
+			wantMetrics: []string{
+				"queue.length",
+				"http.server.duration_count", "http.server.duration",
+				"http.server.duration_bucket", "http.server.duration_bucket",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{LowercaseMetricNames: tt.lowercase})
+			require.NoError(t, err)
+			dps, _ := c.MetricDataToSignalFxV2(rm)
+
+			var gotMetrics []string
+			for _, dp := range dps {
+				gotMetrics = append(gotMetrics, dp.Metric)
+			}
+			assert.Equal(t, tt.wantMetrics, gotMetrics)
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2MetricNamespaceAttributes(t *testing.T) {
+	tests := []struct {
+		name      string
+		attrs     map[string]string
+		delimiter string
+		wantNames []string
+	}{
+		{
+			name:      "two_attributes",
+			attrs:     map[string]string{"team": "teamA", "service.name": "serviceX"},
+			wantNames: []string{"teamA.serviceX.requests", "teamA.serviceX.latency_count", "teamA.serviceX.latency"},
+		},
+		{
+			name:      "one_attribute",
+			attrs:     map[string]string{"service.name": "serviceX"},
+			wantNames: []string{"serviceX.requests", "serviceX.latency_count", "serviceX.latency"},
+		},
+		{
+			name:      "empty_attribute",
+			attrs:     map[string]string{"team": "", "service.name": "serviceX"},
+			wantNames: []string{"serviceX.requests", "serviceX.latency_count", "serviceX.latency"},
+		},
+		{
+			name:      "no_attribute",
+			attrs:     map[string]string{"host.name": "host0"},
+			wantNames: []string{"requests", "latency_count", "latency"},
+		},
+		{
+			name:      "delimiter",
+			attrs:     map[string]string{"team": "teamA", "service.name": "serviceX"},
+			delimiter: "/",
+			wantNames: []string{"teamA/serviceX/requests", "teamA/serviceX/latency_count", "teamA/serviceX/latency"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rm := pdata.NewResourceMetrics()
+			rm.InitEmpty()
+			for k, v := range tt.attrs {
+				rm.Resource().Attributes().InsertString(k, v)
+			}
+			rm.InstrumentationLibraryMetrics().Resize(1)
+			ilm := rm.InstrumentationLibraryMetrics().At(0)
+			ilm.Metrics().Resize(2)
+
+			m := ilm.Metrics().At(0)
+			m.SetName("requests")
+			m.SetDataType(pdata.MetricDataTypeIntGauge)
+			m.IntGauge().DataPoints().Resize(1)
+
+			m = ilm.Metrics().At(1)
+			m.SetName("latency")
+			m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+			m.DoubleHistogram().DataPoints().Resize(1)
+
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+				MetricNamespaceAttributes: []string{"team", "service.name"},
+				MetricNamespaceDelimiter:  tt.delimiter,
+			})
+			require.NoError(t, err)
+			dps, _ := c.MetricDataToSignalFxV2(rm)
+
+			var gotNames []string
+			for _, dp := range dps {
+				gotNames = append(gotNames, dp.Metric)
+			}
+			assert.Equal(t, tt.wantNames, gotNames)
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2MaxDimensionKeyLength(t *testing.T) {
+	tests := []struct {
+		name           string
+		labels         []string
+		wantKeys       []string
+		wantCollisions []string
+	}{
+		{
+			name:     "at_limit",
+			labels:   []string{"key_ten_ch"},
+			wantKeys: []string{"key_ten_ch"},
+		},
+		{
+			name:     "above_limit",
+			labels:   []string{"key.eleven.", "short"},
+			wantKeys: []string{"key_eleven", "short"},
+		},
+		{
+			name:     "multi_byte_character",
+			labels:   []string{"key_nine_é"},
+			wantKeys: []string{"key_nine_"},
+		},
+		{
+			name:           "collision",
+			labels:         []string{"http_request_method", "http_request_path"},
+			wantKeys:       []string{"http_reque", "http_reque"},
+			wantCollisions: []string{"http_reque"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rm := pdata.NewResourceMetrics()
+			rm.InitEmpty()
+			rm.InstrumentationLibraryMetrics().Resize(1)
+			ilm := rm.InstrumentationLibraryMetrics().At(0)
+			ilm.Metrics().Resize(1)
+			m := ilm.Metrics().At(0)
+			m.SetName("gauge")
+			m.SetDataType(pdata.MetricDataTypeIntGauge)
+			m.IntGauge().DataPoints().Resize(1)
+			for _, l := range tt.labels {
+				m.IntGauge().DataPoints().At(0).LabelsMap().Insert(l, "v")
+			}
+
+			core, observedLogs := observer.New(zap.WarnLevel)
+			c, err := NewMetricsConverter(zap.New(core), nil, MetricsConverterOptions{MaxDimensionKeyLength: 10})
+			require.NoError(t, err)
+			dps, _ := c.MetricDataToSignalFxV2(rm)
+			require.Len(t, dps, 1)
+
+			var gotKeys []string
+			for _, d := range dps[0].Dimensions {
+				gotKeys = append(gotKeys, d.Key)
+			}
+			assert.Equal(t, tt.wantKeys, gotKeys)
+
+			var gotCollisions []string
+			for _, entry := range observedLogs.FilterMessage("dimension keys collide after truncation").All() {
+				assert.Equal(t, "gauge", entry.ContextMap()["metric"])
+				gotCollisions = append(gotCollisions, entry.ContextMap()["key"].(string))
+			}
+			assert.Equal(t, tt.wantCollisions, gotCollisions)
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2ValueTransformer(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(4)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("utilization")
+	m.SetDataType(pdata.MetricDataTypeDoubleGauge)
+	m.DoubleGauge().DataPoints().Resize(3)
+	m.DoubleGauge().DataPoints().At(0).SetValue(120.5)
+	m.DoubleGauge().DataPoints().At(1).SetValue(-3)
+	m.DoubleGauge().DataPoints().At(2).SetValue(50.5)
+
+	m = ilm.Metrics().At(1)
+	m.SetName("utilization")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+	m.IntGauge().DataPoints().At(0).SetValue(150)
+
+	m = ilm.Metrics().At(2)
+	m.SetName("halved")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(2)
+	m.IntGauge().DataPoints().At(0).SetValue(3)
+	m.IntGauge().DataPoints().At(1).SetValue(4)
+
+	m = ilm.Metrics().At(3)
+	m.SetName("other")
+	m.SetDataType(pdata.MetricDataTypeDoubleGauge)
+	m.DoubleGauge().DataPoints().Resize(1)
+	m.DoubleGauge().DataPoints().At(0).SetValue(120.5)
+
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+		ValueTransformer: func(metric string, value float64) float64 {
+			switch metric {
+			case "utilization":
+				return math.Max(0, math.Min(100, value))
+			case "halved":
+				return value / 2
+			}
+			return value
+		},
+	})
+	require.NoError(t, err)
+	got, _ := c.MetricDataToSignalFxV2(rm)
+
+	want := []*sfxpb.DataPoint{
+		doubleSFxDataPoint("utilization", 0, &sfxMetricTypeGauge, nil, 100),
+		doubleSFxDataPoint("utilization", 0, &sfxMetricTypeGauge, nil, 0),
+		doubleSFxDataPoint("utilization", 0, &sfxMetricTypeGauge, nil, 50.5),
+		int64SFxDataPoint("utilization", 0, &sfxMetricTypeGauge, nil, 100),
+		doubleSFxDataPoint("halved", 0, &sfxMetricTypeGauge, nil, 1.5),
+		int64SFxDataPoint("halved", 0, &sfxMetricTypeGauge, nil, 2),
+		doubleSFxDataPoint("other", 0, &sfxMetricTypeGauge, nil, 120.5),
+	}
+	assert.Equal(t, want, got)
+}
+
+func TestMetricDataToSignalFxV2DoubleValuePrecision(t *testing.T) {
+	values := []float64{3.14159, -2.71828, 0.125, 42, math.MaxFloat64}
+	tests := []struct {
+		name       string
+		precision  int
+		wantValues []float64
+	}{
+		{
+			name:       "disabled",
+			wantValues: []float64{3.14159, -2.71828, 0.125, 42, math.MaxFloat64, 3.14159},
+		},
+		{
+			name:       "two_decimals",
+			precision:  2,
+			wantValues: []float64{3.14, -2.72, 0.13, 42, math.MaxFloat64, 3.14},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rm := pdata.NewResourceMetrics()
+			rm.InitEmpty()
+			rm.InstrumentationLibraryMetrics().Resize(1)
+			ilm := rm.InstrumentationLibraryMetrics().At(0)
+			ilm.Metrics().Resize(3)
+
+			m := ilm.Metrics().At(0)
+			m.SetName("gauge")
+			m.SetDataType(pdata.MetricDataTypeDoubleGauge)
+			m.DoubleGauge().DataPoints().Resize(len(values))
+			for i, v := range values {
+				m.DoubleGauge().DataPoints().At(i).SetValue(v)
+			}
+
+			// Single datapoint fast path.
+			m = ilm.Metrics().At(1)
+			m.SetName("sum")
+			m.SetDataType(pdata.MetricDataTypeDoubleSum)
+			m.DoubleSum().DataPoints().Resize(1)
+			m.DoubleSum().DataPoints().At(0).SetValue(3.14159)
+
+			// Int values are not affected.
+			m = ilm.Metrics().At(2)
+			m.SetName("int_gauge")
+			m.SetDataType(pdata.MetricDataTypeIntGauge)
+			m.IntGauge().DataPoints().Resize(1)
+			m.IntGauge().DataPoints().At(0).SetValue(7)
+
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{DoubleValuePrecision: tt.precision})
+			require.NoError(t, err)
+			dps, _ := c.MetricDataToSignalFxV2(rm)
+			require.Len(t, dps, len(values)+2)
+
+			var gotValues []float64
+			for _, dp := range dps[:len(dps)-1] {
+				gotValues = append(gotValues, *dp.Value.DoubleValue)
+			}
+			assert.Equal(t, tt.wantValues, gotValues)
+			assert.EqualValues(t, 7, *dps[len(dps)-1].Value.IntValue)
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2UnsignedCounterStringThreshold(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(3)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("bytes")
+	m.SetDataType(pdata.MetricDataTypeIntSum)
+	m.IntSum().SetIsMonotonic(true)
+	m.IntSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.IntSum().DataPoints().Resize(3)
+	m.IntSum().DataPoints().At(0).SetValue(math.MaxInt64)
+	m.IntSum().DataPoints().At(1).SetValue(math.MinInt64)
+	m.IntSum().DataPoints().At(2).SetValue(-1)
+
+	m = ilm.Metrics().At(1)
+	m.SetName("packets")
+	m.SetDataType(pdata.MetricDataTypeIntSum)
+	m.IntSum().SetIsMonotonic(true)
+	m.IntSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.IntSum().DataPoints().Resize(1)
+	m.IntSum().DataPoints().At(0).SetValue(math.MinInt64 + 1)
+
+	m = ilm.Metrics().At(2)
+	m.SetName("temperature")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+	m.IntGauge().DataPoints().At(0).SetValue(-1)
+
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+		UnsignedCounterStringThreshold: 1 << 63,
+	})
+	require.NoError(t, err)
+	got, _ := c.MetricDataToSignalFxV2(rm)
+
+	strSFxDataPoint := func(metric string, value string) *sfxpb.DataPoint {
+		return &sfxpb.DataPoint{
+			Metric:     metric,
+			MetricType: &sfxMetricTypeCumulativeCounter,
+			Value:      sfxpb.Datum{StrValue: &value},
+			Dimensions: sfxDimensions(nil),
+		}
+	}
+	want := []*sfxpb.DataPoint{
+		int64SFxDataPoint("bytes", 0, &sfxMetricTypeCumulativeCounter, nil, math.MaxInt64),
+		strSFxDataPoint("bytes", "9223372036854775808"),
+		strSFxDataPoint("bytes", "18446744073709551615"),
+		strSFxDataPoint("packets", "9223372036854775809"),
+		int64SFxDataPoint("temperature", 0, &sfxMetricTypeGauge, nil, -1),
+	}
+	assert.Equal(t, want, got)
+}
+
+type recordingObserver struct {
+	summaries           []MetricKindSummary
+	converted           []convertedMetric
+	translationFailures []string
+}
+
+type convertedMetric struct {
+	metric     string
+	duration   time.Duration
+	datapoints int
+}
+
+func (o *recordingObserver) OnMetricKindSummary(summary MetricKindSummary) {
+	o.summaries = append(o.summaries, summary)
+}
+
+func (o *recordingObserver) OnMetricConverted(metric string, duration time.Duration, datapoints int) {
+	o.converted = append(o.converted, convertedMetric{metric: metric, duration: duration, datapoints: datapoints})
+}
+
+func (o *recordingObserver) OnTranslationFailure(metric string) {
+	o.translationFailures = append(o.translationFailures, metric)
+}
+
+func TestMetricDataToSignalFxV2MetricKindSummary(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(2)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(4)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+
+	m = ilm.Metrics().At(1)
+	m.SetName("cumulative_counter")
+	m.SetDataType(pdata.MetricDataTypeDoubleSum)
+	m.DoubleSum().SetIsMonotonic(true)
+	m.DoubleSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+
+	m = ilm.Metrics().At(2)
+	m.SetName("non_monotonic_cumulative_sum")
+	m.SetDataType(pdata.MetricDataTypeDoubleSum)
+	m.DoubleSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+
+	m = ilm.Metrics().At(3)
+	m.SetName("delta_histogram")
+	m.SetDataType(pdata.MetricDataTypeIntHistogram)
+	m.IntHistogram().SetAggregationTemporality(pdata.AggregationTemporalityDelta)
+
+	ilm = rm.InstrumentationLibraryMetrics().At(1)
+	ilm.Metrics().Resize(2)
+
+	m = ilm.Metrics().At(0)
+	m.SetName("other_gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+
+	m = ilm.Metrics().At(1)
+	m.SetName("other_cumulative_counter")
+	m.SetDataType(pdata.MetricDataTypeDoubleSum)
+	m.DoubleSum().SetIsMonotonic(true)
+	m.DoubleSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+
+	obs := &recordingObserver{}
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{Observer: obs})
+	require.NoError(t, err)
+	c.MetricDataToSignalFxV2(rm)
+
+	require.Len(t, obs.summaries, 1)
+	assert.Equal(t, MetricKindSummary{
+		{DataType: pdata.MetricDataTypeIntGauge}: 2,
+		{
+			DataType:    pdata.MetricDataTypeDoubleSum,
+			Temporality: pdata.AggregationTemporalityCumulative,
+			Monotonic:   true,
+		}: 2,
+		{
+			DataType:    pdata.MetricDataTypeDoubleSum,
+			Temporality: pdata.AggregationTemporalityCumulative,
+		}: 1,
+		{
+			DataType:    pdata.MetricDataTypeIntHistogram,
+			Temporality: pdata.AggregationTemporalityDelta,
+		}: 1,
+	}, obs.summaries[0])
+}
+
+func TestMetricDataToSignalFxV2MetricConverted(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(3)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(3)
+
+	m = ilm.Metrics().At(1)
+	m.SetName("histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, 2})
+
+	m = ilm.Metrics().At(2)
+	m.SetName("no_type")
+
+	obs := &recordingObserver{}
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{Observer: obs})
+	require.NoError(t, err)
+	start := time.Now()
+	c.MetricDataToSignalFxV2(rm)
+	elapsed := time.Since(start)
+
+	require.Len(t, obs.converted, 3)
+	for i, want := range []struct {
+		metric     string
+		datapoints int
+	}{
+		{metric: "gauge", datapoints: 3},
+		{metric: "histo", datapoints: 4},
+		{metric: "no_type", datapoints: 0},
+	} {
+		got := obs.converted[i]
+		assert.Equal(t, want.metric, got.metric)
+		assert.Equal(t, want.datapoints, got.datapoints)
+		assert.GreaterOrEqual(t, int64(got.duration), int64(0))
+		assert.LessOrEqual(t, int64(got.duration), int64(elapsed))
+	}
+}
+
+func TestMetricDataToSignalFxV2MetricConvertedDropped(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(3)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("requests")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(2)
+
+	m = ilm.Metrics().At(1)
+	m.SetName("requests")
+	m.SetDataType(pdata.MetricDataTypeIntSum)
+	m.IntSum().SetIsMonotonic(true)
+	m.IntSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.IntSum().DataPoints().Resize(1)
+
+	m = ilm.Metrics().At(2)
+	m.SetName("histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, 2})
+
+	obs := &recordingObserver{}
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+		Observer:                   obs,
+		DropConflictingMetricTypes: true,
+		MaxDatapointsPerBatch:      3,
+		EmitConversionStats:        true,
+	})
+	require.NoError(t, err)
+	dps, _ := c.MetricDataToSignalFxV2(rm)
+	// 2 gauges and their conversion stats datapoint.
+	require.Len(t, dps, 3)
+
+	require.Len(t, obs.converted, 3)
+	for i, want := range []struct {
+		metric     string
+		datapoints int
+	}{
+		{metric: "requests", datapoints: 2},
+		// Dropped because of the type conflict.
+		{metric: "requests", datapoints: 0},
+		// Dropped because of the batch limit.
+		{metric: "histo", datapoints: 0},
+	} {
+		assert.Equal(t, want.metric, obs.converted[i].metric)
+		assert.Equal(t, want.datapoints, obs.converted[i].datapoints)
+	}
+}
+
+func TestMetricDataToSignalFxV2MaxDimensions(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.Resource().Attributes().InsertString("r0", "v")
+	rm.Resource().Attributes().InsertString("r1", "v")
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(2)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+	m.IntGauge().DataPoints().At(0).LabelsMap().Insert("l0", "v")
+
+	m = ilm.Metrics().At(1)
+	m.SetName("histo")
+	m.SetDataType(pdata.MetricDataTypeIntHistogram)
+	m.IntHistogram().DataPoints().Resize(1)
+	m.IntHistogram().DataPoints().At(0).LabelsMap().Insert("l0", "v")
+	m.IntHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1})
+
+	tests := []struct {
+		name     string
+		options  MetricsConverterOptions
+		wantDims map[string][]string
+		wantWarn bool
+	}{
+		{
+			name:    "no_limit",
+			options: MetricsConverterOptions{},
+			wantDims: map[string][]string{
+				"gauge":        {"r0", "r1", "l0"},
+				"histo_bucket": {"r0", "r1", "l0", "upper_bound"},
+			},
+		},
+		{
+			name:    "at_limit",
+			options: MetricsConverterOptions{MaxDimensions: 4},
+			wantDims: map[string][]string{
+				"gauge":        {"r0", "r1", "l0"},
+				"histo_bucket": {"r0", "r1", "l0", "upper_bound"},
+			},
+		},
+		{
+			name:    "above_limit_resource_priority",
+			options: MetricsConverterOptions{MaxDimensions: 2},
+			wantDims: map[string][]string{
+				"gauge":        {"r0", "r1"},
+				"histo_bucket": {"r0", "upper_bound"},
+			},
+			wantWarn: true,
+		},
+		{
+			name: "above_limit_labels_priority",
+			options: MetricsConverterOptions{
+				MaxDimensions:     2,
+				DimensionPriority: DimensionPriorityLabels,
+			},
+			wantDims: map[string][]string{
+				"gauge":        {"r0", "l0"},
+				"histo_bucket": {"l0", "upper_bound"},
+			},
+			wantWarn: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, observedLogs := observer.New(zap.WarnLevel)
+			c, err := NewMetricsConverter(zap.New(core), nil, tt.options)
+			require.NoError(t, err)
+			dps, _ := c.MetricDataToSignalFxV2(rm)
+
+			gotDims := map[string][]string{}
+			for _, dp := range dps {
+				if _, ok := tt.wantDims[dp.Metric]; !ok {
+					continue
+				}
+				var keys []string
+				for _, d := range dp.Dimensions {
+					keys = append(keys, d.Key)
+				}
+				gotDims[dp.Metric] = keys
+			}
+			assert.Equal(t, tt.wantDims, gotDims)
+
+			if tt.wantWarn {
+				require.Equal(t, 1, observedLogs.Len())
+				entry := observedLogs.All()[0]
+				assert.Equal(t, "datapoints have too many dimensions, dropping dimensions", entry.Message)
+				assert.Equal(t, int64(4), entry.ContextMap()["datapoints"])
+			} else {
+				assert.Equal(t, 0, observedLogs.Len())
+			}
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2MaxDimensionsBlocked(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(1)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+	m.IntGauge().DataPoints().At(0).LabelsMap().InitFromMap(map[string]string{
+		"l0":     "v",
+		"l1":     "v",
+		"secret": "v",
+	})
+
+	core, observedLogs := observer.New(zap.WarnLevel)
+	c, err := NewMetricsConverter(zap.New(core), nil, MetricsConverterOptions{
+		MaxDimensions:        2,
+		BlockedDimensionKeys: []string{"secret"},
+	})
+	require.NoError(t, err)
+	dps, _ := c.MetricDataToSignalFxV2(rm)
+	require.Len(t, dps, 1)
+
+	// The blocked dimension doesn't push the datapoint over the limit.
+	var gotKeys []string
+	for _, d := range dps[0].Dimensions {
+		gotKeys = append(gotKeys, d.Key)
+	}
+	assert.ElementsMatch(t, []string{"l0", "l1"}, gotKeys)
+	assert.Equal(t, 0, observedLogs.FilterMessage("datapoints have too many dimensions, dropping dimensions").Len())
+}
+
+func TestMetricDataToSignalFxV2DedupLatestGauge(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(2)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeDoubleGauge)
+	dps := m.DoubleGauge().DataPoints()
+	dps.Resize(4)
+	for i, v := range []struct {
+		ts    int64
+		value float64
+		host  string
+	}{
+		{ts: 2, value: 2, host: "a"},
+		{ts: 3, value: 3, host: "a"},
+		{ts: 1, value: 1, host: "a"},
+		{ts: 1, value: 10, host: "b"},
+	} {
+		dps.At(i).SetTimestamp(pdata.TimestampUnixNano(v.ts * 1e6))
+		dps.At(i).SetValue(v.value)
+		dps.At(i).LabelsMap().Insert("host", v.host)
+	}
+
+	m = ilm.Metrics().At(1)
+	m.SetName("counter")
+	m.SetDataType(pdata.MetricDataTypeIntSum)
+	m.IntSum().SetIsMonotonic(true)
+	m.IntSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.IntSum().DataPoints().Resize(2)
+	m.IntSum().DataPoints().At(0).SetTimestamp(pdata.TimestampUnixNano(2e6))
+	m.IntSum().DataPoints().At(1).SetTimestamp(pdata.TimestampUnixNano(1e6))
+
+	tests := []struct {
+		name    string
+		options MetricsConverterOptions
+		want    []*sfxpb.DataPoint
+	}{
+		{
+			name: "disabled",
+			want: []*sfxpb.DataPoint{
+				doubleSFxDataPoint("gauge", 2, &sfxMetricTypeGauge, map[string]string{"host": "a"}, 2),
+				doubleSFxDataPoint("gauge", 3, &sfxMetricTypeGauge, map[string]string{"host": "a"}, 3),
+				doubleSFxDataPoint("gauge", 1, &sfxMetricTypeGauge, map[string]string{"host": "a"}, 1),
+				doubleSFxDataPoint("gauge", 1, &sfxMetricTypeGauge, map[string]string{"host": "b"}, 10),
+				int64SFxDataPoint("counter", 2, &sfxMetricTypeCumulativeCounter, map[string]string{}, 0),
+				int64SFxDataPoint("counter", 1, &sfxMetricTypeCumulativeCounter, map[string]string{}, 0),
+			},
+		},
+		{
+			name:    "enabled",
+			options: MetricsConverterOptions{DedupLatestGauge: true},
+			want: []*sfxpb.DataPoint{
+				doubleSFxDataPoint("gauge", 3, &sfxMetricTypeGauge, map[string]string{"host": "a"}, 3),
+				doubleSFxDataPoint("gauge", 1, &sfxMetricTypeGauge, map[string]string{"host": "b"}, 10),
+				int64SFxDataPoint("counter", 2, &sfxMetricTypeCumulativeCounter, map[string]string{}, 0),
+				int64SFxDataPoint("counter", 1, &sfxMetricTypeCumulativeCounter, map[string]string{}, 0),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewMetricsConverter(zap.NewNop(), nil, tt.options)
+			require.NoError(t, err)
+			got, numDropped := c.MetricDataToSignalFxV2(rm)
+			assert.Equal(t, 0, numDropped)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2InfinityBoundDimensionValue(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(2)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("int_histo")
+	m.SetDataType(pdata.MetricDataTypeIntHistogram)
+	m.IntHistogram().DataPoints().Resize(1)
+	m.IntHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1, 2})
+	m.IntHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, 2, 3})
+
+	m = ilm.Metrics().At(1)
+	m.SetName("double_histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1, 2})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, 2, 3})
+
+	tests := []struct {
+		name       string
+		options    MetricsConverterOptions
+		wantBounds []string
+	}{
+		{
+			name:       "default",
+			wantBounds: []string{"1", "2", "+Inf", "1", "2", "+Inf"},
+		},
+		{
+			name:       "custom",
+			options:    MetricsConverterOptions{InfinityBoundDimensionValue: "Inf"},
+			wantBounds: []string{"1", "2", "Inf", "1", "2", "Inf"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewMetricsConverter(zap.NewNop(), nil, tt.options)
+			require.NoError(t, err)
+			dps, _ := c.MetricDataToSignalFxV2(rm)
+			var gotBounds []string
+			for _, dp := range dps {
+				for _, d := range dp.Dimensions {
+					if d.Key == upperBoundDimensionKey {
+						gotBounds = append(gotBounds, d.Value)
+					}
+				}
+			}
+			assert.Equal(t, tt.wantBounds, gotBounds)
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2InfinityBucketMetricSuffix(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(2)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("int_histo")
+	m.SetDataType(pdata.MetricDataTypeIntHistogram)
+	m.IntHistogram().DataPoints().Resize(1)
+	m.IntHistogram().DataPoints().At(0).LabelsMap().Insert("k0", "v0")
+	m.IntHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1})
+	m.IntHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, 2})
+
+	m = ilm.Metrics().At(1)
+	m.SetName("double_histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).LabelsMap().Insert("k0", "v0")
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, 2})
+
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+		InfinityBucketMetricSuffix: "_bucket_inf",
+		OmitHistogramSum:           true,
+	})
+	require.NoError(t, err)
+	dps, _ := c.MetricDataToSignalFxV2(rm)
+
+	type bucket struct {
+		metric string
+		dims   map[string]string
+		value  int64
+	}
+	var got []bucket
+	for _, dp := range dps {
+		dims := map[string]string{}
+		for _, d := range dp.Dimensions {
+			dims[d.Key] = d.Value
+		}
+		got = append(got, bucket{metric: dp.Metric, dims: dims, value: *dp.Value.IntValue})
+	}
+	assert.Equal(t, []bucket{
+		{metric: "int_histo_count", dims: map[string]string{"k0": "v0"}, value: 0},
+		{metric: "int_histo_bucket", dims: map[string]string{"k0": "v0", upperBoundDimensionKey: "1"}, value: 1},
+		{metric: "int_histo_bucket_inf", dims: map[string]string{"k0": "v0"}, value: 2},
+		{metric: "double_histo_count", dims: map[string]string{"k0": "v0"}, value: 0},
+		{metric: "double_histo_bucket", dims: map[string]string{"k0": "v0", upperBoundDimensionKey: "1"}, value: 1},
+		{metric: "double_histo_bucket_inf", dims: map[string]string{"k0": "v0"}, value: 2},
+	}, got)
+}
+
+func TestMetricDataToSignalFxV2BucketBoundPrecision(t *testing.T) {
+	tests := []struct {
+		name       string
+		precision  int
+		wantBounds []string
+	}{
+		{
+			name:       "default",
+			wantBounds: []string{"0.30000000000000004", "1234.5678", "1e-07", "+Inf"},
+		},
+		{
+			name:       "precision_15",
+			precision:  15,
+			wantBounds: []string{"0.3", "1234.5678", "1e-07", "+Inf"},
+		},
+		{
+			name:       "precision_3",
+			precision:  3,
+			wantBounds: []string{"0.3", "1230", "1e-07", "+Inf"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rm := pdata.NewResourceMetrics()
+			rm.InitEmpty()
+			rm.InstrumentationLibraryMetrics().Resize(1)
+			ilm := rm.InstrumentationLibraryMetrics().At(0)
+			ilm.Metrics().Resize(2)
+
+			m := ilm.Metrics().At(0)
+			m.SetName("histo")
+			m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+			m.DoubleHistogram().DataPoints().Resize(1)
+			m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1e-7, 0.30000000000000004, 1234.5678})
+			m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, 2, 3, 4})
+
+			m = ilm.Metrics().At(1)
+			m.SetName("gauge")
+			m.SetDataType(pdata.MetricDataTypeDoubleGauge)
+			m.DoubleGauge().DataPoints().Resize(1)
+			m.DoubleGauge().DataPoints().At(0).SetValue(0.30000000000000004)
+
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{BucketBoundPrecision: tt.precision})
+			require.NoError(t, err)
+			dps, _ := c.MetricDataToSignalFxV2(rm)
+
+			gotBounds := map[string]bool{}
+			for _, dp := range dps {
+				switch dp.Metric {
+				case "histo_bucket":
+					for _, d := range dp.Dimensions {
+						if d.Key == upperBoundDimensionKey {
+							gotBounds[d.Value] = true
+						}
+					}
+				case "gauge":
+					// Values are not rounded.
+					assert.Equal(t, 0.30000000000000004, *dp.Value.DoubleValue)
+				}
+			}
+			wantBounds := map[string]bool{}
+			for _, b := range tt.wantBounds {
+				wantBounds[b] = true
+			}
+			assert.Equal(t, wantBounds, gotBounds)
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2FullPrecisionBucketBounds(t *testing.T) {
+	nextBound := math.Nextafter(0.1, 1)
+	tests := []struct {
+		name       string
+		options    MetricsConverterOptions
+		wantBounds []string
+	}{
+		{
+			name:       "default",
+			wantBounds: []string{"1e-07", "0.1", "0.10000000000000002", "+Inf"},
+		},
+		{
+			name:       "full_precision",
+			options:    MetricsConverterOptions{FullPrecisionBucketBounds: true},
+			wantBounds: []string{"9.9999999999999995e-08", "0.10000000000000001", "0.10000000000000002", "+Inf"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rm := pdata.NewResourceMetrics()
+			rm.InitEmpty()
+			rm.InstrumentationLibraryMetrics().Resize(1)
+			ilm := rm.InstrumentationLibraryMetrics().At(0)
+			ilm.Metrics().Resize(1)
+
+			m := ilm.Metrics().At(0)
+			m.SetName("histo")
+			m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+			m.DoubleHistogram().DataPoints().Resize(1)
+			m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1e-7, 0.1, nextBound})
+			m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, 2, 3, 4})
+
+			c, err := NewMetricsConverter(zap.NewNop(), nil, tt.options)
+			require.NoError(t, err)
+			dps, _ := c.MetricDataToSignalFxV2(rm)
+
+			var gotBounds []string
+			for _, dp := range dps {
+				if dp.Metric != "histo_bucket" {
+					continue
+				}
+				for _, d := range dp.Dimensions {
+					if d.Key == upperBoundDimensionKey {
+						gotBounds = append(gotBounds, d.Value)
+					}
+				}
+			}
+			assert.Equal(t, tt.wantBounds, gotBounds)
+
+			// Distinct bounds must never alias to the same dimension value.
+			parsed, err := strconv.ParseFloat(gotBounds[2], 64)
+			require.NoError(t, err)
+			assert.Equal(t, nextBound, parsed)
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2HistogramBucketTemporality(t *testing.T) {
+	tests := []struct {
+		name        string
+		temporality pdata.AggregationTemporality
+		wantType    *sfxpb.MetricType
+	}{
+		{
+			name:        "delta",
+			temporality: pdata.AggregationTemporalityDelta,
+			wantType:    &sfxMetricTypeCounter,
+		},
+		{
+			name:        "cumulative",
+			temporality: pdata.AggregationTemporalityCumulative,
+			wantType:    &sfxMetricTypeCumulativeCounter,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rm := pdata.NewResourceMetrics()
+			rm.InitEmpty()
+			rm.InstrumentationLibraryMetrics().Resize(1)
+			ilm := rm.InstrumentationLibraryMetrics().At(0)
+			ilm.Metrics().Resize(2)
+
+			m := ilm.Metrics().At(0)
+			m.SetName("int_histo")
+			m.SetDataType(pdata.MetricDataTypeIntHistogram)
+			m.IntHistogram().SetAggregationTemporality(tt.temporality)
+			m.IntHistogram().DataPoints().Resize(1)
+			m.IntHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1, 2})
+			m.IntHistogram().DataPoints().At(0).SetBucketCounts([]uint64{4, 0, 2})
+
+			m = ilm.Metrics().At(1)
+			m.SetName("double_histo")
+			m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+			m.DoubleHistogram().SetAggregationTemporality(tt.temporality)
+			m.DoubleHistogram().DataPoints().Resize(1)
+			m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1, 2})
+			m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{4, 0, 2})
+
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{})
+			require.NoError(t, err)
+			dps, _ := c.MetricDataToSignalFxV2(rm)
+
+			var gotCounts []int64
+			for _, dp := range dps {
+				assert.Equal(t, tt.wantType, dp.MetricType)
+				if strings.HasSuffix(dp.Metric, "_bucket") {
+					gotCounts = append(gotCounts, *dp.Value.IntValue)
+				}
+			}
+			// Bucket counts are emitted per bucket for both temporalities.
+			assert.Equal(t, []int64{4, 0, 2, 4, 0, 2}, gotCounts)
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2TimestampResolution(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(1)
+	m := ilm.Metrics().At(0)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+	m.IntGauge().DataPoints().At(0).SetTimestamp(pdata.TimestampUnixNano(1600000000123456789))
+
+	tests := []struct {
+		name       string
+		resolution TimestampResolution
+		want       int64
+	}{
+		{
+			name: "default",
+			want: 1600000000123,
+		},
+		{
+			name:       "millis",
+			resolution: TimestampResolutionMillis,
+			want:       1600000000123,
+		},
+		{
+			name:       "micros",
+			resolution: TimestampResolutionMicros,
+			want:       1600000000123456,
+		},
+		{
+			name:       "nanos",
+			resolution: TimestampResolutionNanos,
+			want:       1600000000123456789,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{TimestampResolution: tt.resolution})
+			require.NoError(t, err)
+			dps, dropped := c.MetricDataToSignalFxV2(rm)
+			assert.Zero(t, dropped)
+			require.Len(t, dps, 1)
+			assert.Equal(t, tt.want, dps[0].Timestamp)
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2HistogramSumAsGauge(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(2)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("int_histo")
+	m.SetDataType(pdata.MetricDataTypeIntHistogram)
+	m.IntHistogram().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.IntHistogram().DataPoints().Resize(1)
+	m.IntHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1, 2})
+	m.IntHistogram().DataPoints().At(0).SetBucketCounts([]uint64{4, 0, 2})
+
+	m = ilm.Metrics().At(1)
+	m.SetName("double_histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().SetAggregationTemporality(pdata.AggregationTemporalityDelta)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1, 2})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{4, 0, 2})
+
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{HistogramSumAsGauge: true})
+	require.NoError(t, err)
+	dps, _ := c.MetricDataToSignalFxV2(rm)
+	require.Len(t, dps, 10)
+
+	gotTypes := map[string]*sfxpb.MetricType{}
+	for _, dp := range dps {
+		gotTypes[dp.Metric] = dp.MetricType
+	}
+	assert.Equal(t, map[string]*sfxpb.MetricType{
+		"int_histo":           &sfxMetricTypeGauge,
+		"int_histo_count":     &sfxMetricTypeCumulativeCounter,
+		"int_histo_bucket":    &sfxMetricTypeCumulativeCounter,
+		"double_histo":        &sfxMetricTypeGauge,
+		"double_histo_count":  &sfxMetricTypeCounter,
+		"double_histo_bucket": &sfxMetricTypeCounter,
+	}, gotTypes)
+}
+
+func TestMetricDataToSignalFxV2HistogramBucketsAsGauge(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(2)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("int_histo")
+	m.SetDataType(pdata.MetricDataTypeIntHistogram)
+	m.IntHistogram().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.IntHistogram().DataPoints().Resize(1)
+	m.IntHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1, 2})
+	m.IntHistogram().DataPoints().At(0).SetBucketCounts([]uint64{4, 0, 2})
+
+	m = ilm.Metrics().At(1)
+	m.SetName("double_histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().SetAggregationTemporality(pdata.AggregationTemporalityDelta)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1, 2})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{4, 0, 2})
+
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{HistogramBucketsAsGauge: true})
+	require.NoError(t, err)
+	dps, _ := c.MetricDataToSignalFxV2(rm)
+	require.Len(t, dps, 10)
+
+	gotTypes := map[string]*sfxpb.MetricType{}
+	for _, dp := range dps {
+		gotTypes[dp.Metric] = dp.MetricType
+	}
+	assert.Equal(t, map[string]*sfxpb.MetricType{
+		"int_histo":           &sfxMetricTypeCumulativeCounter,
+		"int_histo_count":     &sfxMetricTypeCumulativeCounter,
+		"int_histo_bucket":    &sfxMetricTypeGauge,
+		"double_histo":        &sfxMetricTypeCounter,
+		"double_histo_count":  &sfxMetricTypeCounter,
+		"double_histo_bucket": &sfxMetricTypeGauge,
+	}, gotTypes)
+}
+
+func TestMetricDataToSignalFxV2IncludeBucketIndex(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.Resource().Attributes().InsertString("host", "host0")
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(2)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("int_histo")
+	m.SetDataType(pdata.MetricDataTypeIntHistogram)
+	m.IntHistogram().DataPoints().Resize(1)
+	m.IntHistogram().DataPoints().At(0).SetExplicitBounds([]float64{5, 10, 100})
+	m.IntHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, 2, 3, 4})
+
+	m = ilm.Metrics().At(1)
+	m.SetName("double_histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{5, 10, 100})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, 2, 3, 4})
+
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+		IncludeBucketIndex: true,
+		// The bucket index must be kept like the upper bound.
+		MaxDimensions: 2,
+	})
+	require.NoError(t, err)
+	dps, _ := c.MetricDataToSignalFxV2(rm)
+
+	wantIndexes := map[string]string{
+		"5":    "0",
+		"10":   "1",
+		"100":  "2",
+		"+Inf": "3",
+	}
+	gotIndexes := map[string]map[string]string{}
+	for _, dp := range dps {
+		dims := map[string]string{}
+		for _, d := range dp.Dimensions {
+			dims[d.Key] = d.Value
+		}
+		if !strings.HasSuffix(dp.Metric, "_bucket") {
+			assert.NotContains(t, dims, bucketIndexDimensionKey)
+			continue
+		}
+		if gotIndexes[dp.Metric] == nil {
+			gotIndexes[dp.Metric] = map[string]string{}
+		}
+		gotIndexes[dp.Metric][dims[upperBoundDimensionKey]] = dims[bucketIndexDimensionKey]
+	}
+	assert.Equal(t, map[string]map[string]string{
+		"int_histo_bucket":    wantIndexes,
+		"double_histo_bucket": wantIndexes,
+	}, gotIndexes)
+}
+
+func TestMetricDataToSignalFxV2MaxHistogramBuckets(t *testing.T) {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(1)
+	rm := md.ResourceMetrics().At(0)
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(1)
+	m := ilm.Metrics().At(0)
+	m.SetName("histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).SetCount(21)
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1, 2, 3, 4, 5})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, 2, 3, 4, 5, 6})
+
+	tests := []struct {
+		name        string
+		maxBuckets  int
+		overflow    HistogramBucketOverflow
+		wantBuckets map[string]int64
+		wantLog     string
+	}{
+		{
+			name: "disabled",
+			wantBuckets: map[string]int64{
+				"1": 1, "2": 2, "3": 3, "4": 4, "5": 5, "+Inf": 6,
+			},
+		},
+		{
+			name:       "not_exceeded",
+			maxBuckets: 6,
+			wantBuckets: map[string]int64{
+				"1": 1, "2": 2, "3": 3, "4": 4, "5": 5, "+Inf": 6,
+			},
+		},
+		{
+			name:        "drop",
+			maxBuckets:  4,
+			wantBuckets: map[string]int64{},
+			wantLog:     "histogram has too many buckets, dropping buckets",
+		},
+		{
+			name:       "merge",
+			maxBuckets: 4,
+			overflow:   HistogramBucketOverflowMerge,
+			wantBuckets: map[string]int64{
+				"2": 3, "4": 7, "+Inf": 11,
+			},
+			wantLog: "histogram has too many buckets, merging buckets",
+		},
+		{
+			name:       "merge_single_bucket",
+			maxBuckets: 1,
+			overflow:   HistogramBucketOverflowMerge,
+			wantBuckets: map[string]int64{
+				"+Inf": 21,
+			},
+			wantLog: "histogram has too many buckets, merging buckets",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.WarnLevel)
+			c, err := NewMetricsConverter(zap.New(core), nil, MetricsConverterOptions{
+				MaxHistogramBuckets:     tt.maxBuckets,
+				HistogramBucketOverflow: tt.overflow,
+			})
+			require.NoError(t, err)
+			dps, _ := c.MetricsToSignalFxV2(md)
+			assert.Equal(t, len(dps), c.DatapointCountEstimate(md))
+
+			gotBuckets := map[string]int64{}
+			var gotCount int64
+			for _, dp := range dps {
+				switch dp.Metric {
+				case "histo_count":
+					gotCount = *dp.Value.IntValue
+				case "histo_bucket":
+					for _, d := range dp.Dimensions {
+						if d.Key == upperBoundDimensionKey {
+							gotBuckets[d.Value] = *dp.Value.IntValue
+						}
+					}
+				}
+			}
+			assert.EqualValues(t, 21, gotCount)
+			assert.Equal(t, tt.wantBuckets, gotBuckets)
+
+			if tt.wantLog == "" {
+				assert.Zero(t, logs.Len())
+				return
+			}
+			require.Equal(t, 1, logs.Len())
+			assert.Equal(t, tt.wantLog, logs.All()[0].Message)
+			assert.EqualValues(t, tt.maxBuckets, logs.All()[0].ContextMap()["max_histogram_buckets"])
+		})
+	}
+}
+
+func TestEstimateQuantile(t *testing.T) {
+	tests := []struct {
+		name   string
+		q      float64
+		bounds []float64
+		counts []uint64
+		want   float64
+		wantOk bool
+	}{
+		{
+			name:   "uniform_median",
+			q:      0.5,
+			bounds: []float64{25, 50, 75},
+			counts: []uint64{25, 25, 25, 25},
+			want:   50,
+			wantOk: true,
+		},
+		{
+			name:   "interpolated_median",
+			q:      0.5,
+			bounds: []float64{10, 20, 30, 40},
+			counts: []uint64{10, 20, 30, 40, 0},
+			want:   20 + 10*20.0/30,
+			wantOk: true,
+		},
+		{
+			name:   "interpolated_p90",
+			q:      0.9,
+			bounds: []float64{10, 20, 30, 40},
+			counts: []uint64{10, 20, 30, 40, 0},
+			want:   37.5,
+			wantOk: true,
+		},
+		{
+			name:   "first_bucket_from_zero",
+			q:      0.05,
+			bounds: []float64{10, 20, 30, 40},
+			counts: []uint64{10, 20, 30, 40, 0},
+			want:   5,
+			wantOk: true,
+		},
+		{
+			name:   "first_bucket_not_positive",
+			q:      0.05,
+			bounds: []float64{-10, 20},
+			counts: []uint64{10, 10, 0},
+			want:   -10,
+			wantOk: true,
+		},
+		{
+			name:   "skips_empty_buckets",
+			q:      0.5,
+			bounds: []float64{10, 20, 30},
+			counts: []uint64{0, 0, 4, 0},
+			want:   25,
+			wantOk: true,
+		},
+		{
+			name:   "infinity_bucket",
+			q:      0.95,
+			bounds: []float64{25, 50, 75},
+			counts: []uint64{25, 25, 25, 25},
+			want:   75,
+			wantOk: true,
+		},
+		{
+			name:   "no_observations",
+			q:      0.5,
+			bounds: []float64{10},
+			counts: []uint64{0, 0},
+		},
+		{
+			name:   "no_bounds",
+			q:      0.5,
+			counts: []uint64{10},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := estimateQuantile(tt.q, tt.bounds, tt.counts)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.InDelta(t, tt.want, got, 1e-9)
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2EmitQuantileEstimates(t *testing.T) {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(1)
+	rm := md.ResourceMetrics().At(0)
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(2)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("latency")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).LabelsMap().Insert("route", "/a")
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{10, 20, 30, 40})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{10, 20, 30, 40, 0})
+
+	// No observations, no estimates.
+	m = ilm.Metrics().At(1)
+	m.SetName("empty")
+	m.SetDataType(pdata.MetricDataTypeIntHistogram)
+	m.IntHistogram().DataPoints().Resize(1)
+	m.IntHistogram().DataPoints().At(0).SetExplicitBounds([]float64{10})
+	m.IntHistogram().DataPoints().At(0).SetBucketCounts([]uint64{0, 0})
+
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+		EmitQuantileEstimates: []float64{0.5, 0.9, 0.999, 0.57, 0.07},
+	})
+	require.NoError(t, err)
+	dps, _ := c.MetricsToSignalFxV2(md)
+	assert.Equal(t, len(dps), c.DatapointCountEstimate(md))
+
+	got := map[string]float64{}
+	for _, dp := range dps {
+		if !strings.Contains(dp.Metric, ".p") {
+			continue
+		}
+		assert.Equal(t, &sfxMetricTypeGauge, dp.MetricType)
+		assert.Equal(t, []*sfxpb.Dimension{{Key: "route", Value: "/a"}}, dp.Dimensions)
+		got[dp.Metric] = *dp.Value.DoubleValue
+	}
+	assert.Len(t, got, 5)
+	assert.InDelta(t, 20+10*20.0/30, got["latency.p50"], 1e-9)
+	assert.InDelta(t, 37.5, got["latency.p90"], 1e-9)
+	assert.InDelta(t, 39.975, got["latency.p99.9"], 1e-9)
+	// The names have no floating point noise, e.g. "p56.99999999999999".
+	assert.Contains(t, got, "latency.p57")
+	assert.InDelta(t, 7, got["latency.p7"], 1e-9)
+}
+
+func TestQuantilePercentile(t *testing.T) {
+	for q, want := range map[float64]string{
+		0:     "0",
+		0.07:  "7",
+		0.29:  "29",
+		0.5:   "50",
+		0.57:  "57",
+		0.99:  "99",
+		0.999: "99.9",
+		0.005: "0.5",
+		1:     "100",
+	} {
+		assert.Equal(t, want, quantilePercentile(q), q)
+	}
+}
+
+func TestMetricDataToSignalFxV2OmitHistogramSum(t *testing.T) {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(1)
+	rm := md.ResourceMetrics().At(0)
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(2)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("int_histo")
+	m.SetDataType(pdata.MetricDataTypeIntHistogram)
+	m.IntHistogram().DataPoints().Resize(1)
+	m.IntHistogram().DataPoints().At(0).SetSum(10)
+	m.IntHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1, 2})
+	m.IntHistogram().DataPoints().At(0).SetBucketCounts([]uint64{4, 0, 2})
+
+	m = ilm.Metrics().At(1)
+	m.SetName("double_histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).SetSum(10)
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1, 2})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{4, 0, 2})
+
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{OmitHistogramSum: true})
+	require.NoError(t, err)
+	dps, _ := c.MetricDataToSignalFxV2(rm)
+
+	var gotMetrics []string
+	for _, dp := range dps {
+		gotMetrics = append(gotMetrics, dp.Metric)
+	}
+	assert.Equal(t, []string{
+		"int_histo_count", "int_histo_bucket", "int_histo_bucket", "int_histo_bucket",
+		"double_histo_count", "double_histo_bucket", "double_histo_bucket", "double_histo_bucket",
+	}, gotMetrics)
+	assert.Equal(t, len(dps), c.DatapointCountEstimate(md))
+}
+
+func TestMetricDataToSignalFxV2OmitEmptyHistograms(t *testing.T) {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(1)
+	rm := md.ResourceMetrics().At(0)
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(2)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("int_histo")
+	m.SetDataType(pdata.MetricDataTypeIntHistogram)
+	m.IntHistogram().DataPoints().Resize(2)
+	m.IntHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1})
+	m.IntHistogram().DataPoints().At(0).SetBucketCounts([]uint64{0, 0})
+	m.IntHistogram().DataPoints().At(1).SetCount(3)
+	m.IntHistogram().DataPoints().At(1).SetSum(4)
+	m.IntHistogram().DataPoints().At(1).SetExplicitBounds([]float64{1})
+	m.IntHistogram().DataPoints().At(1).SetBucketCounts([]uint64{2, 1})
+
+	m = ilm.Metrics().At(1)
+	m.SetName("double_histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().DataPoints().Resize(2)
+	m.DoubleHistogram().DataPoints().At(0).SetCount(3)
+	m.DoubleHistogram().DataPoints().At(0).SetSum(4)
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{2, 1})
+	m.DoubleHistogram().DataPoints().At(1).SetExplicitBounds([]float64{1})
+	m.DoubleHistogram().DataPoints().At(1).SetBucketCounts([]uint64{0, 0})
+
+	tests := []struct {
+		name        string
+		omit        bool
+		wantMetrics []string
+	}{
+		{
+			name: "disabled",
+			wantMetrics: []string{
+				"int_histo_count", "int_histo", "int_histo_bucket", "int_histo_bucket",
+				"int_histo_count", "int_histo", "int_histo_bucket", "int_histo_bucket",
+				"double_histo_count", "double_histo", "double_histo_bucket", "double_histo_bucket",
+				"double_histo_count", "double_histo", "double_histo_bucket", "double_histo_bucket",
+			},
+		},
+		{
+			name: "enabled",
+			omit: true,
+			wantMetrics: []string{
+				"int_histo_count", "int_histo", "int_histo_bucket", "int_histo_bucket",
+				"double_histo_count", "double_histo", "double_histo_bucket", "double_histo_bucket",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{OmitEmptyHistograms: tt.omit})
+			require.NoError(t, err)
+			dps, _ := c.MetricDataToSignalFxV2(rm)
+
+			var gotMetrics []string
+			for _, dp := range dps {
+				gotMetrics = append(gotMetrics, dp.Metric)
+			}
+			assert.Equal(t, tt.wantMetrics, gotMetrics)
+			assert.Equal(t, len(dps), c.DatapointCountEstimate(md))
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2HistogramCountAsBase(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(2)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("int_histo")
+	m.SetDataType(pdata.MetricDataTypeIntHistogram)
+	m.IntHistogram().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.IntHistogram().DataPoints().Resize(1)
+	m.IntHistogram().DataPoints().At(0).SetCount(3)
+	m.IntHistogram().DataPoints().At(0).SetSum(4)
+	m.IntHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1})
+	m.IntHistogram().DataPoints().At(0).SetBucketCounts([]uint64{2, 1})
+
+	m = ilm.Metrics().At(1)
+	m.SetName("double_histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).SetCount(3)
+	m.DoubleHistogram().DataPoints().At(0).SetSum(4.5)
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{2, 1})
+
+	tests := []struct {
+		name        string
+		countAsBase bool
+		wantMetrics []string
+	}{
+		{
+			name: "disabled",
+			wantMetrics: []string{
+				"int_histo_count", "int_histo", "int_histo_bucket", "int_histo_bucket",
+				"double_histo_count", "double_histo", "double_histo_bucket", "double_histo_bucket",
+			},
+		},
+		{
+			name:        "enabled",
+			countAsBase: true,
+			wantMetrics: []string{
+				"int_histo", "int_histo_sum", "int_histo_bucket", "int_histo_bucket",
+				"double_histo", "double_histo_sum", "double_histo_bucket", "double_histo_bucket",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{HistogramCountAsBase: tt.countAsBase})
+			require.NoError(t, err)
+			dps, _ := c.MetricDataToSignalFxV2(rm)
+			require.Len(t, dps, len(tt.wantMetrics))
+
+			var gotMetrics []string
+			for _, dp := range dps {
+				gotMetrics = append(gotMetrics, dp.Metric)
+			}
+			assert.Equal(t, tt.wantMetrics, gotMetrics)
+
+			// The count datapoint comes first for both histograms.
+			for _, i := range []int{0, 4} {
+				assert.Equal(t, int64(3), *dps[i].Value.IntValue)
+				assert.Equal(t, &sfxMetricTypeCumulativeCounter, dps[i].MetricType)
+			}
+			assert.Equal(t, int64(4), *dps[1].Value.IntValue)
+			assert.Equal(t, 4.5, *dps[5].Value.DoubleValue)
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2MaxStaleness(t *testing.T) {
+	now := time.Unix(1600000100, 0)
+	seconds := func(s int64) pdata.TimestampUnixNano {
+		return pdata.TimestampUnixNano(time.Unix(s, 0).UnixNano())
+	}
+
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(3)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(3)
+	m.IntGauge().DataPoints().At(0).SetTimestamp(seconds(1600000090))
+	m.IntGauge().DataPoints().At(0).SetValue(1)
+	m.IntGauge().DataPoints().At(1).SetTimestamp(seconds(1600000000))
+	m.IntGauge().DataPoints().At(1).SetValue(2)
+	m.IntGauge().DataPoints().At(2).SetTimestamp(seconds(1600000040))
+	m.IntGauge().DataPoints().At(2).SetValue(3)
+
+	m = ilm.Metrics().At(1)
+	m.SetName("stale_single")
+	m.SetDataType(pdata.MetricDataTypeDoubleGauge)
+	m.DoubleGauge().DataPoints().Resize(1)
+	m.DoubleGauge().DataPoints().At(0).SetTimestamp(seconds(1500000000))
+
+	m = ilm.Metrics().At(2)
+	m.SetName("stale_histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).SetTimestamp(seconds(1600000039))
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, 2})
+
+	tests := []struct {
+		name        string
+		staleness   time.Duration
+		wantValues  []int64
+		wantDropped int
+	}{
+		{
+			name:        "disabled",
+			wantValues:  []int64{1, 2, 3},
+			wantDropped: 0,
+		},
+		{
+			name:      "one_minute",
+			staleness: time.Minute,
+			// The datapoint exactly one minute old is kept.
+			wantValues:  []int64{1, 3},
+			wantDropped: 1 + 1 + 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+				MaxStaleness: tt.staleness,
+				Clock:        func() time.Time { return now },
+			})
+			require.NoError(t, err)
+			dps, dropped := c.MetricDataToSignalFxV2(rm)
+			assert.Equal(t, tt.wantDropped, dropped)
+
+			var gotValues []int64
+			for _, dp := range dps {
+				if dp.Metric == "gauge" {
+					gotValues = append(gotValues, *dp.Value.IntValue)
+				}
+			}
+			assert.Equal(t, tt.wantValues, gotValues)
+		})
+	}
+}
+
+func TestMetricsToSignalFxV2MaxDatapointsPerBatch(t *testing.T) {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(2)
+	addGauge := func(ilm pdata.InstrumentationLibraryMetrics, name string) {
+		m := pdata.NewMetric()
+		m.InitEmpty()
+		m.SetName(name)
+		m.SetDataType(pdata.MetricDataTypeIntGauge)
+		m.IntGauge().DataPoints().Resize(1)
+		ilm.Metrics().Append(m)
+	}
+
+	rm := md.ResourceMetrics().At(0)
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	addGauge(ilm, "a")
+	m := pdata.NewMetric()
+	m.InitEmpty()
+	m.SetName("histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, 2})
+	ilm.Metrics().Append(m)
+	addGauge(ilm, "b")
+
+	rm = md.ResourceMetrics().At(1)
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	addGauge(rm.InstrumentationLibraryMetrics().At(0), "c")
+
+	allMetrics := []string{"a", "histo_count", "histo", "histo_bucket", "histo_bucket", "b", "c"}
+	tests := []struct {
+		name        string
+		max         int
+		wantMetrics []string
+		wantDropped int
+	}{
+		{
+			name:        "disabled",
+			wantMetrics: allMetrics,
+		},
+		{
+			name:        "not_reached",
+			max:         7,
+			wantMetrics: allMetrics,
+		},
+		{
+			name:        "reached_after_histogram",
+			max:         5,
+			wantMetrics: []string{"a", "histo_count", "histo", "histo_bucket", "histo_bucket"},
+			wantDropped: 2,
+		},
+		{
+			// The histogram isn't split and the following metrics are
+			// dropped even though they would fit.
+			name:        "reached_in_histogram",
+			max:         3,
+			wantMetrics: []string{"a"},
+			wantDropped: 6,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, observedLogs := observer.New(zap.WarnLevel)
+			c, err := NewMetricsConverter(zap.New(core), nil, MetricsConverterOptions{
+				MaxDatapointsPerBatch: tt.max,
+			})
+			require.NoError(t, err)
+			dps, dropped := c.MetricsToSignalFxV2(md)
+			assert.Equal(t, tt.wantDropped, dropped)
+			assert.LessOrEqual(t, len(dps), c.DatapointCountEstimate(md))
+
+			var gotMetrics []string
+			for _, dp := range dps {
+				gotMetrics = append(gotMetrics, dp.Metric)
+			}
+			assert.Equal(t, tt.wantMetrics, gotMetrics)
+
+			logs := observedLogs.FilterMessage("datapoints per batch limit reached, dropping datapoints").All()
+			if tt.wantDropped == 0 {
+				assert.Empty(t, logs)
+				return
+			}
+			require.Len(t, logs, 1)
+			assert.Equal(t, int64(tt.wantDropped), logs[0].ContextMap()["datapoints"])
+		})
+	}
+}
+
+func TestMetricsToSignalFxV2NilHandling(t *testing.T) {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Append(pdata.NewResourceMetrics())
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	md.ResourceMetrics().Append(rm)
+	rm.InstrumentationLibraryMetrics().Append(pdata.NewInstrumentationLibraryMetrics())
+	rm.InstrumentationLibraryMetrics().Resize(2)
+	ilm := rm.InstrumentationLibraryMetrics().At(1)
+	ilm.Metrics().Append(pdata.NewMetric())
+
+	m := pdata.NewMetric()
+	m.InitEmpty()
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Append(pdata.NewIntDataPoint())
+	m.IntGauge().DataPoints().Resize(2)
+	m.IntGauge().DataPoints().At(1).SetValue(1)
+	ilm.Metrics().Append(m)
+
+	m = pdata.NewMetric()
+	m.InitEmpty()
+	m.SetName("sum")
+	m.SetDataType(pdata.MetricDataTypeDoubleSum)
+	m.DoubleSum().DataPoints().Append(pdata.NewDoubleDataPoint())
+	ilm.Metrics().Append(m)
+
+	wantMessages := []string{
+		"skipping nil resource metrics",
+		"skipping nil instrumentation library metrics",
+		"skipping nil metrics",
+		"skipping nil datapoints",
+		"skipping nil datapoints",
+	}
+	tests := []struct {
+		name        string
+		nilHandling NilHandling
+		wantLevel   zapcore.Level
+		wantLogs    []string
+		wantDropped int
+	}{
+		{
+			name: "default",
+		},
+		{
+			name:        "skip",
+			nilHandling: NilHandlingSkip,
+		},
+		{
+			name:        "warn",
+			nilHandling: NilHandlingWarn,
+			wantLevel:   zapcore.WarnLevel,
+			wantLogs:    wantMessages,
+		},
+		{
+			name:        "error_count",
+			nilHandling: NilHandlingErrorCount,
+			wantLevel:   zapcore.ErrorLevel,
+			wantLogs:    wantMessages,
+			wantDropped: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			c, err := NewMetricsConverter(zap.New(core), nil, MetricsConverterOptions{NilHandling: tt.nilHandling})
+			require.NoError(t, err)
+
+			dps, dropped := c.MetricsToSignalFxV2(md)
+			require.Len(t, dps, 1)
+			assert.Equal(t, "gauge", dps[0].Metric)
+			assert.Equal(t, tt.wantDropped, dropped)
+
+			var gotLogs []string
+			for _, e := range logs.All() {
+				assert.Equal(t, tt.wantLevel, e.Level)
+				assert.EqualValues(t, 1, e.ContextMap()["count"])
+				gotLogs = append(gotLogs, e.Message)
+			}
+			assert.Equal(t, tt.wantLogs, gotLogs)
+		})
+	}
+}
+
+func TestMetricsToSignalFxV2WithDropStats(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	nowTs := pdata.TimestampUnixNano(now.UnixNano())
+
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(1)
+	rm := md.ResourceMetrics().At(0)
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Append(pdata.NewMetric())
+
+	m := pdata.NewMetric()
+	m.InitEmpty()
+	m.SetName("counter")
+	m.SetDataType(pdata.MetricDataTypeIntSum)
+	m.IntSum().SetIsMonotonic(true)
+	m.IntSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.IntSum().DataPoints().Append(pdata.NewIntDataPoint())
+	m.IntSum().DataPoints().Resize(4)
+	m.IntSum().DataPoints().At(1).SetTimestamp(nowTs)
+	m.IntSum().DataPoints().At(2).SetTimestamp(nowTs)
+	m.IntSum().DataPoints().At(2).SetValue(5)
+	m.IntSum().DataPoints().At(3).SetTimestamp(pdata.TimestampUnixNano(now.Add(-time.Hour).UnixNano()))
+	m.IntSum().DataPoints().At(3).SetValue(7)
+	ilm.Metrics().Append(m)
+
+	addHistogram := func(name string, bounds []float64, counts []uint64) {
+		m := pdata.NewMetric()
+		m.InitEmpty()
+		m.SetName(name)
+		m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+		m.DoubleHistogram().DataPoints().Resize(1)
+		m.DoubleHistogram().DataPoints().At(0).SetTimestamp(nowTs)
+		m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds(bounds)
+		m.DoubleHistogram().DataPoints().At(0).SetBucketCounts(counts)
+		ilm.Metrics().Append(m)
+	}
+	addHistogram("mismatch", []float64{1}, []uint64{1, 2, 3})
+	addHistogram("nan", []float64{math.NaN()}, []uint64{1, 2})
+
+	m = pdata.NewMetric()
+	m.InitEmpty()
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+	m.IntGauge().DataPoints().At(0).SetTimestamp(nowTs)
+	ilm.Metrics().Append(m)
+
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+		DropZeroValueCounters: true,
+		MaxStaleness:          time.Minute,
+		Clock:                 func() time.Time { return now },
+		// Room for the counter and the count and sum of both histograms.
+		MaxDatapointsPerBatch: 5,
+	})
+	require.NoError(t, err)
+
+	dps, dropped, stats := c.MetricsToSignalFxV2WithDropStats(md)
+	assert.Len(t, dps, 5)
+	assert.Equal(t, 3, dropped)
+	assert.Equal(t, DropStats{
+		NilMetrics:        1,
+		NilDataPoints:     1,
+		ZeroValue:         1,
+		Stale:             1,
+		HistogramMismatch: 3,
+		NaNBounds:         2,
+		BatchLimit:        1,
+	}, stats)
+
+	wantDps, wantDropped := c.MetricsToSignalFxV2(md)
+	assert.Equal(t, wantDps, dps)
+	assert.Equal(t, wantDropped, dropped)
+}
+
+func TestMetricsConverterDropEvents(t *testing.T) {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(1)
+	rm := md.ResourceMetrics().At(0)
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(3)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("nan_histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{math.NaN()})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, 2})
+
+	m = ilm.Metrics().At(1)
+	m.SetName("counter")
+	m.SetDataType(pdata.MetricDataTypeIntSum)
+	m.IntSum().SetIsMonotonic(true)
+	m.IntSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.IntSum().DataPoints().Resize(2)
+	m.IntSum().DataPoints().At(1).SetValue(1)
+
+	ilm.Metrics().At(2).SetName("typeless")
+
+	now := time.Unix(1600000000, 0)
+	nowMs := now.UnixNano() / 1e6
+	category := sfxpb.EventCategory_AGENT
+	dropEvent := func(metric string, ts int64, reasons map[string]int64) *sfxpb.Event {
+		var keys []string
+		for k := range reasons {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var props []*sfxpb.Property
+		for _, k := range keys {
+			v := reasons[k]
+			props = append(props, &sfxpb.Property{Key: k, Value: &sfxpb.PropertyValue{IntValue: &v}})
+		}
+		return &sfxpb.Event{
+			EventType:  dropEventType,
+			Category:   &category,
+			Dimensions: []*sfxpb.Dimension{{Key: "metric", Value: metric}},
+			Properties: props,
+			Timestamp:  ts,
+		}
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{DropZeroValueCounters: true})
+		require.NoError(t, err)
+		c.MetricsToSignalFxV2(md)
+		assert.Nil(t, c.DropEvents())
+	})
+
+	t.Run("rate_limited", func(t *testing.T) {
+		c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+			DropZeroValueCounters: true,
+			EmitDropEvents:        true,
+			DropEventsInterval:    time.Minute,
+			Clock:                 func() time.Time { return now },
+		})
+		require.NoError(t, err)
+
+		assert.Nil(t, c.DropEvents())
+
+		c.MetricsToSignalFxV2(md)
+		assert.Equal(t, []*sfxpb.Event{
+			dropEvent("counter", nowMs, map[string]int64{dropReasonZeroValue: 1}),
+			dropEvent("nan_histo", nowMs, map[string]int64{dropReasonNaNBounds: 2}),
+			dropEvent("typeless", nowMs, map[string]int64{dropReasonNoDataType: 1}),
+		}, c.DropEvents())
+
+		// Drops are summed up until the interval elapsed.
+		c.MetricsToSignalFxV2(md)
+		c.MetricsToSignalFxV2(md)
+		now = now.Add(30 * time.Second)
+		assert.Nil(t, c.DropEvents())
+
+		now = now.Add(30 * time.Second)
+		nowMs = now.UnixNano() / 1e6
+		assert.Equal(t, []*sfxpb.Event{
+			dropEvent("counter", nowMs, map[string]int64{dropReasonZeroValue: 2}),
+			dropEvent("nan_histo", nowMs, map[string]int64{dropReasonNaNBounds: 4}),
+			dropEvent("typeless", nowMs, map[string]int64{dropReasonNoDataType: 2}),
+		}, c.DropEvents())
+
+		// Nothing dropped since the last events.
+		now = now.Add(time.Hour)
+		assert.Nil(t, c.DropEvents())
+	})
+
+	t.Run("max_events", func(t *testing.T) {
+		c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+			EmitDropEvents:           true,
+			MaxDropEventsPerInterval: 1,
+			Clock:                    func() time.Time { return now },
+		})
+		require.NoError(t, err)
+
+		c.MetricsToSignalFxV2(md)
+		nowMs = now.UnixNano() / 1e6
+		assert.Equal(t, []*sfxpb.Event{
+			dropEvent("nan_histo", nowMs, map[string]int64{dropReasonNaNBounds: 2}),
+		}, c.DropEvents())
+	})
+}
+
+func TestMetricsToSignalFxV2MetricWithoutDataType(t *testing.T) {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(1)
+	rm := md.ResourceMetrics().At(0)
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(2)
+
+	ilm.Metrics().At(0).SetName("typeless")
+
+	m := ilm.Metrics().At(1)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+
+	core, observedLogs := observer.New(zap.WarnLevel)
+	c, err := NewMetricsConverter(zap.New(core), nil, MetricsConverterOptions{})
+	require.NoError(t, err)
+
+	dps, dropped, stats := c.MetricsToSignalFxV2WithDropStats(md)
+	require.Len(t, dps, 1)
+	assert.Equal(t, "gauge", dps[0].Metric)
+	assert.Equal(t, 1, dropped)
+	assert.Equal(t, DropStats{NoDataType: 1}, stats)
+
+	require.Equal(t, 1, observedLogs.Len())
+	entry := observedLogs.All()[0]
+	assert.Equal(t, "dropping metric without data type", entry.Message)
+	assert.Equal(t, map[string]interface{}{
+		"metric":     "typeless",
+		"reason":     dropReasonNoDataType,
+		"datapoints": int64(1),
+	}, entry.ContextMap())
+}
+
+func TestMetricsToSignalFxV2DefaultMetricTypeForNone(t *testing.T) {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(1)
+	rm := md.ResourceMetrics().At(0)
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(3)
+
+	ilm.Metrics().At(0).SetName("typeless")
+	ilm.Metrics().At(1).SetName("requests")
+
+	m := ilm.Metrics().At(2)
+	m.SetName("requests")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+
+	tests := []struct {
+		name         string
+		metricType   sfxpb.MetricType
+		wantWarnings []string
+	}{
+		{
+			name:       "gauge",
+			metricType: sfxpb.MetricType_GAUGE,
+		},
+		{
+			name:         "counter",
+			metricType:   sfxpb.MetricType_COUNTER,
+			wantWarnings: []string{"metric name has conflicting types"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, observedLogs := observer.New(zap.DebugLevel)
+			metricType := tt.metricType
+			c, err := NewMetricsConverter(zap.New(core), nil, MetricsConverterOptions{
+				DefaultMetricTypeForNone: &metricType,
+			})
+			require.NoError(t, err)
+
+			dps, dropped, stats := c.MetricsToSignalFxV2WithDropStats(md)
+			require.Len(t, dps, 1)
+			assert.Equal(t, "requests", dps[0].Metric)
+			assert.Equal(t, 0, dropped)
+			assert.Equal(t, DropStats{}, stats)
+			assert.Equal(t, 1, c.DatapointCountEstimate(md))
+
+			assert.Equal(t, 2, observedLogs.FilterMessage("metric without data type has no datapoints").Len())
+			var warnings []string
+			for _, e := range observedLogs.FilterField(zap.String("metric", "requests")).All() {
+				if e.Level == zap.WarnLevel {
+					warnings = append(warnings, e.Message)
+				}
+			}
+			assert.Equal(t, tt.wantWarnings, warnings)
+		})
+	}
+}
+
+type stubDimensionProvider struct {
+	dims  []*sfxpb.Dimension
+	calls int
+}
+
+func (p *stubDimensionProvider) Dimensions() []*sfxpb.Dimension {
+	p.calls++
+	return p.dims
+}
+
+func TestMetricsToSignalFxV2DimensionProvider(t *testing.T) {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(2)
+	for i, host := range []string{"host0", "host1"} {
+		rm := md.ResourceMetrics().At(i)
+		rm.Resource().Attributes().InsertString("host", host)
+		rm.InstrumentationLibraryMetrics().Resize(1)
+		ilm := rm.InstrumentationLibraryMetrics().At(0)
+		ilm.Metrics().Resize(1)
+		m := ilm.Metrics().At(0)
+		m.SetName("gauge")
+		m.SetDataType(pdata.MetricDataTypeIntGauge)
+		m.IntGauge().DataPoints().Resize(1)
+	}
+
+	provider := &stubDimensionProvider{
+		dims: []*sfxpb.Dimension{
+			{Key: "availability_zone", Value: "us-east-1a"},
+			{Key: "instance.type", Value: "m5.large"},
+			// Resource dimensions take precedence.
+			{Key: "host", Value: "metadata-host"},
+		},
+	}
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{DimensionProvider: provider})
+	require.NoError(t, err)
+
+	dps, _ := c.MetricsToSignalFxV2(md)
+	assert.Equal(t, 1, provider.calls)
+	require.Len(t, dps, 2)
+	for i, host := range []string{"host0", "host1"} {
+		assert.Equal(t, []*sfxpb.Dimension{
+			{Key: "host", Value: host},
+			{Key: "availability_zone", Value: "us-east-1a"},
+			{Key: "instance_type", Value: "m5.large"},
+		}, dps[i].Dimensions)
+	}
+	// Provided dimensions are not modified by the sanitization.
+	assert.Equal(t, "instance.type", provider.dims[1].Key)
+
+	// Dimensions are refreshed on each call.
+	provider.dims[0].Value = "us-east-1b"
+	dps, _ = c.MetricDataToSignalFxV2(md.ResourceMetrics().At(0))
+	assert.Equal(t, 2, provider.calls)
+	require.Len(t, dps, 1)
+	assert.Equal(t, []*sfxpb.Dimension{
+		{Key: "host", Value: "host0"},
+		{Key: "availability_zone", Value: "us-east-1b"},
+		{Key: "instance_type", Value: "m5.large"},
+	}, dps[0].Dimensions)
+}
+
+func TestMetricsToSignalFxV2ByToken(t *testing.T) {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(4)
+	for i, token := range []string{"tokenA", "tokenB", "", "tokenA"} {
+		rm := md.ResourceMetrics().At(i)
+		rm.Resource().Attributes().InsertString("host", fmt.Sprintf("host%d", i))
+		if token != "" {
+			rm.Resource().Attributes().InsertString(splunk.SFxAccessTokenLabel, token)
+		}
+		rm.InstrumentationLibraryMetrics().Resize(1)
+		ilm := rm.InstrumentationLibraryMetrics().At(0)
+		ilm.Metrics().Resize(1)
+		m := ilm.Metrics().At(0)
+		m.SetName("gauge")
+		m.SetDataType(pdata.MetricDataTypeIntGauge)
+		m.IntGauge().DataPoints().Resize(1)
+	}
+
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{})
+	require.NoError(t, err)
+	byToken := c.MetricsToSignalFxV2ByToken(md)
+
+	gotHosts := map[string][]string{}
+	for token, dps := range byToken {
+		for _, dp := range dps {
+			require.Len(t, dp.Dimensions, 1)
+			assert.Equal(t, "host", dp.Dimensions[0].Key)
+			gotHosts[token] = append(gotHosts[token], dp.Dimensions[0].Value)
+		}
+	}
+	assert.Equal(t, map[string][]string{
+		"tokenA": {"host0", "host3"},
+		"tokenB": {"host1"},
+		"":       {"host2"},
+	}, gotHosts)
+}
+
+func TestMetricDataToSignalFxV2EmitHeartbeat(t *testing.T) {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(3)
+
+	rm := md.ResourceMetrics().At(0)
+	rm.Resource().Attributes().InsertString("host.name", "host0")
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(1)
+	m := ilm.Metrics().At(0)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(2)
+
+	// Resource without metrics.
+	md.ResourceMetrics().At(1).Resource().Attributes().InsertString("host.name", "host1")
+
+	// Resource without dimensions.
+	rm = md.ResourceMetrics().At(2)
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm = rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(1)
+	m = ilm.Metrics().At(0)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{EmitHeartbeat: true})
+	require.NoError(t, err)
+
+	var heartbeats []*sfxpb.DataPoint
+	numDPs := 0
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		dps, _ := c.MetricDataToSignalFxV2(md.ResourceMetrics().At(i))
+		numDPs += len(dps)
+		for _, dp := range dps {
+			if dp.Metric == "sf.up" {
+				heartbeats = append(heartbeats, dp)
+			}
+		}
+	}
+
+	require.Len(t, heartbeats, 2)
+	for i, hb := range heartbeats {
+		assert.Equal(t, &sfxMetricTypeGauge, hb.MetricType)
+		assert.Equal(t, int64(1), *hb.Value.IntValue)
+		assert.NotZero(t, hb.Timestamp)
+		assert.Equal(t, []*sfxpb.Dimension{{Key: "host_name", Value: fmt.Sprintf("host%d", i)}}, hb.Dimensions)
+	}
+	assert.Equal(t, 5, numDPs)
+	assert.Equal(t, numDPs, c.DatapointCountEstimate(md))
+}
+
+func TestMetricsToSignalFxV2EmitBuildInfo(t *testing.T) {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(3)
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		rm := md.ResourceMetrics().At(i)
+		rm.Resource().Attributes().InsertString("host.name", fmt.Sprintf("host%d", i))
+		rm.InstrumentationLibraryMetrics().Resize(1)
+		ilm := rm.InstrumentationLibraryMetrics().At(0)
+		ilm.Metrics().Resize(1)
+		m := ilm.Metrics().At(0)
+		m.SetName("gauge")
+		m.SetDataType(pdata.MetricDataTypeIntGauge)
+		m.IntGauge().DataPoints().Resize(1)
+	}
+
+	now := time.Unix(1600000000, 0)
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+		EmitBuildInfo:       "v0.15.0",
+		CollectorInstanceID: "collector0",
+		Clock:               func() time.Time { return now },
+	})
+	require.NoError(t, err)
+
+	val := int64(1)
+	wantBuildInfo := &sfxpb.DataPoint{
+		Metric:     "sf.collector.build_info",
+		Timestamp:  now.UnixNano() / 1e6,
+		Value:      sfxpb.Datum{IntValue: &val},
+		MetricType: &sfxMetricTypeGauge,
+		Dimensions: []*sfxpb.Dimension{
+			{Key: "version", Value: "v0.15.0"},
+			{Key: "collector_id", Value: "collector0"},
+		},
+	}
+	buildInfos := func(dps []*sfxpb.DataPoint) []*sfxpb.DataPoint {
+		var got []*sfxpb.DataPoint
+		for _, dp := range dps {
+			if dp.Metric == "sf.collector.build_info" {
+				got = append(got, dp)
+			}
+		}
+		return got
+	}
+
+	dps, _ := c.MetricsToSignalFxV2(md)
+	assert.Len(t, dps, 4)
+	assert.Equal(t, []*sfxpb.DataPoint{wantBuildInfo}, buildInfos(dps))
+	assert.Equal(t, len(dps), c.DatapointCountEstimate(md))
+
+	dps, _ = c.MetricDataToSignalFxV2(md.ResourceMetrics().At(0))
+	assert.Len(t, dps, 2)
+	assert.Equal(t, []*sfxpb.DataPoint{wantBuildInfo}, buildInfos(dps))
+
+	byToken := c.MetricsToSignalFxV2ByToken(md)
+	require.Len(t, byToken, 1)
+	assert.Len(t, byToken[""], 4)
+	assert.Equal(t, []*sfxpb.DataPoint{wantBuildInfo}, buildInfos(byToken[""]))
+
+	// Nothing emitted by default.
+	c, err = NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{})
+	require.NoError(t, err)
+	dps, _ = c.MetricsToSignalFxV2(md)
+	assert.Len(t, dps, 3)
+	assert.Empty(t, buildInfos(dps))
+}
+
+func TestMetricsToSignalFxV2EmitConversionStats(t *testing.T) {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(1)
+	rm := md.ResourceMetrics().At(0)
+	rm.Resource().Attributes().InsertString("host.name", "host0")
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(4)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(2)
+
+	m = ilm.Metrics().At(1)
+	m.SetName("histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, 2})
+
+	// Metrics without datapoints and stats metrics get no stats.
+	m = ilm.Metrics().At(2)
+	m.SetName("empty")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+
+	m = ilm.Metrics().At(3)
+	m.SetName("sf.converter.datapoints")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+	m.IntGauge().DataPoints().At(0).LabelsMap().Insert("source_metric", "other")
+
+	now := time.Unix(1600000000, 0)
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+		EmitConversionStats: true,
+		Clock:               func() time.Time { return now },
+	})
+	require.NoError(t, err)
+	dps, _ := c.MetricsToSignalFxV2(md)
+	assert.Equal(t, len(dps), c.DatapointCountEstimate(md))
+
+	gotStats := map[string]int64{}
+	numConverted := map[string]int64{}
+	for _, dp := range dps {
+		// Only the emitted stats have the clock timestamp.
+		if dp.Metric != "sf.converter.datapoints" || dp.Timestamp != now.UnixNano()/1e6 {
+			numConverted[strings.TrimSuffix(strings.TrimSuffix(dp.Metric, "_count"), "_bucket")]++
+			continue
+		}
+		assert.Equal(t, &sfxMetricTypeGauge, dp.MetricType)
+		require.Len(t, dp.Dimensions, 2)
+		assert.Equal(t, &sfxpb.Dimension{Key: "host_name", Value: "host0"}, dp.Dimensions[0])
+		assert.Equal(t, "source_metric", dp.Dimensions[1].Key)
+		gotStats[dp.Dimensions[1].Value] = *dp.Value.IntValue
+	}
+	assert.Equal(t, map[string]int64{"gauge": 2, "histo": 4}, gotStats)
+	assert.Equal(t, map[string]int64{"gauge": 2, "histo": 4, "sf.converter.datapoints": 1}, numConverted)
+}
+
+func TestMetricDataToSignalFxV2CollectorInstanceID(t *testing.T) {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(1)
+	rm := md.ResourceMetrics().At(0)
+	rm.Resource().Attributes().InsertString("host.name", "host0")
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(2)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(2)
+	m.IntGauge().DataPoints().At(0).LabelsMap().Insert("k0", "v0")
+
+	m = ilm.Metrics().At(1)
+	m.SetName("histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, 2})
+
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+		CollectorInstanceID: "collector-0",
+		EmitHeartbeat:       true,
+	})
+	require.NoError(t, err)
+	dps, _ := c.MetricDataToSignalFxV2(rm)
+	// 2 gauges, count, sum, 2 buckets and the heartbeat.
+	require.Len(t, dps, 7)
+	assert.Equal(t, len(dps), c.DatapointCountEstimate(md))
+
+	var collectorIDDim *sfxpb.Dimension
+	for _, dp := range dps {
+		var got *sfxpb.Dimension
+		for _, d := range dp.Dimensions {
+			if d.Key == "collector_id" {
+				got = d
+			}
+		}
+		require.NotNil(t, got, dp.Metric)
+		assert.Equal(t, "collector-0", got.Value)
+		// The dimension is created once and shared by all datapoints.
+		if collectorIDDim == nil {
+			collectorIDDim = got
+		}
+		assert.Same(t, collectorIDDim, got)
+	}
+}
+
+func TestMetricDataToSignalFxV2NegativeBucketCount(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(2)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("int_histo")
+	m.SetDataType(pdata.MetricDataTypeIntHistogram)
+	m.IntHistogram().DataPoints().Resize(1)
+	m.IntHistogram().DataPoints().At(0).SetCount(3)
+	m.IntHistogram().DataPoints().At(0).SetSum(10)
+	m.IntHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1, 2})
+	m.IntHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, math.MaxUint64, 2})
+
+	m = ilm.Metrics().At(1)
+	m.SetName("double_histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).SetCount(3)
+	m.DoubleHistogram().DataPoints().At(0).SetSum(10)
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1, 2})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, 2, math.MaxInt64 + 1})
+
+	core, observedLogs := observer.New(zap.ErrorLevel)
+	c, err := NewMetricsConverter(zap.New(core), nil, MetricsConverterOptions{})
+	require.NoError(t, err)
+	got, _ := c.MetricDataToSignalFxV2(rm)
+
+	// Only count and sum are expected for histograms with negative counts.
+	want := []*sfxpb.DataPoint{
+		int64SFxDataPoint("int_histo_count", 0, &sfxMetricTypeCumulativeCounter, nil, 3),
+		int64SFxDataPoint("int_histo", 0, &sfxMetricTypeCumulativeCounter, nil, 10),
+		int64SFxDataPoint("double_histo_count", 0, &sfxMetricTypeCumulativeCounter, nil, 3),
+		doubleSFxDataPoint("double_histo", 0, &sfxMetricTypeCumulativeCounter, nil, 10),
+	}
+	assert.Equal(t, want, got)
+
+	require.Equal(t, 2, observedLogs.Len())
+	for i, tc := range []struct {
+		metric string
+		index  int64
+	}{{"int_histo", 1}, {"double_histo", 2}} {
+		entry := observedLogs.All()[i]
+		assert.Equal(t, "histogram bucket count is negative when converted to int64, dropping buckets", entry.Message)
+		assert.Equal(t, tc.metric, entry.ContextMap()["metric"])
+		assert.Equal(t, tc.index, entry.ContextMap()["bucket_index"])
+		assert.Equal(t, dropReasonNegativeBucketCount, entry.ContextMap()["reason"])
+	}
+
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Append(rm)
+	assert.Equal(t, len(got), c.DatapointCountEstimate(md))
+}
+
+func TestMetricDataToSignalFxV2DeltaToCumulative(t *testing.T) {
+	deltaSums := func(intVals map[string]int64, doubleVal float64) pdata.ResourceMetrics {
+		rm := pdata.NewResourceMetrics()
+		rm.InitEmpty()
+		rm.InstrumentationLibraryMetrics().Resize(1)
+		ilm := rm.InstrumentationLibraryMetrics().At(0)
+		ilm.Metrics().Resize(3)
+
+		m := ilm.Metrics().At(0)
+		m.SetName("requests")
+		m.SetDataType(pdata.MetricDataTypeIntSum)
+		m.IntSum().SetIsMonotonic(true)
+		m.IntSum().SetAggregationTemporality(pdata.AggregationTemporalityDelta)
+		for host, v := range intVals {
+			dp := pdata.NewIntDataPoint()
+			dp.InitEmpty()
+			dp.LabelsMap().Insert("host", host)
+			dp.SetValue(v)
+			m.IntSum().DataPoints().Append(dp)
+		}
+
+		m = ilm.Metrics().At(1)
+		m.SetName("bytes")
+		m.SetDataType(pdata.MetricDataTypeDoubleSum)
+		m.DoubleSum().SetIsMonotonic(true)
+		m.DoubleSum().SetAggregationTemporality(pdata.AggregationTemporalityDelta)
+		m.DoubleSum().DataPoints().Resize(1)
+		m.DoubleSum().DataPoints().At(0).SetValue(doubleVal)
+
+		m = ilm.Metrics().At(2)
+		m.SetName("cumulative")
+		m.SetDataType(pdata.MetricDataTypeIntSum)
+		m.IntSum().SetIsMonotonic(true)
+		m.IntSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+		m.IntSum().DataPoints().Resize(1)
+		m.IntSum().DataPoints().At(0).SetValue(100)
+		return rm
+	}
+
+	type series struct {
+		metric     string
+		host       string
+		metricType sfxpb.MetricType
+		value      float64
+	}
+	toSeries := func(dps []*sfxpb.DataPoint) []series {
+		var out []series
+		for _, dp := range dps {
+			s := series{metric: dp.Metric, metricType: *dp.MetricType}
+			for _, d := range dp.Dimensions {
+				if d.Key == "host" {
+					s.host = d.Value
+				}
+			}
+			if dp.Value.IntValue != nil {
+				s.value = float64(*dp.Value.IntValue)
+			} else {
+				s.value = *dp.Value.DoubleValue
+			}
+			out = append(out, s)
+		}
+		sort.Slice(out, func(i, j int) bool {
+			return out[i].metric+out[i].host < out[j].metric+out[j].host
+		})
+		return out
+	}
+
+	t.Run("accumulate", func(t *testing.T) {
+		c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{DeltaToCumulative: true})
+		require.NoError(t, err)
+		defer c.Shutdown()
+
+		batches := []struct {
+			intVals   map[string]int64
+			doubleVal float64
+			want      []series
+		}{
+			{
+				intVals:   map[string]int64{"a": 1, "b": 10},
+				doubleVal: 0.5,
+				want: []series{
+					{metric: "bytes", metricType: sfxMetricTypeCumulativeCounter, value: 0.5},
+					{metric: "cumulative", metricType: sfxMetricTypeCumulativeCounter, value: 100},
+					{metric: "requests", host: "a", metricType: sfxMetricTypeCumulativeCounter, value: 1},
+					{metric: "requests", host: "b", metricType: sfxMetricTypeCumulativeCounter, value: 10},
+				},
+			},
+			{
+				intVals:   map[string]int64{"a": 2, "b": 5},
+				doubleVal: 1.25,
+				want: []series{
+					{metric: "bytes", metricType: sfxMetricTypeCumulativeCounter, value: 1.75},
+					{metric: "cumulative", metricType: sfxMetricTypeCumulativeCounter, value: 100},
+					{metric: "requests", host: "a", metricType: sfxMetricTypeCumulativeCounter, value: 3},
+					{metric: "requests", host: "b", metricType: sfxMetricTypeCumulativeCounter, value: 15},
+				},
+			},
+			{
+				intVals:   map[string]int64{"a": 4},
+				doubleVal: 0,
+				want: []series{
+					{metric: "bytes", metricType: sfxMetricTypeCumulativeCounter, value: 1.75},
+					{metric: "cumulative", metricType: sfxMetricTypeCumulativeCounter, value: 100},
+					{metric: "requests", host: "a", metricType: sfxMetricTypeCumulativeCounter, value: 7},
+				},
+			},
+			{
+				// Negative deltas reset the totals instead of decreasing them.
+				intVals:   map[string]int64{"a": -3},
+				doubleVal: -1,
+				want: []series{
+					{metric: "bytes", metricType: sfxMetricTypeCumulativeCounter, value: 0},
+					{metric: "cumulative", metricType: sfxMetricTypeCumulativeCounter, value: 100},
+					{metric: "requests", host: "a", metricType: sfxMetricTypeCumulativeCounter, value: 0},
+				},
+			},
+			{
+				intVals:   map[string]int64{"a": 2},
+				doubleVal: 0.5,
+				want: []series{
+					{metric: "bytes", metricType: sfxMetricTypeCumulativeCounter, value: 0.5},
+					{metric: "cumulative", metricType: sfxMetricTypeCumulativeCounter, value: 100},
+					{metric: "requests", host: "a", metricType: sfxMetricTypeCumulativeCounter, value: 2},
+				},
+			},
+		}
+		for _, b := range batches {
+			dps, dropped := c.MetricDataToSignalFxV2(deltaSums(b.intVals, b.doubleVal))
+			assert.Equal(t, 0, dropped)
+			assert.Equal(t, b.want, toSeries(dps))
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{})
+		require.NoError(t, err)
+		for i := 0; i < 2; i++ {
+			dps, _ := c.MetricDataToSignalFxV2(deltaSums(map[string]int64{"a": 2}, 0.5))
+			assert.Equal(t, []series{
+				{metric: "bytes", metricType: sfxMetricTypeCounter, value: 0.5},
+				{metric: "cumulative", metricType: sfxMetricTypeCumulativeCounter, value: 100},
+				{metric: "requests", host: "a", metricType: sfxMetricTypeCounter, value: 2},
+			}, toSeries(dps))
+		}
+	})
+
+	t.Run("max_series", func(t *testing.T) {
+		core, logs := observer.New(zapcore.WarnLevel)
+		c, err := NewMetricsConverter(zap.New(core), nil, MetricsConverterOptions{
+			DeltaToCumulative:          true,
+			DeltaToCumulativeMaxSeries: 2,
+		})
+		require.NoError(t, err)
+		defer c.Shutdown()
+
+		// The series of "bytes" and of host "a" fill the state, host "b" is
+		// dropped until series are evicted.
+		dps, dropped := c.MetricDataToSignalFxV2(deltaSums(map[string]int64{"a": 1}, 0.5))
+		assert.Equal(t, 0, dropped)
+		assert.Len(t, dps, 3)
+
+		dps, dropped = c.MetricDataToSignalFxV2(deltaSums(map[string]int64{"a": 1, "b": 1}, 0.5))
+		assert.Equal(t, 1, dropped)
+		assert.Equal(t, []series{
+			{metric: "bytes", metricType: sfxMetricTypeCumulativeCounter, value: 1},
+			{metric: "cumulative", metricType: sfxMetricTypeCumulativeCounter, value: 100},
+			{metric: "requests", host: "a", metricType: sfxMetricTypeCumulativeCounter, value: 2},
+		}, toSeries(dps))
+		require.Equal(t, 1, logs.Len())
+		assert.Equal(t, "too many delta series accumulated, dropping datapoints", logs.All()[0].Message)
+	})
+}
+
+func TestMetricDataToSignalFxV2SanitizeBucketCounts(t *testing.T) {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(1)
+	rm := md.ResourceMetrics().At(0)
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(2)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("int_histo")
+	m.SetDataType(pdata.MetricDataTypeIntHistogram)
+	m.IntHistogram().DataPoints().Resize(1)
+	m.IntHistogram().DataPoints().At(0).SetCount(math.MaxUint64)
+	m.IntHistogram().DataPoints().At(0).SetSum(10)
+	m.IntHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1})
+	m.IntHistogram().DataPoints().At(0).SetBucketCounts([]uint64{math.MaxUint64, 2})
+
+	m = ilm.Metrics().At(1)
+	m.SetName("double_histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).SetCount(3)
+	m.DoubleHistogram().DataPoints().At(0).SetSum(10)
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{3, math.MaxInt64 + 1})
+
+	core, observedLogs := observer.New(zap.WarnLevel)
+	c, err := NewMetricsConverter(zap.New(core), nil, MetricsConverterOptions{SanitizeBucketCounts: true})
+	require.NoError(t, err)
+	got, _ := c.MetricDataToSignalFxV2(rm)
+
+	want := []*sfxpb.DataPoint{
+		int64SFxDataPoint("int_histo_count", 0, &sfxMetricTypeCumulativeCounter, nil, 0),
+		int64SFxDataPoint("int_histo", 0, &sfxMetricTypeCumulativeCounter, nil, 10),
+		int64SFxDataPoint("int_histo_bucket", 0, &sfxMetricTypeCumulativeCounter, map[string]string{"upper_bound": "1"}, 0),
+		int64SFxDataPoint("int_histo_bucket", 0, &sfxMetricTypeCumulativeCounter, map[string]string{"upper_bound": "+Inf"}, 2),
+		int64SFxDataPoint("double_histo_count", 0, &sfxMetricTypeCumulativeCounter, nil, 3),
+		doubleSFxDataPoint("double_histo", 0, &sfxMetricTypeCumulativeCounter, nil, 10),
+		int64SFxDataPoint("double_histo_bucket", 0, &sfxMetricTypeCumulativeCounter, map[string]string{"upper_bound": "1"}, 3),
+		int64SFxDataPoint("double_histo_bucket", 0, &sfxMetricTypeCumulativeCounter, map[string]string{"upper_bound": "+Inf"}, 0),
+	}
+	assert.Equal(t, want, got)
+	assert.Equal(t, len(got), c.DatapointCountEstimate(md))
+
+	var gotLogs []string
+	for _, entry := range observedLogs.All() {
+		gotLogs = append(gotLogs, entry.Message+": "+entry.ContextMap()["metric"].(string))
+	}
+	assert.Equal(t, []string{
+		"histogram count is negative when converted to int64, emitting zero: int_histo",
+		"histogram bucket count is negative when converted to int64, emitting zero: int_histo",
+		"histogram bucket count is negative when converted to int64, emitting zero: double_histo",
+	}, gotLogs)
+}
+
+func TestMetricDataToSignalFxV2LargeBucketCountsAsDouble(t *testing.T) {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(1)
+	rm := md.ResourceMetrics().At(0)
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(2)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("int_histo")
+	m.SetDataType(pdata.MetricDataTypeIntHistogram)
+	m.IntHistogram().DataPoints().Resize(1)
+	m.IntHistogram().DataPoints().At(0).SetCount(math.MaxInt64)
+	m.IntHistogram().DataPoints().At(0).SetSum(10)
+	m.IntHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1})
+	m.IntHistogram().DataPoints().At(0).SetBucketCounts([]uint64{math.MaxInt64, 0})
+
+	m = ilm.Metrics().At(1)
+	m.SetName("double_histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).SetCount(math.MaxInt64 + 4)
+	m.DoubleHistogram().DataPoints().At(0).SetSum(10)
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{3, math.MaxInt64 + 1})
+
+	core, observedLogs := observer.New(zap.WarnLevel)
+	c, err := NewMetricsConverter(zap.New(core), nil, MetricsConverterOptions{
+		LargeBucketCountsAsDouble: true,
+		SanitizeBucketCounts:      true,
+	})
+	require.NoError(t, err)
+	got, dropped := c.MetricDataToSignalFxV2(rm)
+
+	want := []*sfxpb.DataPoint{
+		int64SFxDataPoint("int_histo_count", 0, &sfxMetricTypeCumulativeCounter, nil, math.MaxInt64),
+		int64SFxDataPoint("int_histo", 0, &sfxMetricTypeCumulativeCounter, nil, 10),
+		int64SFxDataPoint("int_histo_bucket", 0, &sfxMetricTypeCumulativeCounter, map[string]string{"upper_bound": "1"}, math.MaxInt64),
+		int64SFxDataPoint("int_histo_bucket", 0, &sfxMetricTypeCumulativeCounter, map[string]string{"upper_bound": "+Inf"}, 0),
+		doubleSFxDataPoint("double_histo_count", 0, &sfxMetricTypeCumulativeCounter, nil, math.MaxInt64+4),
+		doubleSFxDataPoint("double_histo", 0, &sfxMetricTypeCumulativeCounter, nil, 10),
+		doubleSFxDataPoint("double_histo_bucket", 0, &sfxMetricTypeCumulativeCounter, map[string]string{"upper_bound": "1"}, 3),
+		doubleSFxDataPoint("double_histo_bucket", 0, &sfxMetricTypeCumulativeCounter, map[string]string{"upper_bound": "+Inf"}, math.MaxInt64+1),
+	}
+	assert.Equal(t, want, got)
+	assert.Equal(t, 0, dropped)
+	assert.Equal(t, len(got), c.DatapointCountEstimate(md))
+	assert.Equal(t, 0, observedLogs.Len())
+}
+
+func TestMetricDataToSignalFxV2PrometheusCumulativeBuckets(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(2)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("int_histo")
+	m.SetDataType(pdata.MetricDataTypeIntHistogram)
+	m.IntHistogram().DataPoints().Resize(1)
+	m.IntHistogram().DataPoints().At(0).SetCount(10)
+	m.IntHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1, 2, 4})
+	m.IntHistogram().DataPoints().At(0).SetBucketCounts([]uint64{4, 0, 2, 4})
+
+	m = ilm.Metrics().At(1)
+	m.SetName("double_histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).SetCount(10)
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1, 2, 4})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{4, 0, 2, 4})
+
+	tests := []struct {
+		name       string
+		options    MetricsConverterOptions
+		wantCounts []int64
+	}{
+		{
+			name:       "per_bucket",
+			wantCounts: []int64{4, 0, 2, 4},
+		},
+		{
+			name:    "cumulative",
+			options: MetricsConverterOptions{PrometheusCumulativeBuckets: true},
+			// The last finite bucket is the count minus the population of
+			// the Inf bucket, the Inf bucket is the count.
+			wantCounts: []int64{4, 4, 10 - 4, 10},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewMetricsConverter(zap.NewNop(), nil, tt.options)
+			require.NoError(t, err)
+			dps, _ := c.MetricDataToSignalFxV2(rm)
+
+			buckets := map[string][]int64{}
+			for _, dp := range dps {
+				if strings.HasSuffix(dp.Metric, "_bucket") {
+					buckets[dp.Metric] = append(buckets[dp.Metric], *dp.Value.IntValue)
+				}
+			}
+			require.Len(t, buckets, 2)
+			for name, counts := range buckets {
+				assert.Equal(t, tt.wantCounts, counts, name)
+			}
+		})
+	}
+}
+
+func TestDatapointCountEstimate(t *testing.T) {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(2)
+
+	{
+		rm := md.ResourceMetrics().At(0)
+		rm.InstrumentationLibraryMetrics().Resize(1)
+		ilm := rm.InstrumentationLibraryMetrics().At(0)
+		ilm.Metrics().Resize(3)
+
+		m := ilm.Metrics().At(0)
+		m.SetName("int_gauge")
+		m.SetDataType(pdata.MetricDataTypeIntGauge)
+		m.IntGauge().DataPoints().Resize(2)
+		m.IntGauge().DataPoints().Append(pdata.NewIntDataPoint())
+
+		m = ilm.Metrics().At(1)
+		m.SetName("double_sum")
+		m.SetDataType(pdata.MetricDataTypeDoubleSum)
+		m.DoubleSum().SetIsMonotonic(true)
+		m.DoubleSum().DataPoints().Resize(3)
+
+		m = ilm.Metrics().At(2)
+		m.SetName("no_type")
+	}
+	{
+		rm := md.ResourceMetrics().At(1)
+		rm.InstrumentationLibraryMetrics().Resize(1)
+		ilm := rm.InstrumentationLibraryMetrics().At(0)
+		ilm.Metrics().Resize(2)
+
+		m := ilm.Metrics().At(0)
+		m.SetName("int_histo")
+		m.SetDataType(pdata.MetricDataTypeIntHistogram)
+		m.IntHistogram().DataPoints().Resize(2)
+		m.IntHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1, 2, 4})
+		m.IntHistogram().DataPoints().At(0).SetBucketCounts([]uint64{4, 2, 3, 7})
+
+		m = ilm.Metrics().At(1)
+		m.SetName("double_histo")
+		m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+		m.DoubleHistogram().DataPoints().Resize(3)
+		m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1, 2})
+		m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{4, 2, 3})
+		// Mismatched and unsorted buckets only produce count and sum.
+		m.DoubleHistogram().DataPoints().At(1).SetExplicitBounds([]float64{1, 2})
+		m.DoubleHistogram().DataPoints().At(1).SetBucketCounts([]uint64{4, 2})
+		m.DoubleHistogram().DataPoints().At(2).SetExplicitBounds([]float64{2, 1})
+		m.DoubleHistogram().DataPoints().At(2).SetBucketCounts([]uint64{4, 2, 3})
+	}
+
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{})
+	require.NoError(t, err)
+
+	got := 0
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		dps, _ := c.MetricDataToSignalFxV2(md.ResourceMetrics().At(i))
+		got += len(dps)
+	}
+	assert.Equal(t, 2+3+(2+4)+2+(2+3)+2+2, got)
+	assert.Equal(t, got, c.DatapointCountEstimate(md))
+}
+
+func TestDatapointCountEstimateOptions(t *testing.T) {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(1)
+	rm := md.ResourceMetrics().At(0)
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(4)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(2)
+
+	m = ilm.Metrics().At(1)
+	m.SetName("delta")
+	m.SetDataType(pdata.MetricDataTypeIntSum)
+	m.IntSum().SetIsMonotonic(true)
+	m.IntSum().SetAggregationTemporality(pdata.AggregationTemporalityDelta)
+	m.IntSum().DataPoints().Resize(2)
+	m.IntSum().DataPoints().At(0).SetValue(1)
+	m.IntSum().DataPoints().At(0).LabelsMap().Insert("host", "a")
+	m.IntSum().DataPoints().At(1).SetValue(1)
+	m.IntSum().DataPoints().At(1).LabelsMap().Insert("host", "b")
+
+	m = ilm.Metrics().At(2)
+	m.SetName("cumulative")
+	m.SetDataType(pdata.MetricDataTypeIntSum)
+	m.IntSum().SetIsMonotonic(true)
+	m.IntSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.IntSum().DataPoints().Resize(1)
+	m.IntSum().DataPoints().At(0).SetValue(1)
+
+	m = ilm.Metrics().At(3)
+	m.SetName("histo")
+	m.SetDataType(pdata.MetricDataTypeDoubleHistogram)
+	m.DoubleHistogram().DataPoints().Resize(1)
+	m.DoubleHistogram().DataPoints().At(0).SetExplicitBounds([]float64{1})
+	m.DoubleHistogram().DataPoints().At(0).SetBucketCounts([]uint64{1, 2})
+
+	tests := []struct {
+		name         string
+		options      MetricsConverterOptions
+		rules        []Rule
+		wantDps      int
+		wantEstimate int
+	}{
+		{
+			name:         "default",
+			wantDps:      9,
+			wantEstimate: 9,
+		},
+		{
+			name:         "dedup_latest_gauge",
+			options:      MetricsConverterOptions{DedupLatestGauge: true},
+			wantDps:      8,
+			wantEstimate: 9,
+		},
+		{
+			name: "heartbeat",
+			options: MetricsConverterOptions{
+				EmitHeartbeat:      true,
+				RequireServiceName: true,
+			},
+			wantDps:      10,
+			wantEstimate: 10,
+		},
+		{
+			name: "no_heartbeat_from_provided_dimensions",
+			options: MetricsConverterOptions{
+				EmitHeartbeat:       true,
+				CollectorInstanceID: "collector-0",
+				DimensionProvider:   &stubDimensionProvider{dims: []*sfxpb.Dimension{{Key: "az", Value: "a"}}},
+			},
+			wantDps:      9,
+			wantEstimate: 9,
+		},
+		{
+			name:         "build_info",
+			options:      MetricsConverterOptions{EmitBuildInfo: "v1"},
+			wantDps:      10,
+			wantEstimate: 10,
+		},
+		{
+			name:         "conversion_stats",
+			options:      MetricsConverterOptions{EmitConversionStats: true},
+			wantDps:      13,
+			wantEstimate: 13,
+		},
+		{
+			name:         "quantile_estimates",
+			options:      MetricsConverterOptions{EmitQuantileEstimates: []float64{0.5, 0.9}},
+			wantDps:      11,
+			wantEstimate: 11,
+		},
+		{
+			name: "delta_to_cumulative_overflow",
+			options: MetricsConverterOptions{
+				DeltaToCumulative:          true,
+				DeltaToCumulativeMaxSeries: 1,
+			},
+			wantDps:      8,
+			wantEstimate: 9,
+		},
+		{
+			name:         "batch_limit",
+			options:      MetricsConverterOptions{MaxDatapointsPerBatch: 3},
+			wantDps:      2,
+			wantEstimate: 3,
+		},
+		{
+			// Datapoints added by translation rules aren't counted.
+			name: "translation_rules",
+			rules: []Rule{{
+				Action:  ActionCopyMetrics,
+				Mapping: map[string]string{"gauge": "gauge_copy"},
+			}},
+			wantDps:      11,
+			wantEstimate: 9,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mt *MetricTranslator
+			if len(tt.rules) > 0 {
+				var err error
+				mt, err = NewMetricTranslator(tt.rules, 1)
+				require.NoError(t, err)
+			}
+			c, err := NewMetricsConverter(zap.NewNop(), mt, tt.options)
+			require.NoError(t, err)
+			defer c.Shutdown()
+			estimate := c.DatapointCountEstimate(md)
+			dps, _ := c.MetricsToSignalFxV2(md)
+			assert.Len(t, dps, tt.wantDps)
+			assert.Equal(t, tt.wantEstimate, estimate)
+		})
+	}
+}
+
+func TestResourceAttributesToDimensionsAttributeFilter(t *testing.T) {
+	k8sAttr := regexp.MustCompile(`^k8s\.`)
+	notK8s := func(key string) bool {
+		return !k8sAttr.MatchString(key)
+	}
+
+	tests := []struct {
+		name     string
+		provider string
+		filter   func(key string) bool
+		wantDims []*sfxpb.Dimension
+	}{
+		{
+			name:     "cloud_filter_only",
+			provider: conventions.AttributeCloudProviderAWS,
+			wantDims: []*sfxpb.Dimension{
+				{Key: "AWSUniqueId", Value: "i-abcd_us-west-2_1234"},
+				{Key: "k8s.pod.name", Value: "pod0"},
+				{Key: "service.name", Value: "checkout"},
+			},
+		},
+		{
+			name:     "cloud_and_custom_filters",
+			provider: conventions.AttributeCloudProviderAWS,
+			filter:   notK8s,
+			wantDims: []*sfxpb.Dimension{
+				{Key: "AWSUniqueId", Value: "i-abcd_us-west-2_1234"},
+				{Key: "service.name", Value: "checkout"},
+			},
+		},
+		{
+			name:     "custom_filter_only",
+			provider: "other",
+			filter:   notK8s,
+			wantDims: []*sfxpb.Dimension{
+				{Key: "cloud.account.id", Value: "1234"},
+				{Key: "cloud.provider", Value: "other"},
+				{Key: "cloud.region", Value: "us-west-2"},
+				{Key: "host.id", Value: "i-abcd"},
+				{Key: "service.name", Value: "checkout"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := pdata.NewAttributeMap()
+			attrs.InsertString("cloud.provider", tt.provider)
+			attrs.InsertString("cloud.account.id", "1234")
+			attrs.InsertString("cloud.region", "us-west-2")
+			attrs.InsertString("host.id", "i-abcd")
+			attrs.InsertString("k8s.pod.name", "pod0")
+			attrs.InsertString("service.name", "checkout")
+
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{AttributeFilter: tt.filter})
+			require.NoError(t, err)
+			dims := c.resourceAttributesToDimensions(attrs)
+			sort.Slice(dims, func(i, j int) bool {
+				return dims[i].Key < dims[j].Key
+			})
+			assert.Equal(t, tt.wantDims, dims)
+		})
+	}
+}
+
+func TestResourceAttributesToDimensionsDimensionAllowLists(t *testing.T) {
+	options := MetricsConverterOptions{
+		DimensionAllowListAttribute: "tenant.id",
+		DimensionAllowLists: map[string][]string{
+			"tenant-a": {"tenant.id", "k8s.pod.name"},
+			"tenant-b": {"service.name"},
+		},
+		DefaultDimensionAllowList: []string{"host.name"},
+	}
+
+	tests := []struct {
+		name     string
+		tenant   string
+		options  MetricsConverterOptions
+		wantDims []*sfxpb.Dimension
+	}{
+		{
+			name:    "tenant_a",
+			tenant:  "tenant-a",
+			options: options,
+			wantDims: []*sfxpb.Dimension{
+				{Key: "k8s.pod.name", Value: "pod0"},
+				{Key: "tenant.id", Value: "tenant-a"},
+			},
+		},
+		{
+			name:    "tenant_b",
+			tenant:  "tenant-b",
+			options: options,
+			wantDims: []*sfxpb.Dimension{
+				{Key: "service.name", Value: "checkout"},
+			},
+		},
+		{
+			name:    "unknown_tenant",
+			tenant:  "tenant-c",
+			options: options,
+			wantDims: []*sfxpb.Dimension{
+				{Key: "host.name", Value: "host0"},
+			},
+		},
+		{
+			name:    "missing_tenant",
+			options: options,
+			wantDims: []*sfxpb.Dimension{
+				{Key: "host.name", Value: "host0"},
+			},
+		},
+		{
+			name:   "no_default",
+			tenant: "tenant-c",
+			options: MetricsConverterOptions{
+				DimensionAllowListAttribute: options.DimensionAllowListAttribute,
+				DimensionAllowLists:         options.DimensionAllowLists,
+			},
+			wantDims: []*sfxpb.Dimension{
+				{Key: "host.name", Value: "host0"},
+				{Key: "k8s.pod.name", Value: "pod0"},
+				{Key: "service.name", Value: "checkout"},
+				{Key: "tenant.id", Value: "tenant-c"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := pdata.NewAttributeMap()
+			if tt.tenant != "" {
+				attrs.InsertString("tenant.id", tt.tenant)
+			}
+			attrs.InsertString("host.name", "host0")
+			attrs.InsertString("k8s.pod.name", "pod0")
+			attrs.InsertString("service.name", "checkout")
+
+			c, err := NewMetricsConverter(zap.NewNop(), nil, tt.options)
+			require.NoError(t, err)
+			dims := c.resourceAttributesToDimensions(attrs)
+			sort.Slice(dims, func(i, j int) bool {
+				return dims[i].Key < dims[j].Key
+			})
+			assert.Equal(t, tt.wantDims, dims)
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2BlockedDimensionKeys(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	attrs := rm.Resource().Attributes()
+	attrs.InsertString("host.name", "host0")
+	attrs.InsertString("auth.token", "secret0")
+	attrs.InsertString("db.secret", "secret1")
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(1)
+	m := ilm.Metrics().At(0)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+	labels := m.IntGauge().DataPoints().At(0).LabelsMap()
+	labels.Insert("env", "prod")
+	labels.Insert("password", "secret2")
+	labels.Insert("user.secret", "secret3")
+
+	tests := []struct {
+		name        string
+		options     MetricsConverterOptions
+		wantKeys    []string
+		wantBlocked int
+	}{
+		{
+			name:     "not_blocked",
+			wantKeys: []string{"auth_token", "db_secret", "env", "host_name", "password", "user_secret"},
+		},
+		{
+			name: "blocked",
+			options: MetricsConverterOptions{
+				BlockedDimensionKeys: []string{"auth.token", "password"},
+			},
+			wantKeys:    []string{"db_secret", "env", "host_name", "user_secret"},
+			wantBlocked: 2,
+		},
+		{
+			name: "blocked_in_allow_list",
+			options: MetricsConverterOptions{
+				BlockedDimensionKeys:        []string{"auth.token", "password"},
+				DimensionAllowListAttribute: "tenant.id",
+				DefaultDimensionAllowList:   []string{"host.name", "auth.token"},
+			},
+			wantKeys:    []string{"env", "host_name", "user_secret"},
+			wantBlocked: 2,
+		},
+		{
+			name: "blocked_after_mapping",
+			options: MetricsConverterOptions{
+				BlockedDimensionKeys: []string{"auth.token", "password"},
+				DimensionKeyMapping: map[string]string{
+					"db.secret":   "password",
+					"user.secret": "auth.token",
+				},
+			},
+			wantKeys:    []string{"env", "host_name"},
+			wantBlocked: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, observedLogs := observer.New(zap.DebugLevel)
+			c, err := NewMetricsConverter(zap.New(core), nil, tt.options)
+			require.NoError(t, err)
+			dps, _ := c.MetricDataToSignalFxV2(rm)
+			require.Len(t, dps, 1)
+
+			var gotKeys []string
+			for _, d := range dps[0].Dimensions {
+				gotKeys = append(gotKeys, d.Key)
+			}
+			sort.Strings(gotKeys)
+			assert.Equal(t, tt.wantKeys, gotKeys)
+
+			assert.Equal(t, tt.wantBlocked, observedLogs.FilterMessage("dimension key is blocked, dropping dimension").Len())
+		})
+	}
+}
+
+func TestMetricsToSignalFxV2BlockedDimensionKeysInjected(t *testing.T) {
+	tests := []struct {
+		name    string
+		attrs   map[string]string
+		options MetricsConverterOptions
+		rules   []Rule
+		blocked string
+	}{
+		{
+			name:    "service",
+			options: MetricsConverterOptions{RequireServiceName: true},
+			blocked: "service",
+		},
+		{
+			name:    "host_name_fallback",
+			attrs:   map[string]string{"host.name": "h0"},
+			options: MetricsConverterOptions{HostNameFallback: true},
+			blocked: "host",
+		},
+		{
+			name:    "collector_id",
+			options: MetricsConverterOptions{CollectorInstanceID: "c0"},
+			blocked: "collector_id",
+		},
+		{
+			name: "dimension_provider",
+			options: MetricsConverterOptions{
+				DimensionProvider: &stubDimensionProvider{dims: []*sfxpb.Dimension{{Key: "az", Value: "a"}}},
+			},
+			blocked: "az",
+		},
+		{
+			name:    "otlp_type",
+			options: MetricsConverterOptions{AnnotateOTLPType: true},
+			blocked: "otlp_type",
+		},
+		{
+			name:    "metric_name_context",
+			options: MetricsConverterOptions{MetricNameDelimiter: "."},
+			blocked: "context",
+		},
+		{
+			name: "cloud_host_id",
+			attrs: map[string]string{
+				"cloud.provider":   "aws",
+				"cloud.account.id": "123",
+				"cloud.region":     "us-east-1",
+				"host.id":          "i-1",
+			},
+			blocked: "AWSUniqueId",
+		},
+		{
+			name:    "sample_rate",
+			options: MetricsConverterOptions{SampleRateLabel: "sampling.rate"},
+			blocked: "sample_rate",
+		},
+		{
+			name:  "heartbeat",
+			attrs: map[string]string{"host.name": "h0"},
+			options: MetricsConverterOptions{
+				EmitHeartbeat:       true,
+				CollectorInstanceID: "c0",
+			},
+			blocked: "collector_id",
+		},
+		{
+			name: "build_info",
+			options: MetricsConverterOptions{
+				EmitBuildInfo: "v1",
+			},
+			blocked: "version",
+		},
+		{
+			name:  "copy_dimension",
+			attrs: map[string]string{"host.name": "h0"},
+			rules: []Rule{
+				{
+					Action:  ActionCopyDimension,
+					Mapping: map[string]string{"host.name": "copied.host"},
+				},
+			},
+			blocked: "copied.host",
+		},
+		{
+			name:  "rename_dimension_keys",
+			attrs: map[string]string{"host.name": "h0"},
+			rules: []Rule{
+				{
+					Action:  ActionRenameDimensionKeys,
+					Mapping: map[string]string{"host.name": "renamed_host"},
+				},
+			},
+			blocked: "renamed_host",
+		},
+		{
+			name: "inject_dimension_by_metric",
+			rules: []Rule{
+				{
+					Action:             ActionInjectDimensionByMetric,
+					MetricNamePatterns: []string{"requests"},
+					AddDimensions:      map[string]string{"injected": "x"},
+				},
+			},
+			blocked: "injected",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := pdata.NewMetrics()
+			md.ResourceMetrics().Resize(1)
+			rm := md.ResourceMetrics().At(0)
+			for k, v := range tt.attrs {
+				rm.Resource().Attributes().InsertString(k, v)
+			}
+			rm.InstrumentationLibraryMetrics().Resize(1)
+			ilm := rm.InstrumentationLibraryMetrics().At(0)
+			ilm.Metrics().Resize(1)
+			m := ilm.Metrics().At(0)
+			m.SetName("app.requests")
+			m.SetDataType(pdata.MetricDataTypeIntGauge)
+			m.IntGauge().DataPoints().Resize(1)
+			m.IntGauge().DataPoints().At(0).LabelsMap().Insert("sampling.rate", "0.5")
+
+			var mt *MetricTranslator
+			if len(tt.rules) > 0 {
+				var err error
+				mt, err = NewMetricTranslator(tt.rules, 1)
+				require.NoError(t, err)
+			}
+			blockedKey := filterKeyChars(tt.blocked)
+			hasBlockedKey := func(dps []*sfxpb.DataPoint) bool {
+				for _, dp := range dps {
+					for _, d := range dp.Dimensions {
+						if d.Key == blockedKey {
+							return true
+						}
+					}
+				}
+				return false
+			}
+
+			// The dimension is only emitted if not blocked.
+			c, err := NewMetricsConverter(zap.NewNop(), mt, tt.options)
+			require.NoError(t, err)
+			dps, _ := c.MetricsToSignalFxV2(md)
+			require.True(t, hasBlockedKey(dps))
+
+			options := tt.options
+			options.BlockedDimensionKeys = []string{tt.blocked}
+			c, err = NewMetricsConverter(zap.NewNop(), mt, options)
+			require.NoError(t, err)
+			dps, _ = c.MetricsToSignalFxV2(md)
+			require.NotEmpty(t, dps)
+			assert.False(t, hasBlockedKey(dps))
+
+			byToken := c.MetricsToSignalFxV2ByToken(md)
+			assert.False(t, hasBlockedKey(byToken[""]))
+		})
+	}
+}
+
+func TestMetricsToSignalFxV2ConflictingMetricTypes(t *testing.T) {
+	md := pdata.NewMetrics()
+	md.ResourceMetrics().Resize(1)
+	rm := md.ResourceMetrics().At(0)
+	rm.InstrumentationLibraryMetrics().Resize(2)
+
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(1)
+	m := ilm.Metrics().At(0)
+	m.SetName("requests")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+	m.IntGauge().DataPoints().At(0).SetValue(1)
+
+	ilm = rm.InstrumentationLibraryMetrics().At(1)
+	ilm.Metrics().Resize(2)
+	m = ilm.Metrics().At(0)
+	m.SetName("requests")
+	m.SetDataType(pdata.MetricDataTypeIntSum)
+	m.IntSum().SetIsMonotonic(true)
+	m.IntSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.IntSum().DataPoints().Resize(2)
+	m.IntSum().DataPoints().At(0).SetValue(2)
+	m.IntSum().DataPoints().At(1).SetValue(3)
+	m = ilm.Metrics().At(1)
+	m.SetName("errors")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+
+	tests := []struct {
+		name        string
+		drop        bool
+		wantTypes   []sfxpb.MetricType
+		wantDropped int
+		wantMsg     string
+	}{
+		{
+			name: "kept",
+			wantTypes: []sfxpb.MetricType{
+				sfxpb.MetricType_GAUGE,
+				sfxpb.MetricType_CUMULATIVE_COUNTER,
+				sfxpb.MetricType_CUMULATIVE_COUNTER,
+				sfxpb.MetricType_GAUGE,
+			},
+			wantMsg: "metric name has conflicting types",
+		},
+		{
+			name: "dropped",
+			drop: true,
+			wantTypes: []sfxpb.MetricType{
+				sfxpb.MetricType_GAUGE,
+				sfxpb.MetricType_GAUGE,
+			},
+			wantDropped: 2,
+			wantMsg:     "metric name has conflicting types, dropping datapoints",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, observedLogs := observer.New(zap.WarnLevel)
+			c, err := NewMetricsConverter(zap.New(core), nil, MetricsConverterOptions{
+				DropConflictingMetricTypes: tt.drop,
+			})
+			require.NoError(t, err)
+
+			dps, dropped, stats := c.MetricsToSignalFxV2WithDropStats(md)
+			var gotTypes []sfxpb.MetricType
+			for _, dp := range dps {
+				gotTypes = append(gotTypes, *dp.MetricType)
+			}
+			assert.Equal(t, tt.wantTypes, gotTypes)
+			assert.Equal(t, tt.wantDropped, dropped)
+			assert.Equal(t, DropStats{TypeConflict: tt.wantDropped}, stats)
+			assert.Equal(t, len(tt.wantTypes), c.DatapointCountEstimate(md))
+
+			require.Equal(t, 1, observedLogs.Len())
+			entry := observedLogs.All()[0]
+			assert.Equal(t, tt.wantMsg, entry.Message)
+			assert.Equal(t, "requests", entry.ContextMap()["metric"])
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2SampleRate(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(3)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("int_counter")
+	m.SetDataType(pdata.MetricDataTypeIntSum)
+	m.IntSum().SetIsMonotonic(true)
+	m.IntSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.IntSum().DataPoints().Resize(3)
+	for i, rate := range []string{"0.1", "0.4", "0"} {
+		dp := m.IntSum().DataPoints().At(i)
+		dp.SetValue(3)
+		dp.LabelsMap().Insert("sampling.rate", rate)
+	}
+
+	m = ilm.Metrics().At(1)
+	m.SetName("double_counter")
+	m.SetDataType(pdata.MetricDataTypeDoubleSum)
+	m.DoubleSum().SetIsMonotonic(true)
+	m.DoubleSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.DoubleSum().DataPoints().Resize(1)
+	m.DoubleSum().DataPoints().At(0).SetValue(1.5)
+	m.DoubleSum().DataPoints().At(0).LabelsMap().Insert("sampling.rate", "0.5")
+
+	m = ilm.Metrics().At(2)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+	m.IntGauge().DataPoints().At(0).SetValue(3)
+	m.IntGauge().DataPoints().At(0).LabelsMap().Insert("sampling.rate", "0.1")
+
+	intVal := func(v int64) sfxpb.Datum { return sfxpb.Datum{IntValue: &v} }
+	doubleVal := func(v float64) sfxpb.Datum { return sfxpb.Datum{DoubleValue: &v} }
+
+	tests := []struct {
+		name       string
+		scale      bool
+		wantValues []sfxpb.Datum
+	}{
+		{
+			name:       "unscaled",
+			wantValues: []sfxpb.Datum{intVal(3), intVal(3), intVal(3), doubleVal(1.5), intVal(3)},
+		},
+		{
+			name:       "scaled",
+			scale:      true,
+			wantValues: []sfxpb.Datum{intVal(30), doubleVal(7.5), intVal(3), doubleVal(3), intVal(3)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+				SampleRateLabel:           "sampling.rate",
+				ScaleCountersBySampleRate: tt.scale,
+			})
+			require.NoError(t, err)
+			dps, _ := c.MetricDataToSignalFxV2(rm)
+			require.Len(t, dps, len(tt.wantValues))
+			for i, dp := range dps {
+				assert.Equal(t, tt.wantValues[i], dp.Value, dp.Metric)
+				require.Len(t, dp.Dimensions, 1)
+				assert.Equal(t, "sample_rate", dp.Dimensions[0].Key)
+			}
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2SampleRateWithValueTransformer(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(2)
+
+	// The single datapoint and multiple datapoints conversions take
+	// different paths.
+	m := ilm.Metrics().At(0)
+	m.SetName("single")
+	m.SetDataType(pdata.MetricDataTypeIntSum)
+	m.IntSum().SetIsMonotonic(true)
+	m.IntSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.IntSum().DataPoints().Resize(1)
+	m.IntSum().DataPoints().At(0).SetValue(1)
+	m.IntSum().DataPoints().At(0).LabelsMap().Insert("sampling.rate", "0.8")
+
+	m = ilm.Metrics().At(1)
+	m.SetName("multiple")
+	m.SetDataType(pdata.MetricDataTypeIntSum)
+	m.IntSum().SetIsMonotonic(true)
+	m.IntSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.IntSum().DataPoints().Resize(2)
+	for i, rate := range []string{"0.8", "0.5"} {
+		dp := m.IntSum().DataPoints().At(i)
+		dp.SetValue(1)
+		dp.LabelsMap().Insert("sampling.rate", rate)
+	}
+
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+		SampleRateLabel:           "sampling.rate",
+		ScaleCountersBySampleRate: true,
+		ValueTransformer: func(metric string, v float64) float64 {
+			return v + 1
+		},
+	})
+	require.NoError(t, err)
+	dps, _ := c.MetricDataToSignalFxV2(rm)
+
+	intVal := func(v int64) sfxpb.Datum { return sfxpb.Datum{IntValue: &v} }
+	doubleVal := func(v float64) sfxpb.Datum { return sfxpb.Datum{DoubleValue: &v} }
+	want := []sfxpb.Datum{doubleVal(2.5), doubleVal(2.5), intVal(4)}
+	require.Len(t, dps, len(want))
+	for i, dp := range dps {
+		assert.Equal(t, want[i], dp.Value, dp.Metric)
+	}
+}
+
+func TestResourceAttributesToDimensionsRequireServiceName(t *testing.T) {
+	tests := []struct {
+		name     string
+		attrs    map[string]string
+		fallback string
+		wantDims []*sfxpb.Dimension
+	}{
+		{
+			name:  "present",
+			attrs: map[string]string{"service.name": "checkout", "host.name": "host0"},
+			wantDims: []*sfxpb.Dimension{
+				{Key: "host.name", Value: "host0"},
+				{Key: "service", Value: "checkout"},
+				{Key: "service.name", Value: "checkout"},
+			},
+		},
+		{
+			name:  "absent",
+			attrs: map[string]string{"host.name": "host0"},
+			wantDims: []*sfxpb.Dimension{
+				{Key: "host.name", Value: "host0"},
+				{Key: "service", Value: "unknown_service"},
+			},
+		},
+		{
+			name:     "absent_custom_fallback",
+			attrs:    map[string]string{"host.name": "host0"},
+			fallback: "unnamed",
+			wantDims: []*sfxpb.Dimension{
+				{Key: "host.name", Value: "host0"},
+				{Key: "service", Value: "unnamed"},
+			},
+		},
+		{
+			name:  "empty",
+			attrs: map[string]string{"service.name": ""},
+			wantDims: []*sfxpb.Dimension{
+				{Key: "service", Value: "unknown_service"},
+				{Key: "service.name", Value: ""},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := pdata.NewAttributeMap()
+			for k, v := range tt.attrs {
+				attrs.InsertString(k, v)
+			}
+
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+				RequireServiceName:  true,
+				ServiceNameFallback: tt.fallback,
+			})
+			require.NoError(t, err)
+			dims := c.resourceAttributesToDimensions(attrs)
+			sort.Slice(dims, func(i, j int) bool {
+				return dims[i].Key < dims[j].Key
+			})
+			assert.Equal(t, tt.wantDims, dims)
+		})
+	}
+}
+
+func TestResourceAttributesToDimensionsHostIDAttributes(t *testing.T) {
+	customKeys := HostIDAttributes{
+		CloudAccount:  "custom.account",
+		CloudRegion:   "custom.region",
+		HostID:        "custom.instance",
+		CloudProvider: "custom.provider",
+	}
+
+	tests := []struct {
+		name     string
+		keys     HostIDAttributes
+		attrs    map[string]string
+		wantDims []*sfxpb.Dimension
+	}{
+		{
+			name: "aws_custom_keys",
+			keys: customKeys,
+			attrs: map[string]string{
+				"custom.provider": "aws",
+				"custom.account":  "1234",
+				"custom.region":   "us-west-2",
+				"custom.instance": "i-abcd",
+				"host.id":         "other",
+			},
+			wantDims: []*sfxpb.Dimension{
+				{Key: "AWSUniqueId", Value: "i-abcd_us-west-2_1234"},
+				{Key: "host.id", Value: "other"},
+			},
+		},
+		{
+			name: "gcp_custom_keys",
+			keys: customKeys,
+			attrs: map[string]string{
+				"custom.provider": "gcp",
+				"custom.account":  "5678",
+				"custom.instance": "instance0",
+			},
+			wantDims: []*sfxpb.Dimension{
+				{Key: "gcp_id", Value: "5678_instance0"},
+			},
+		},
+		{
+			name: "gcp_custom_project_number_key",
+			keys: HostIDAttributes{GCPProjectNumber: "custom.project.number"},
+			attrs: map[string]string{
+				"cloud.provider":        "gcp",
+				"cloud.account.id":      "my-project",
+				"custom.project.number": "5678",
+				"gcp.project.number":    "1234",
+				"host.id":               "instance0",
+			},
+			wantDims: []*sfxpb.Dimension{
+				{Key: "gcp.project.number", Value: "1234"},
+				{Key: "gcp_id", Value: "5678_instance0"},
+			},
+		},
+		{
+			name: "partially_overridden_keys",
+			keys: HostIDAttributes{HostID: "custom.instance"},
+			attrs: map[string]string{
+				"cloud.provider":   "aws",
+				"cloud.account.id": "1234",
+				"cloud.region":     "us-west-2",
+				"custom.instance":  "i-abcd",
+			},
+			wantDims: []*sfxpb.Dimension{
+				{Key: "AWSUniqueId", Value: "i-abcd_us-west-2_1234"},
+			},
+		},
+		{
+			name: "convention_keys_ignored",
+			keys: customKeys,
+			attrs: map[string]string{
+				"cloud.provider":   "aws",
+				"cloud.account.id": "1234",
+				"cloud.region":     "us-west-2",
+				"host.id":          "i-abcd",
+			},
+			wantDims: []*sfxpb.Dimension{
+				{Key: "cloud.account.id", Value: "1234"},
+				{Key: "cloud.provider", Value: "aws"},
+				{Key: "cloud.region", Value: "us-west-2"},
+				{Key: "host.id", Value: "i-abcd"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := pdata.NewAttributeMap()
+			for k, v := range tt.attrs {
+				attrs.InsertString(k, v)
+			}
+
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{HostIDAttributes: tt.keys})
+			require.NoError(t, err)
+			dims := c.resourceAttributesToDimensions(attrs)
+			sort.Slice(dims, func(i, j int) bool {
+				return dims[i].Key < dims[j].Key
+			})
+			assert.Equal(t, tt.wantDims, dims)
+		})
+	}
+}
+
+func TestResourceAttributesToDimensionsHostNameFallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		fallback bool
+		attrs    map[string]string
+		wantDims []*sfxpb.Dimension
+	}{
+		{
+			name:     "aws_missing_account",
+			fallback: true,
+			attrs: map[string]string{
+				"cloud.provider": "aws",
+				"cloud.region":   "us-west-2",
+				"host.id":        "i-abcd",
+				"host.name":      "host0",
+			},
+			wantDims: []*sfxpb.Dimension{
+				{Key: "cloud.provider", Value: "aws"},
+				{Key: "cloud.region", Value: "us-west-2"},
+				{Key: "host", Value: "host0"},
+				{Key: "host.id", Value: "i-abcd"},
+				{Key: "host.name", Value: "host0"},
+			},
+		},
+		{
+			name: "aws_missing_account_disabled",
+			attrs: map[string]string{
+				"cloud.provider": "aws",
+				"cloud.region":   "us-west-2",
+				"host.id":        "i-abcd",
+				"host.name":      "host0",
+			},
+			wantDims: []*sfxpb.Dimension{
+				{Key: "cloud.provider", Value: "aws"},
+				{Key: "cloud.region", Value: "us-west-2"},
+				{Key: "host.id", Value: "i-abcd"},
+				{Key: "host.name", Value: "host0"},
+			},
+		},
+		{
+			name:     "aws_complete",
+			fallback: true,
+			attrs: map[string]string{
+				"cloud.provider":   "aws",
+				"cloud.account.id": "1234",
+				"cloud.region":     "us-west-2",
+				"host.id":          "i-abcd",
+				"host.name":        "host0",
+			},
+			wantDims: []*sfxpb.Dimension{
+				{Key: "AWSUniqueId", Value: "i-abcd_us-west-2_1234"},
+				{Key: "host.name", Value: "host0"},
+			},
+		},
+		{
+			name:     "aws_missing_account_and_host_name",
+			fallback: true,
+			attrs: map[string]string{
+				"cloud.provider": "aws",
+				"host.id":        "i-abcd",
+			},
+			wantDims: []*sfxpb.Dimension{
+				{Key: "cloud.provider", Value: "aws"},
+				{Key: "host.id", Value: "i-abcd"},
+			},
+		},
+		{
+			name:     "existing_host",
+			fallback: true,
+			attrs: map[string]string{
+				"host":      "other",
+				"host.name": "host0",
+			},
+			wantDims: []*sfxpb.Dimension{
+				{Key: "host", Value: "other"},
+				{Key: "host.name", Value: "host0"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := pdata.NewAttributeMap()
+			for k, v := range tt.attrs {
+				attrs.InsertString(k, v)
+			}
+
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{HostNameFallback: tt.fallback})
+			require.NoError(t, err)
+			dims := c.resourceAttributesToDimensions(attrs)
+			sort.Slice(dims, func(i, j int) bool {
+				return dims[i].Key < dims[j].Key
+			})
+			assert.Equal(t, tt.wantDims, dims)
+		})
+	}
+}
+
+func TestResourceAttributesToDimensionsGCPProjectNumber(t *testing.T) {
+	tests := []struct {
+		name      string
+		attrs     map[string]pdata.AttributeValue
+		wantGCPID string
+		wantWarn  bool
+	}{
+		{
+			name: "project_number_string",
+			attrs: map[string]pdata.AttributeValue{
+				"cloud.account.id":   pdata.NewAttributeValueString("my-project"),
+				"gcp.project.number": pdata.NewAttributeValueString("1234"),
+			},
+			wantGCPID: "1234_abcd",
+		},
+		{
+			name: "project_number_int",
+			attrs: map[string]pdata.AttributeValue{
+				"gcp.project.number": pdata.NewAttributeValueInt(1234),
+			},
+			wantGCPID: "1234_abcd",
+		},
+		{
+			name: "numeric_account_id",
+			attrs: map[string]pdata.AttributeValue{
+				"cloud.account.id": pdata.NewAttributeValueString("1234"),
+			},
+			wantGCPID: "1234_abcd",
+		},
+		{
+			name: "textual_project_id_only",
+			attrs: map[string]pdata.AttributeValue{
+				"cloud.account.id": pdata.NewAttributeValueString("my-project"),
+			},
+			wantGCPID: "my-project_abcd",
+			wantWarn:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := pdata.NewAttributeMap()
+			attrs.InitFromMap(tt.attrs)
+			attrs.InsertString("cloud.provider", conventions.AttributeCloudProviderGCP)
+			attrs.InsertString("host.id", "abcd")
+
+			core, observedLogs := observer.New(zap.WarnLevel)
+			c, err := NewMetricsConverter(zap.New(core), nil, MetricsConverterOptions{})
+			require.NoError(t, err)
+			dims := c.resourceAttributesToDimensions(attrs)
+
+			require.Len(t, dims, 1)
+			assert.Equal(t, "gcp_id", dims[0].Key)
+			assert.Equal(t, tt.wantGCPID, dims[0].Value)
+
+			// The warning is only logged once per project.
+			c.resourceAttributesToDimensions(attrs)
+			if tt.wantWarn {
+				require.Equal(t, 1, observedLogs.Len())
+				assert.Equal(t, "my-project", observedLogs.All()[0].ContextMap()["project_id"])
+			} else {
+				assert.Equal(t, 0, observedLogs.Len())
+			}
+		})
+	}
+}
+
+func TestResourceAttributesToDimensionsGCPWarningsBounded(t *testing.T) {
+	core, observedLogs := observer.New(zap.WarnLevel)
+	c, err := NewMetricsConverter(zap.New(core), nil, MetricsConverterOptions{})
+	require.NoError(t, err)
+
+	convert := func(projectID string) {
+		attrs := pdata.NewAttributeMap()
+		attrs.InsertString("cloud.provider", conventions.AttributeCloudProviderGCP)
+		attrs.InsertString("cloud.account.id", projectID)
+		attrs.InsertString("host.id", "abcd")
+		c.resourceAttributesToDimensions(attrs)
+	}
+	for i := 0; i < maxWarnedGCPProjectIDs+10; i++ {
+		convert(fmt.Sprintf("project-%d", i))
+		assert.LessOrEqual(t, len(c.warnedGCPProjectIDs), maxWarnedGCPProjectIDs)
+	}
+	assert.Equal(t, maxWarnedGCPProjectIDs+10, observedLogs.Len())
+
+	// Projects remembered since the last reset aren't warned about again.
+	convert(fmt.Sprintf("project-%d", maxWarnedGCPProjectIDs+9))
+	assert.Equal(t, maxWarnedGCPProjectIDs+10, observedLogs.Len())
+}
+
+func TestMetricDataToSignalFxV2DropZeroValueCounters(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(5)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("int_counter")
+	m.SetDataType(pdata.MetricDataTypeIntSum)
+	m.IntSum().SetIsMonotonic(true)
+	m.IntSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.IntSum().DataPoints().Resize(2)
+	m.IntSum().DataPoints().At(1).SetValue(2)
+
+	m = ilm.Metrics().At(1)
+	m.SetName("double_delta_counter")
+	m.SetDataType(pdata.MetricDataTypeDoubleSum)
+	m.DoubleSum().SetIsMonotonic(true)
+	m.DoubleSum().SetAggregationTemporality(pdata.AggregationTemporalityDelta)
+	m.DoubleSum().DataPoints().Resize(2)
+	m.DoubleSum().DataPoints().At(1).SetValue(1.5)
+
+	m = ilm.Metrics().At(2)
+	m.SetName("int_gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+
+	m = ilm.Metrics().At(3)
+	m.SetName("double_gauge")
+	m.SetDataType(pdata.MetricDataTypeDoubleGauge)
+	m.DoubleGauge().DataPoints().Resize(1)
+
+	m = ilm.Metrics().At(4)
+	m.SetName("non_monotonic_sum")
+	m.SetDataType(pdata.MetricDataTypeIntSum)
+	m.IntSum().DataPoints().Resize(1)
+
+	tests := []struct {
+		name        string
+		options     MetricsConverterOptions
+		wantMetrics []string
+		wantDropped int
+	}{
+		{
+			name:        "disabled",
+			wantMetrics: []string{"int_counter", "int_counter", "double_delta_counter", "double_delta_counter", "int_gauge", "double_gauge", "non_monotonic_sum"},
+		},
+		{
+			name:        "enabled",
+			options:     MetricsConverterOptions{DropZeroValueCounters: true},
+			wantMetrics: []string{"int_counter", "double_delta_counter", "int_gauge", "double_gauge", "non_monotonic_sum"},
+			wantDropped: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewMetricsConverter(zap.NewNop(), nil, tt.options)
+			require.NoError(t, err)
+			dps, numDropped := c.MetricDataToSignalFxV2(rm)
+			var gotMetrics []string
+			for _, dp := range dps {
+				gotMetrics = append(gotMetrics, dp.Metric)
+			}
+			assert.Equal(t, tt.wantMetrics, gotMetrics)
+			assert.Equal(t, tt.wantDropped, numDropped)
+		})
+	}
+}
+
+func TestResourceAttributesToDimensionsArrayRendering(t *testing.T) {
+	arr := pdata.NewAttributeValueArray()
+	arr.ArrayVal().Append(pdata.NewAttributeValueString("a"))
+	arr.ArrayVal().Append(pdata.NewAttributeValueInt(2))
+	arr.ArrayVal().Append(pdata.NewAttributeValueString("c"))
+
+	tests := []struct {
+		name    string
+		options MetricsConverterOptions
+		want    string
+	}{
+		{
+			name: "default",
+			want: `["a",2,"c"]`,
+		},
+		{
+			name:    "json",
+			options: MetricsConverterOptions{ArrayAttributeRendering: ArrayAttributeRenderingJSON},
+			want:    `["a",2,"c"]`,
+		},
+		{
+			name:    "first",
+			options: MetricsConverterOptions{ArrayAttributeRendering: ArrayAttributeRenderingFirst},
+			want:    "a",
+		},
+		{
+			name: "join",
+			options: MetricsConverterOptions{
+				ArrayAttributeRendering: ArrayAttributeRenderingJoin,
+				ArrayAttributeSeparator: "|",
+			},
+			want: "a|2|c",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := pdata.NewAttributeMap()
+			attrs.Insert("arr", arr)
+
+			c, err := NewMetricsConverter(zap.NewNop(), nil, tt.options)
+			require.NoError(t, err)
+			dims := c.resourceAttributesToDimensions(attrs)
+			require.Len(t, dims, 1)
+			assert.Equal(t, "arr", dims[0].Key)
+			assert.Equal(t, tt.want, dims[0].Value)
+		})
+	}
+}
+
+func TestResourceAttributesToDimensionsBooleanEncoding(t *testing.T) {
+	arr := pdata.NewAttributeValueArray()
+	arr.ArrayVal().Append(pdata.NewAttributeValueBool(true))
+	arr.ArrayVal().Append(pdata.NewAttributeValueBool(false))
+
+	tests := []struct {
+		name     string
+		options  MetricsConverterOptions
+		wantDims map[string]string
+	}{
+		{
+			name: "default",
+			options: MetricsConverterOptions{
+				ArrayAttributeRendering: ArrayAttributeRenderingJoin,
+				ArrayAttributeSeparator: ",",
+			},
+			wantDims: map[string]string{
+				"enabled":  "true",
+				"disabled": "false",
+				"count":    "1",
+				"flags":    "true,false",
+			},
+		},
+		{
+			name: "truefalse",
+			options: MetricsConverterOptions{
+				BooleanDimensionEncoding: BooleanDimensionEncodingTrueFalse,
+				ArrayAttributeRendering:  ArrayAttributeRenderingJoin,
+				ArrayAttributeSeparator:  ",",
+			},
+			wantDims: map[string]string{
+				"enabled":  "true",
+				"disabled": "false",
+				"count":    "1",
+				"flags":    "true,false",
+			},
+		},
+		{
+			name: "onezero",
+			options: MetricsConverterOptions{
+				BooleanDimensionEncoding: BooleanDimensionEncodingOneZero,
+				ArrayAttributeRendering:  ArrayAttributeRenderingJoin,
+				ArrayAttributeSeparator:  ",",
+			},
+			wantDims: map[string]string{
+				"enabled":  "1",
+				"disabled": "0",
+				"count":    "1",
+				"flags":    "1,0",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := pdata.NewAttributeMap()
+			attrs.InsertBool("enabled", true)
+			attrs.InsertBool("disabled", false)
+			attrs.InsertInt("count", 1)
+			attrs.Insert("flags", arr)
+
+			c, err := NewMetricsConverter(zap.NewNop(), nil, tt.options)
+			require.NoError(t, err)
+			gotDims := map[string]string{}
+			for _, d := range c.resourceAttributesToDimensions(attrs) {
+				gotDims[d.Key] = d.Value
+			}
+			assert.Equal(t, tt.wantDims, gotDims)
+		})
+	}
+}
+
+func TestResourceAttributesToDimensionsAttributeTypeDimensions(t *testing.T) {
+	tests := []struct {
+		name     string
+		val      pdata.AttributeValue
+		wantDims []*sfxpb.Dimension
+	}{
+		{
+			name: "string",
+			val:  pdata.NewAttributeValueString("a"),
+			wantDims: []*sfxpb.Dimension{
+				{Key: "attr", Value: "a"},
+			},
+		},
+		{
+			name: "bool",
+			val:  pdata.NewAttributeValueBool(true),
+			wantDims: []*sfxpb.Dimension{
+				{Key: "attr", Value: "true"},
+				{Key: "attr_type", Value: "bool"},
+			},
+		},
+		{
+			name: "int",
+			val:  pdata.NewAttributeValueInt(42),
+			wantDims: []*sfxpb.Dimension{
+				{Key: "attr", Value: "42"},
+				{Key: "attr_type", Value: "int"},
+			},
+		},
+		{
+			name: "double",
+			val:  pdata.NewAttributeValueDouble(1.5),
+			wantDims: []*sfxpb.Dimension{
+				{Key: "attr", Value: "1.5"},
+				{Key: "attr_type", Value: "double"},
+			},
+		},
+		{
+			name: "map",
+			val:  pdata.NewAttributeValueMap(),
+			wantDims: []*sfxpb.Dimension{
+				{Key: "attr", Value: "{}"},
+				{Key: "attr_type", Value: "map"},
+			},
+		},
+		{
+			name: "array",
+			val:  pdata.NewAttributeValueArray(),
+			wantDims: []*sfxpb.Dimension{
+				{Key: "attr", Value: "[]"},
+				{Key: "attr_type", Value: "array"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := pdata.NewAttributeMap()
+			attrs.Insert("attr", tt.val)
+
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{AttributeTypeDimensions: true})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantDims, c.resourceAttributesToDimensions(attrs))
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2FlattenMapAttributes(t *testing.T) {
+	nested := pdata.NewAttributeValueMap()
+	nested.MapVal().InsertString("tier", "frontend")
+	labels := pdata.NewAttributeValueMap()
+	labels.MapVal().InsertString("app", "web")
+	labels.MapVal().InsertString("app.kubernetes.io/name", "web-ui")
+	labels.MapVal().Insert("nested", nested)
+
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.Resource().Attributes().InsertString("host.name", "host0")
+	rm.Resource().Attributes().Insert("k8s.labels", labels)
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(1)
+	m := ilm.Metrics().At(0)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+
+	tests := []struct {
+		name     string
+		flatten  bool
+		wantDims []*sfxpb.Dimension
+	}{
+		{
+			name: "disabled",
+			wantDims: []*sfxpb.Dimension{
+				{Key: "host_name", Value: "host0"},
+				{Key: "k8s_labels", Value: `{"app":"web","app.kubernetes.io/name":"web-ui","nested":{"tier":"frontend"}}`},
+			},
+		},
+		{
+			name:    "flatten",
+			flatten: true,
+			wantDims: []*sfxpb.Dimension{
+				{Key: "host_name", Value: "host0"},
+				{Key: "k8s_labels_app", Value: "web"},
+				{Key: "k8s_labels_app_kubernetes_io_name", Value: "web-ui"},
+				{Key: "k8s_labels_nested_tier", Value: "frontend"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{FlattenMapAttributes: tt.flatten})
+			require.NoError(t, err)
+			dps, _ := c.MetricDataToSignalFxV2(rm)
+			require.Len(t, dps, 1)
+			assert.Equal(t, tt.wantDims, dps[0].Dimensions)
+		})
+	}
+}
+
+func TestResourceAttributesToDimensionsAccessTokenHandler(t *testing.T) {
+	attrs := pdata.NewAttributeMap()
+	attrs.InsertString("service.name", "checkout")
+	attrs.InsertString(splunk.SFxAccessTokenLabel, "token")
+
+	var gotTokens []string
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+		AccessTokenHandler: func(accessToken string) {
+			gotTokens = append(gotTokens, accessToken)
+		},
+	})
+	require.NoError(t, err)
+	dims := c.resourceAttributesToDimensions(attrs)
+	assert.Equal(t, []*sfxpb.Dimension{{Key: "service.name", Value: "checkout"}}, dims)
+	assert.Equal(t, []string{"token"}, gotTokens)
+
+	// The handler isn't called for resources without token.
+	gotTokens = nil
+	attrs.Delete(splunk.SFxAccessTokenLabel)
+	dims = c.resourceAttributesToDimensions(attrs)
+	assert.Equal(t, []*sfxpb.Dimension{{Key: "service.name", Value: "checkout"}}, dims)
+	assert.Nil(t, gotTokens)
+
+	// Without handler the token is still dropped.
+	attrs.InsertString(splunk.SFxAccessTokenLabel, "token")
+	c, err = NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{})
+	require.NoError(t, err)
+	dims = c.resourceAttributesToDimensions(attrs)
+	assert.Equal(t, []*sfxpb.Dimension{{Key: "service.name", Value: "checkout"}}, dims)
+}
+
+func TestMetricDataToSignalFxV2WithProperties(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	attrs := rm.Resource().Attributes()
+	attrs.InsertString("service.name", "checkout")
+	attrs.InsertString("k8s.pod.uid", "5d6e7f")
+	attrs.InsertInt("process.pid", 1234)
+	attrs.InsertString(splunk.SFxAccessTokenLabel, "token")
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(1)
+	m := ilm.Metrics().At(0)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(2)
+
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+		PropertyAttributes: []string{"k8s.pod.uid", "process.pid", splunk.SFxAccessTokenLabel},
+	})
+	require.NoError(t, err)
+	dps, props, numDropped := c.MetricDataToSignalFxV2WithProperties(rm)
+	assert.Equal(t, 0, numDropped)
+	require.Len(t, dps, 2)
+
+	pid := int64(1234)
+	uid := "5d6e7f"
+	wantProps := []*sfxpb.Property{
+		{Key: "k8s.pod.uid", Value: &sfxpb.PropertyValue{StrValue: &uid}},
+		{Key: "process.pid", Value: &sfxpb.PropertyValue{IntValue: &pid}},
+	}
+	for _, dp := range dps {
+		assert.Equal(t, []*sfxpb.Dimension{{Key: "service_name", Value: "checkout"}}, dp.Dimensions)
+		gotProps := props[dp]
+		sort.Slice(gotProps, func(i, j int) bool {
+			return gotProps[i].Key < gotProps[j].Key
+		})
+		assert.Equal(t, wantProps, gotProps)
+	}
+
+	// Without designated keys all attributes remain dimensions.
+	c, err = NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{})
+	require.NoError(t, err)
+	dps, props, _ = c.MetricDataToSignalFxV2WithProperties(rm)
+	require.Len(t, dps, 2)
+	assert.Len(t, dps[0].Dimensions, 3)
+	assert.Empty(t, props)
+}
+
+func TestMetricDataToSignalFxV2StartTimestampProperty(t *testing.T) {
+	start := pdata.TimestampUnixNano(1600000000123456789)
+	wantStart := int64(1600000000123)
+
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.Resource().Attributes().InsertString("k8s.pod.uid", "5d6e7f")
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(4)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("int_counter")
+	m.SetDataType(pdata.MetricDataTypeIntSum)
+	m.IntSum().SetIsMonotonic(true)
+	m.IntSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.IntSum().DataPoints().Resize(2)
+	m.IntSum().DataPoints().At(0).SetStartTime(start)
+	m.IntSum().DataPoints().At(0).SetValue(1)
+	// Without start timestamp.
+	m.IntSum().DataPoints().At(1).SetValue(2)
+
+	m = ilm.Metrics().At(1)
+	m.SetName("double_counter")
+	m.SetDataType(pdata.MetricDataTypeDoubleSum)
+	m.DoubleSum().SetIsMonotonic(true)
+	m.DoubleSum().SetAggregationTemporality(pdata.AggregationTemporalityDelta)
+	m.DoubleSum().DataPoints().Resize(1)
+	m.DoubleSum().DataPoints().At(0).SetStartTime(start)
+	m.DoubleSum().DataPoints().At(0).SetValue(1.5)
+
+	m = ilm.Metrics().At(2)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+	m.IntGauge().DataPoints().At(0).SetStartTime(start)
+
+	m = ilm.Metrics().At(3)
+	m.SetName("non_monotonic_sum")
+	m.SetDataType(pdata.MetricDataTypeIntSum)
+	m.IntSum().SetAggregationTemporality(pdata.AggregationTemporalityCumulative)
+	m.IntSum().DataPoints().Resize(1)
+	m.IntSum().DataPoints().At(0).SetStartTime(start)
+
+	uid := "5d6e7f"
+	uidProp := &sfxpb.Property{Key: "k8s.pod.uid", Value: &sfxpb.PropertyValue{StrValue: &uid}}
+	startProp := &sfxpb.Property{Key: "start_timestamp_ms", Value: &sfxpb.PropertyValue{IntValue: &wantStart}}
+
+	tests := []struct {
+		name      string
+		enabled   bool
+		wantProps [][]*sfxpb.Property
+	}{
+		{
+			name:      "disabled",
+			wantProps: [][]*sfxpb.Property{{uidProp}, {uidProp}, {uidProp}, {uidProp}, {uidProp}},
+		},
+		{
+			name:      "enabled",
+			enabled:   true,
+			wantProps: [][]*sfxpb.Property{{uidProp, startProp}, {uidProp}, {uidProp, startProp}, {uidProp}, {uidProp}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+				PropertyAttributes:     []string{"k8s.pod.uid"},
+				StartTimestampProperty: tt.enabled,
+			})
+			require.NoError(t, err)
+			dps, props, _ := c.MetricDataToSignalFxV2WithProperties(rm)
+			require.Len(t, dps, 5)
+
+			var gotProps [][]*sfxpb.Property
+			for _, dp := range dps {
+				gotProps = append(gotProps, props[dp])
+			}
+			assert.Equal(t, tt.wantProps, gotProps)
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2MaxPropertyValueLength(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		wantValue string
+	}{
+		{
+			name:      "below_limit",
+			value:     "abcd",
+			wantValue: "abcd",
+		},
+		{
+			name:      "at_limit",
+			value:     "abcde",
+			wantValue: "abcde",
+		},
+		{
+			name:      "above_limit",
+			value:     "abcdef",
+			wantValue: "abcde",
+		},
+		{
+			name:      "multi_byte_character",
+			value:     "abcdé",
+			wantValue: "abcd",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rm := pdata.NewResourceMetrics()
+			rm.InitEmpty()
+			attrs := rm.Resource().Attributes()
+			attrs.InsertString("k8s.pod.uid", tt.value)
+			attrs.InsertString("k8s.pod.name", tt.value)
+			attrs.InsertInt("process.pid", 1234567)
+			rm.InstrumentationLibraryMetrics().Resize(1)
+			ilm := rm.InstrumentationLibraryMetrics().At(0)
+			ilm.Metrics().Resize(1)
+			m := ilm.Metrics().At(0)
+			m.SetName("gauge")
+			m.SetDescription(tt.value)
+			m.SetDataType(pdata.MetricDataTypeIntGauge)
+			m.IntGauge().DataPoints().Resize(1)
+
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{
+				PropertyAttributes:       []string{"k8s.pod.uid", "process.pid"},
+				MetricMetadataProperties: []MetricMetadataField{MetricMetadataFieldDescription},
+				MaxPropertyValueLength:   5,
+			})
+			require.NoError(t, err)
+			dps, props, _ := c.MetricDataToSignalFxV2WithProperties(rm)
+			require.Len(t, dps, 1)
+
+			// Dimension values are not truncated.
+			assert.Equal(t, []*sfxpb.Dimension{{Key: "k8s_pod_name", Value: tt.value}}, dps[0].Dimensions)
+
+			gotProps := props[dps[0]]
+			sort.Slice(gotProps, func(i, j int) bool {
+				return gotProps[i].Key < gotProps[j].Key
+			})
+			pid := int64(1234567)
+			assert.Equal(t, []*sfxpb.Property{
+				{Key: "description", Value: &sfxpb.PropertyValue{StrValue: &tt.wantValue}},
+				{Key: "k8s.pod.uid", Value: &sfxpb.PropertyValue{StrValue: &tt.wantValue}},
+				{Key: "process.pid", Value: &sfxpb.PropertyValue{IntValue: &pid}},
+			}, gotProps)
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2SingleDatapoint(t *testing.T) {
+	nilIntPt := pdata.NewIntDataPoint()
+	nilDoublePt := pdata.NewDoubleDataPoint()
+
+	intPt := pdata.NewIntDataPoint()
+	intPt.InitEmpty()
+	intPt.SetValue(13)
+	intPt.SetTimestamp(pdata.TimestampUnixNano(2e6))
+	intPt.LabelsMap().InitFromMap(map[string]string{"k0": "v0"})
+
+	doublePt := pdata.NewDoubleDataPoint()
+	doublePt.InitEmpty()
+	doublePt.SetValue(13.1)
+	doublePt.SetTimestamp(pdata.TimestampUnixNano(2e6))
+
+	tests := []struct {
+		name    string
+		fill    func(m pdata.Metric)
+		wantDPs []*sfxpb.DataPoint
+	}{
+		{
+			name: "int",
+			fill: func(m pdata.Metric) {
+				m.SetDataType(pdata.MetricDataTypeIntGauge)
+				m.IntGauge().DataPoints().Append(intPt)
+			},
+			wantDPs: []*sfxpb.DataPoint{
+				int64SFxDataPoint("single", 2, &sfxMetricTypeGauge, map[string]string{"k0": "v0", "host": "host0"}, 13),
+			},
+		},
+		{
+			name: "double",
+			fill: func(m pdata.Metric) {
+				m.SetDataType(pdata.MetricDataTypeDoubleGauge)
+				m.DoubleGauge().DataPoints().Append(doublePt)
+			},
+			wantDPs: []*sfxpb.DataPoint{
+				doubleSFxDataPoint("single", 2, &sfxMetricTypeGauge, map[string]string{"host": "host0"}, 13.1),
+			},
+		},
+		{
+			name: "nil_int",
+			fill: func(m pdata.Metric) {
+				m.SetDataType(pdata.MetricDataTypeIntGauge)
+				m.IntGauge().DataPoints().Append(nilIntPt)
+			},
+		},
+		{
+			name: "nil_double",
+			fill: func(m pdata.Metric) {
+				m.SetDataType(pdata.MetricDataTypeDoubleGauge)
+				m.DoubleGauge().DataPoints().Append(nilDoublePt)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rm := pdata.NewResourceMetrics()
+			rm.InitEmpty()
+			rm.Resource().Attributes().InsertString("host", "host0")
+			rm.InstrumentationLibraryMetrics().Resize(1)
+			ilm := rm.InstrumentationLibraryMetrics().At(0)
+			ilm.Metrics().Resize(1)
+			m := ilm.Metrics().At(0)
+			m.SetName("single")
+			tt.fill(m)
+
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{})
+			require.NoError(t, err)
+			gotDPs, numDropped := c.MetricDataToSignalFxV2(rm)
+			assert.Equal(t, 0, numDropped)
+			sortDimensions(tt.wantDPs)
+			sortDimensions(gotDPs)
+			assert.Equal(t, tt.wantDPs, gotDPs)
+		})
+	}
+}
+
+func TestMetricDataToSignalFxV2MetricMetadataProperties(t *testing.T) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.Resource().Attributes().InsertString("k8s.pod.uid", "5d6e7f")
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(2)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("described")
+	m.SetDescription("A described gauge")
+	m.SetUnit("ms")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(3)
+
+	m = ilm.Metrics().At(1)
+	m.SetName("not_described")
+	m.SetUnit("1")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(2)
+
+	desc := "A described gauge"
+	descProp := &sfxpb.Property{Key: "description", Value: &sfxpb.PropertyValue{StrValue: &desc}}
+	uid := "5d6e7f"
+	uidProp := &sfxpb.Property{Key: "k8s.pod.uid", Value: &sfxpb.PropertyValue{StrValue: &uid}}
+	ms, one := "ms", "1"
+	msProp := &sfxpb.Property{Key: "unit", Value: &sfxpb.PropertyValue{StrValue: &ms}}
+	oneProp := &sfxpb.Property{Key: "unit", Value: &sfxpb.PropertyValue{StrValue: &one}}
+
+	tests := []struct {
+		name      string
+		options   MetricsConverterOptions
+		wantProps [][]*sfxpb.Property
+	}{
+		{
+			name:      "disabled",
+			wantProps: [][]*sfxpb.Property{nil, nil, nil, nil, nil},
+		},
+		{
+			name:      "enabled",
+			options:   MetricsConverterOptions{IncludeMetricDescription: true},
+			wantProps: [][]*sfxpb.Property{{descProp}, nil, nil, nil, nil},
+		},
+		{
+			name: "enabled_with_resource_properties",
+			options: MetricsConverterOptions{
+				IncludeMetricDescription: true,
+				PropertyAttributes:       []string{"k8s.pod.uid"},
+			},
+			wantProps: [][]*sfxpb.Property{{uidProp, descProp}, {uidProp}, {uidProp}, {uidProp}, {uidProp}},
+		},
+		{
+			name: "description_and_unit",
+			options: MetricsConverterOptions{
+				MetricMetadataProperties: []MetricMetadataField{MetricMetadataFieldDescription, MetricMetadataFieldUnit},
+			},
+			wantProps: [][]*sfxpb.Property{{descProp, msProp}, nil, nil, {oneProp}, nil},
+		},
+		{
+			name: "description_option_and_fields",
+			options: MetricsConverterOptions{
+				IncludeMetricDescription: true,
+				MetricMetadataProperties: []MetricMetadataField{MetricMetadataFieldUnit, MetricMetadataFieldDescription},
+			},
+			wantProps: [][]*sfxpb.Property{{descProp, msProp}, nil, nil, {oneProp}, nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewMetricsConverter(zap.NewNop(), nil, tt.options)
+			require.NoError(t, err)
+			dps, props, _ := c.MetricDataToSignalFxV2WithProperties(rm)
+			require.Len(t, dps, len(tt.wantProps))
+			for i, dp := range dps {
+				assert.Equal(t, tt.wantProps[i], props[dp], "datapoint %d", i)
+			}
+		})
+	}
+}
+
+func BenchmarkMetricDataToSignalFxV2SingleDatapointGauges(b *testing.B) {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.Resource().Attributes().InsertString("host.name", "host0")
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(2)
+
+	m := ilm.Metrics().At(0)
+	m.SetName("int_gauge")
+	m.SetDataType(pdata.MetricDataTypeIntGauge)
+	m.IntGauge().DataPoints().Resize(1)
+	m.IntGauge().DataPoints().At(0).SetValue(13)
+	m.IntGauge().DataPoints().At(0).LabelsMap().InitFromMap(map[string]string{"k0": "v0"})
+
+	m = ilm.Metrics().At(1)
+	m.SetName("double_gauge")
+	m.SetDataType(pdata.MetricDataTypeDoubleGauge)
+	m.DoubleGauge().DataPoints().Resize(1)
+	m.DoubleGauge().DataPoints().At(0).SetValue(13.1)
+	m.DoubleGauge().DataPoints().At(0).LabelsMap().InitFromMap(map[string]string{"k0": "v0"})
+
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{})
+	require.NoError(b, err)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.MetricDataToSignalFxV2(rm)
+	}
+}
+
+func TestMetricDataToSignalFxV2PoolDimensionBuffers(t *testing.T) {
+	rm := newLabeledGaugesResourceMetrics(3, 4)
+
+	c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{})
+	require.NoError(t, err)
+	want, _ := c.MetricDataToSignalFxV2(rm)
+
+	c, err = NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{PoolDimensionBuffers: true})
+	require.NoError(t, err)
+	got, _ := c.MetricDataToSignalFxV2(rm)
+	assert.Equal(t, want, got)
+
+	// Datapoints of a previous conversion must not be affected by the reuse
+	// of the buffers.
+	for i := 0; i < 10; i++ {
+		_, _ = c.MetricDataToSignalFxV2(rm)
+	}
+	assert.Equal(t, want, got)
+}
+
+func BenchmarkMetricDataToSignalFxV2PoolDimensionBuffers(b *testing.B) {
+	rm := newLabeledGaugesResourceMetrics(100, 5)
+	for _, pool := range []bool{false, true} {
+		b.Run(fmt.Sprintf("pool=%t", pool), func(b *testing.B) {
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{PoolDimensionBuffers: pool})
+			require.NoError(b, err)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				c.MetricDataToSignalFxV2(rm)
+			}
+		})
+	}
+}
+
+func BenchmarkMetricDataToSignalFxV2InternDimensionStrings(b *testing.B) {
+	rm := newRepetitiveLabeledGaugesResourceMetrics(100)
+	for _, intern := range []bool{false, true} {
+		b.Run(fmt.Sprintf("intern=%t", intern), func(b *testing.B) {
+			c, err := NewMetricsConverter(zap.NewNop(), nil, MetricsConverterOptions{InternDimensionStrings: intern})
+			require.NoError(b, err)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				c.MetricDataToSignalFxV2(rm)
+			}
+		})
+	}
+}
+
+// newRepetitiveLabeledGaugesResourceMetrics creates a ResourceMetrics with a
+// gauge having numDPs datapoints with the same label keys, which need to be
+// sanitized, and few distinct label values.
+func newRepetitiveLabeledGaugesResourceMetrics(numDPs int) pdata.ResourceMetrics {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.Resource().Attributes().InsertString("host.name", "host0")
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(1)
+	m := ilm.Metrics().At(0)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeDoubleGauge)
+	m.DoubleGauge().DataPoints().Resize(numDPs)
+	for i := 0; i < numDPs; i++ {
+		dp := m.DoubleGauge().DataPoints().At(i)
+		dp.SetValue(float64(i))
+		dp.LabelsMap().InitFromMap(map[string]string{
+			"k8s.namespace.name": "default",
+			"k8s.pod.name":       fmt.Sprintf("pod%d", i%4),
+			"http.method":        "GET",
+			"http.status_code":   fmt.Sprintf("%d", 200+i%2),
+		})
+	}
+	return rm
+}
+
+// newLabeledGaugesResourceMetrics creates a ResourceMetrics with a gauge
+// having numDPs datapoints with numLabels labels each.
+func newLabeledGaugesResourceMetrics(numDPs, numLabels int) pdata.ResourceMetrics {
+	rm := pdata.NewResourceMetrics()
+	rm.InitEmpty()
+	rm.Resource().Attributes().InsertString("host.name", "host0")
+	rm.InstrumentationLibraryMetrics().Resize(1)
+	ilm := rm.InstrumentationLibraryMetrics().At(0)
+	ilm.Metrics().Resize(1)
+	m := ilm.Metrics().At(0)
+	m.SetName("gauge")
+	m.SetDataType(pdata.MetricDataTypeDoubleGauge)
+	m.DoubleGauge().DataPoints().Resize(numDPs)
+	for i := 0; i < numDPs; i++ {
+		dp := m.DoubleGauge().DataPoints().At(i)
+		dp.SetValue(float64(i))
+		labels := make(map[string]string, numLabels)
+		for j := 0; j < numLabels; j++ {
+			labels[fmt.Sprintf("k%d", j)] = fmt.Sprintf("v%d_%d", i, j)
+		}
+		dp.LabelsMap().InitFromMap(labels)
+	}
+	return rm
 }
 
 func sortDimensions(points []*sfxpb.DataPoint) {
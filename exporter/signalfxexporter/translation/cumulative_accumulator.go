@@ -0,0 +1,110 @@
+// Copyright 2020, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package translation
+
+import (
+	"sync"
+
+	sfxpb "github.com/signalfx/com_signalfx_metrics_protobuf/model"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/exporter/signalfxexporter/ttlmap"
+)
+
+// cumulativeAccumulator accumulates the values of delta datapoints per metric
+// and dimensions into running totals emitted as cumulative counters.
+type cumulativeAccumulator struct {
+	totals    *ttlmap.TTLMap
+	maxSeries int
+	// mu makes reading and updating a total atomic across concurrent
+	// conversions.
+	mu sync.Mutex
+}
+
+// cumulativeTotal is the running total of a series, either int or double
+// depending on the values of its datapoints.
+type cumulativeTotal struct {
+	intVal    int64
+	doubleVal float64
+	isDouble  bool
+}
+
+func newCumulativeAccumulator(ttl int64, maxSeries int) *cumulativeAccumulator {
+	sweepIntervalSeconds := ttl / 2
+	if sweepIntervalSeconds == 0 {
+		sweepIntervalSeconds = 1
+	}
+	m := ttlmap.New(sweepIntervalSeconds, ttl)
+	m.Start()
+	return &cumulativeAccumulator{totals: m, maxSeries: maxSeries}
+}
+
+// shutdown stops the eviction of expired totals.
+func (a *cumulativeAccumulator) shutdown() {
+	a.totals.Shutdown()
+}
+
+// accumulate adds the values of the delta datapoints to the totals of their
+// series, replacing them with the totals and making them cumulative counters.
+// A negative delta means that the source counter went below its previous value
+// and was reset, the total of the series then starts over from zero instead of
+// decreasing. Datapoints of series not tracked yet are dropped once maxSeries
+// series are tracked, the kept datapoints and the number of dropped ones are
+// returned.
+func (a *cumulativeAccumulator) accumulate(dps []*sfxpb.DataPoint) ([]*sfxpb.DataPoint, int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	kept := dps[:0]
+	numDropped := 0
+	for _, dp := range dps {
+		key := dp.Metric + ":" + stringifyDimensions(dp.Dimensions, nil)
+		var total cumulativeTotal
+		if v := a.totals.Get(key); v != nil {
+			total = v.(cumulativeTotal)
+		} else if a.totals.Len() >= a.maxSeries {
+			numDropped++
+			continue
+		}
+
+		switch {
+		case dp.Value.IntValue != nil:
+			if total.isDouble || *dp.Value.IntValue < 0 {
+				// The value type changed or the counter was reset, start over.
+				total = cumulativeTotal{}
+			}
+			if *dp.Value.IntValue > 0 {
+				total.intVal += *dp.Value.IntValue
+			}
+			v := total.intVal
+			dp.Value.IntValue = &v
+		case dp.Value.DoubleValue != nil:
+			if !total.isDouble || *dp.Value.DoubleValue < 0 {
+				total = cumulativeTotal{isDouble: true}
+			}
+			if *dp.Value.DoubleValue > 0 {
+				total.doubleVal += *dp.Value.DoubleValue
+			}
+			v := total.doubleVal
+			dp.Value.DoubleValue = &v
+		default:
+			kept = append(kept, dp)
+			continue
+		}
+		a.totals.Put(key, total)
+		dp.MetricType = &sfxMetricTypeCumulativeCounter
+		kept = append(kept, dp)
+	}
+	return kept, numDropped
+}
@@ -42,16 +42,12 @@ func (t *deltaTranslator) translate(pts []*sfxpb.DataPoint, tr Rule) []*sfxpb.Da
 			// only metrics defined in Rule.Mapping get translated
 			continue
 		}
-		deltaPt := t.deltaPt(deltaMetricName, currPt)
-		if deltaPt == nil {
-			continue
-		}
-		pts = append(pts, deltaPt)
+		pts = append(pts, t.deltaPts(deltaMetricName, currPt, tr.ZeroOnReset)...)
 	}
 	return pts
 }
 
-func (t *deltaTranslator) deltaPt(deltaMetricName string, currPt *sfxpb.DataPoint) *sfxpb.DataPoint {
+func (t *deltaTranslator) deltaPts(deltaMetricName string, currPt *sfxpb.DataPoint, zeroOnReset bool) []*sfxpb.DataPoint {
 	// check if we have a previous point for this metric + dimensions
 	dimKey := stringifyDimensions(currPt.Dimensions, nil)
 	fullKey := currPt.Metric + ":" + dimKey
@@ -64,36 +60,57 @@ func (t *deltaTranslator) deltaPt(deltaMetricName string, currPt *sfxpb.DataPoin
 	}
 	prevPt := v.(*sfxpb.DataPoint)
 	var deltaPt *sfxpb.DataPoint
+	var reset bool
 	if currPt.Value.DoubleValue != nil && prevPt.Value.DoubleValue != nil {
-		deltaPt = doubleDeltaPt(currPt, prevPt, deltaMetricName)
+		deltaPt, reset = doubleDeltaPt(currPt, prevPt, deltaMetricName)
 	} else if currPt.Value.IntValue != nil && prevPt.Value.IntValue != nil {
-		deltaPt = intDeltaPt(currPt, prevPt, deltaMetricName)
+		deltaPt, reset = intDeltaPt(currPt, prevPt, deltaMetricName)
 	} else {
 		return nil
 	}
-	return deltaPt
+	if reset && zeroOnReset && currPt.Timestamp-1 > prevPt.Timestamp {
+		// emit an explicit zero right before the reset so rate charts dip instead of spiking, SignalFx keeps
+		// a single point per timestamp so it can't share the timestamp of the delta
+		return []*sfxpb.DataPoint{zeroPt(deltaPt), deltaPt}
+	}
+	return []*sfxpb.DataPoint{deltaPt}
 }
 
-func doubleDeltaPt(currPt *sfxpb.DataPoint, prevPt *sfxpb.DataPoint, deltaMetricName string) *sfxpb.DataPoint {
+func doubleDeltaPt(currPt *sfxpb.DataPoint, prevPt *sfxpb.DataPoint, deltaMetricName string) (*sfxpb.DataPoint, bool) {
 	delta := *currPt.Value.DoubleValue - *prevPt.Value.DoubleValue
-	if delta < 0 {
-		// assume a reset, emit the current value
+	reset := delta < 0
+	if reset {
+		// assume a reset, use the current value
 		delta = *currPt.Value.DoubleValue
 	}
 	deltaPt := basePt(currPt, deltaMetricName)
 	*deltaPt.Value.DoubleValue = delta
-	return deltaPt
+	return deltaPt, reset
 }
 
-func intDeltaPt(currPt *sfxpb.DataPoint, prevPt *sfxpb.DataPoint, deltaMetricName string) *sfxpb.DataPoint {
+func intDeltaPt(currPt *sfxpb.DataPoint, prevPt *sfxpb.DataPoint, deltaMetricName string) (*sfxpb.DataPoint, bool) {
 	delta := *currPt.Value.IntValue - *prevPt.Value.IntValue
-	if delta < 0 {
-		// assume a reset, emit the current value
+	reset := delta < 0
+	if reset {
+		// assume a reset, use the current value
 		delta = *currPt.Value.IntValue
 	}
 	deltaPt := basePt(currPt, deltaMetricName)
 	*deltaPt.Value.IntValue = delta
-	return deltaPt
+	return deltaPt, reset
+}
+
+// zeroPt returns a copy of deltaPt with a zero value of the same type, one
+// timestamp unit before it.
+func zeroPt(deltaPt *sfxpb.DataPoint) *sfxpb.DataPoint {
+	pt := proto.Clone(deltaPt).(*sfxpb.DataPoint)
+	pt.Timestamp--
+	if pt.Value.DoubleValue != nil {
+		*pt.Value.DoubleValue = 0
+	} else {
+		*pt.Value.IntValue = 0
+	}
+	return pt
 }
 
 var metricTypeGauge = sfxpb.MetricType_GAUGE
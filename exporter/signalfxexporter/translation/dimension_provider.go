@@ -0,0 +1,29 @@
+// Copyright 2020, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package translation
+
+import (
+	sfxpb "github.com/signalfx/com_signalfx_metrics_protobuf/model"
+)
+
+// DimensionProvider provides dimensions added to all the datapoints converted
+// by a MetricsConverter, e.g. host metadata from a local metadata service that
+// isn't available as resource attributes.
+type DimensionProvider interface {
+	// Dimensions is called once per conversion call and returns the
+	// dimensions to add. The returned dimensions are copied, so they can be
+	// reused across calls. It must be safe for concurrent use.
+	Dimensions() []*sfxpb.Dimension
+}
@@ -0,0 +1,116 @@
+// Copyright 2020, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package translation
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	sfxpb "github.com/signalfx/com_signalfx_metrics_protobuf/model"
+)
+
+// dropEventType is the event type of the events summarizing datapoints
+// dropped by the conversion with the EmitDropEvents option.
+const dropEventType = "otel_collector_conversion_drops"
+
+// dropEventAggregator counts datapoints dropped per metric name and reason
+// between emissions of drop events, emitting at most maxEvents events once
+// per interval.
+type dropEventAggregator struct {
+	interval  time.Duration
+	maxEvents int
+
+	mu       sync.Mutex
+	lastEmit time.Time
+	// Number of dropped datapoints per reason per metric name.
+	drops map[string]map[string]int
+}
+
+func newDropEventAggregator(interval time.Duration, maxEvents int) *dropEventAggregator {
+	return &dropEventAggregator{
+		interval:  interval,
+		maxEvents: maxEvents,
+		drops:     map[string]map[string]int{},
+	}
+}
+
+// record counts count datapoints of the metric dropped for the given reason,
+// it does nothing on a nil aggregator.
+func (a *dropEventAggregator) record(metric string, reason string, count int) {
+	if a == nil || count == 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	reasons, ok := a.drops[metric]
+	if !ok {
+		reasons = map[string]int{}
+		a.drops[metric] = reasons
+	}
+	reasons[reason] += count
+}
+
+// events returns one event per metric name with drops recorded since the
+// last emission, sorted by metric name and capped to maxEvents, and resets
+// the counts. Nothing is returned if there are no drops or if the last
+// emission is less than interval before now. The number of metrics whose
+// events are omitted by the cap is returned too.
+func (a *dropEventAggregator) events(now time.Time) ([]*sfxpb.Event, int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.drops) == 0 || (!a.lastEmit.IsZero() && now.Sub(a.lastEmit) < a.interval) {
+		return nil, 0
+	}
+
+	metrics := make([]string, 0, len(a.drops))
+	for metric := range a.drops {
+		metrics = append(metrics, metric)
+	}
+	sort.Strings(metrics)
+	numOmitted := 0
+	if len(metrics) > a.maxEvents {
+		numOmitted = len(metrics) - a.maxEvents
+		metrics = metrics[:a.maxEvents]
+	}
+
+	ts := now.UnixNano() / int64(time.Millisecond)
+	category := sfxpb.EventCategory_AGENT
+	events := make([]*sfxpb.Event, 0, len(metrics))
+	for _, metric := range metrics {
+		reasons := a.drops[metric]
+		keys := make([]string, 0, len(reasons))
+		for reason := range reasons {
+			keys = append(keys, reason)
+		}
+		sort.Strings(keys)
+		props := make([]*sfxpb.Property, 0, len(keys))
+		for _, reason := range keys {
+			v := int64(reasons[reason])
+			props = append(props, &sfxpb.Property{Key: reason, Value: &sfxpb.PropertyValue{IntValue: &v}})
+		}
+		events = append(events, &sfxpb.Event{
+			EventType:  dropEventType,
+			Category:   &category,
+			Dimensions: []*sfxpb.Dimension{{Key: "metric", Value: metric}},
+			Properties: props,
+			Timestamp:  ts,
+		})
+	}
+
+	a.drops = map[string]map[string]int{}
+	a.lastEmit = now
+	return events, numOmitted
+}
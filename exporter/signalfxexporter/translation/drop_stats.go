@@ -0,0 +1,95 @@
+// Copyright 2020, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package translation
+
+// DropStats holds the number of datapoints dropped or skipped by a conversion
+// per reason, e.g. to report per reason telemetry. Some of them, like
+// histogram buckets and nil elements, aren't counted in the number of dropped
+// datapoints returned by the conversion.
+type DropStats struct {
+	// NilMetrics is the number of nil resource metrics, instrumentation
+	// library metrics and metrics.
+	NilMetrics int
+	// NilDataPoints is the number of nil gauge and sum datapoints.
+	NilDataPoints int
+	// ZeroValue is the number of datapoints dropped by the
+	// DropZeroValueCounters option.
+	ZeroValue int
+	// Stale is the number of datapoints dropped by the MaxStaleness option.
+	Stale int
+	// HistogramMismatch is the number of histogram buckets dropped because
+	// the number of bucket counts doesn't match the number of bounds.
+	HistogramMismatch int
+	// NaNBounds is the number of histogram buckets dropped because of NaN
+	// bounds.
+	NaNBounds int
+	// UnsortedBounds is the number of histogram buckets dropped because
+	// bounds aren't strictly increasing.
+	UnsortedBounds int
+	// NegativeBucketCount is the number of histogram buckets dropped because
+	// of bucket counts above math.MaxInt64.
+	NegativeBucketCount int
+	// TooManyBuckets is the number of histogram buckets dropped by the
+	// MaxHistogramBuckets option.
+	TooManyBuckets int
+	// BatchLimit is the number of datapoints dropped by the
+	// MaxDatapointsPerBatch option.
+	BatchLimit int
+	// DeltaSeriesLimit is the number of datapoints of new delta series
+	// dropped by the DeltaToCumulativeMaxSeries option.
+	DeltaSeriesLimit int
+	// NoDataType is the number of metrics dropped because they have no data
+	// type, each counted as a single datapoint.
+	NoDataType int
+	// TypeConflict is the number of datapoints dropped by the
+	// DropConflictingMetricTypes option.
+	TypeConflict int
+}
+
+// add records count datapoints dropped for the given reason, it does nothing
+// on a nil DropStats.
+func (s *DropStats) add(reason string, count int) {
+	if s == nil {
+		return
+	}
+	switch reason {
+	case dropReasonNilMetric:
+		s.NilMetrics += count
+	case dropReasonNilDataPoint:
+		s.NilDataPoints += count
+	case dropReasonZeroValue:
+		s.ZeroValue += count
+	case dropReasonStale:
+		s.Stale += count
+	case dropReasonHistogramMismatch:
+		s.HistogramMismatch += count
+	case dropReasonNaNBounds:
+		s.NaNBounds += count
+	case dropReasonUnsortedBounds:
+		s.UnsortedBounds += count
+	case dropReasonNegativeBucketCount:
+		s.NegativeBucketCount += count
+	case dropReasonTooManyBuckets:
+		s.TooManyBuckets += count
+	case dropReasonBatchLimit:
+		s.BatchLimit += count
+	case dropReasonDeltaSeriesLimit:
+		s.DeltaSeriesLimit += count
+	case dropReasonNoDataType:
+		s.NoDataType += count
+	case dropReasonTypeConflict:
+		s.TypeConflict += count
+	}
+}
@@ -15,7 +15,13 @@
 package translation
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"math"
+	"regexp"
 	"sort"
 	"strings"
 
@@ -73,6 +79,18 @@ const (
 	// k8s.pod.network.io{direction="transmit"} -> pod_network_transmit_bytes_total{}
 	ActionSplitMetric Action = "split_metric"
 
+	// ActionSplitMetricByDimension splits a metric with Rule.MetricName into multiple metrics
+	// named after the values of the dimension specified in Rule.DimensionKey, removing the dimension.
+	// Datapoints without the dimension or with an empty value are kept as is.
+	// For example, having the following translation rule:
+	//   - action: split_metric_by_dimension
+	//     metric_name: connections
+	//     dimension_key: state
+	// The following translations will be performed:
+	// connections{state="active"} -> connections.active{}
+	// connections{state="idle"} -> connections.idle{}
+	ActionSplitMetricByDimension Action = "split_metric_by_dimension"
+
 	// ActionAggregateMetric aggregates metrics excluding dimensions set in tr.WithoutDimensions.
 	// This method is equivalent of "without" clause in Prometheus aggregation:
 	// https://prometheus.io/docs/prometheus/latest/querying/operators/#aggregation-operators
@@ -115,8 +133,112 @@ const (
 
 	// ActionDeltaMetric creates a new delta (cumulative) metric from an existing non-cumulative int or double
 	// metric. It takes mappings of names of the existing metrics to the names of the new, delta metrics to be
-	// created. All dimensions will be preserved.
+	// created. All dimensions will be preserved. A decrease of the existing metric is considered a reset, the
+	// delta is then the new value, preceded by a zero if Rule.ZeroOnReset is set so rate charts dip instead of spiking.
 	ActionDeltaMetric Action = "delta_metric"
+
+	// ActionInjectDimensionByMetric adds dimensions defined in Rule.AddDimensions to datapoints of metrics
+	// with names matching any of the regular expressions in Rule.MetricNamePatterns. Dimensions already
+	// present on a datapoint are kept unless Rule.OverwriteDimensions is set.
+	// For example, having the following translation rule:
+	// - action: inject_dimension_by_metric
+	//   metric_name_patterns:
+	//   - ^http\.
+	//   add_dimensions:
+	//     layer: http
+	// all metrics starting with "http." will get a "layer" dimension with value "http".
+	ActionInjectDimensionByMetric Action = "inject_dimension_by_metric"
+
+	// ActionMultiplyValue multiplies the values of datapoints of metrics with names matching any of the regular
+	// expressions in Rule.MetricNamePatterns by Rule.ScaleFactor. Int values stay int if the result is integral
+	// and are converted to double values otherwise.
+	// For example, having the following translation rule:
+	// - action: multiply_value
+	//   metric_name_patterns:
+	//   - ^memory\.
+	//   scale_factor: 0.001
+	// values of all metrics starting with "memory." will be divided by 1000.
+	ActionMultiplyValue Action = "multiply_value"
+
+	// ActionExtractDimensionFromName sets the dimension specified in Rule.DimensionKey to the first capture group
+	// of the first regular expression in Rule.MetricNamePatterns matching the metric name, overwriting the
+	// dimension if already present. If Rule.RemoveMatch is set, the part of the metric name matched by the
+	// regular expression is also removed from the name. Datapoints of metrics not matching any of the regular
+	// expressions, or with an empty capture, are kept as is.
+	// For example, having the following translation rule:
+	// - action: extract_dimension_from_name
+	//   metric_name_patterns:
+	//   - ^tenant_(\d+)_
+	//   dimension_key: tenant
+	//   remove_match: true
+	// The following translations will be performed:
+	// tenant_123_requests{} -> requests{tenant="123"}
+	ActionExtractDimensionFromName Action = "extract_dimension_from_name"
+
+	// ActionMapDimensionValues replaces the values of the dimension specified in Rule.DimensionKey using
+	// Rule.Mapping, e.g. to convert state values to numeric values. Values missing from the mapping are kept.
+	// For example, having the following translation rule:
+	// - action: map_dimension_values
+	//   dimension_key: state
+	//   mapping:
+	//     up: "1"
+	//     down: "0"
+	// The following translations will be performed:
+	// link.status{state="up"} -> link.status{state="1"}
+	// link.status{state="unknown"} -> link.status{state="unknown"}
+	ActionMapDimensionValues Action = "map_dimension_values"
+
+	// ActionCopyDimension copies dimensions to new keys using Rule.Mapping, keyed by the source dimension key
+	// with the target dimension key as value, e.g. to keep dimensions expected by existing content when
+	// adopting new dimension keys. Datapoints without the source dimension are kept as is. If the target
+	// dimension is already present, it is only overwritten if Rule.OverwriteDimensions is set.
+	// For example, having the following translation rule:
+	// - action: copy_dimension
+	//   mapping:
+	//     host.name: host
+	// The following translations will be performed:
+	// cpu.utilization{host.name="h1"} -> cpu.utilization{host.name="h1",host="h1"}
+	// cpu.utilization{} -> cpu.utilization{}
+	ActionCopyDimension Action = "copy_dimension"
+
+	// ActionDecodeDimensionValue decodes the value of the dimension specified in Rule.DimensionKey using
+	// Rule.Encoding, e.g. for IDs base64-encoded upstream. Values that fail to decode are kept as is and
+	// logged at debug level.
+	// For example, having the following translation rule:
+	// - action: decode_dimension_value
+	//   dimension_key: trace_id
+	//   encoding: base64
+	// The following translations will be performed:
+	// span.count{trace_id="YWJjMTIz"} -> span.count{trace_id="abc123"}
+	// span.count{trace_id="not base64!"} -> span.count{trace_id="not base64!"}
+	ActionDecodeDimensionValue Action = "decode_dimension_value"
+
+	// ActionConcatenateDimensions sets the dimension specified in Rule.DimensionKey to the values of the
+	// dimensions listed in Rule.SourceDimensionKeys joined with Rule.Separator, e.g. to build composite
+	// dimensions. Datapoints missing any of the source dimensions are kept as is, unless
+	// Rule.AllowMissingDimensions is set, in which case the present ones are joined. If the target dimension
+	// is already present, it is only overwritten if Rule.OverwriteDimensions is set.
+	// For example, having the following translation rule:
+	// - action: concatenate_dimensions
+	//   source_dimension_keys: [service, environment]
+	//   separator: "_"
+	//   dimension_key: service_env
+	// The following translations will be performed:
+	// requests{service="api",environment="prod"} -> requests{service="api",environment="prod",service_env="api_prod"}
+	// requests{service="api"} -> requests{service="api"}
+	ActionConcatenateDimensions Action = "concatenate_dimensions"
+)
+
+// DimensionValueEncoding is the enum to capture the encodings of dimension
+// values decoded by the "decode_dimension_value" translation rule.
+type DimensionValueEncoding string
+
+const (
+	// DimensionValueEncodingBase64 is the standard base64 encoding with
+	// padding.
+	DimensionValueEncodingBase64 DimensionValueEncoding = "base64"
+	// DimensionValueEncodingHex is the hexadecimal encoding.
+	DimensionValueEncodingHex DimensionValueEncoding = "hex"
 )
 
 type MetricOperator string
@@ -148,58 +270,100 @@ const (
 type Rule struct {
 	// Action specifies the translation action to be applied on metrics.
 	// This is a required field.
-	Action Action `mapstructure:"action"`
+	Action Action `mapstructure:"action" json:"action"`
 
 	// Mapping specifies key/value mapping that is used by rename_dimension_keys,
-	// rename_metrics, copy_metrics, and split_metric actions.
-	Mapping map[string]string `mapstructure:"mapping"`
+	// rename_metrics, copy_metrics, split_metric, map_dimension_values and
+	// copy_dimension actions.
+	Mapping map[string]string `mapstructure:"mapping" json:"mapping"`
 
 	// ScaleFactorsInt is used by multiply_int and divide_int action to scale
 	// integer metric values, key/value format: metric_name/scale_factor
-	ScaleFactorsInt map[string]int64 `mapstructure:"scale_factors_int"`
+	ScaleFactorsInt map[string]int64 `mapstructure:"scale_factors_int" json:"scale_factors_int"`
 
 	// ScaleFactorsInt is used by multiply_float action to scale
 	// float metric values, key/value format: metric_name/scale_factor
-	ScaleFactorsFloat map[string]float64 `mapstructure:"scale_factors_float"`
+	ScaleFactorsFloat map[string]float64 `mapstructure:"scale_factors_float" json:"scale_factors_float"`
 
 	// MetricName is used by "split_metric" translation rule to specify a name
 	// of a metric that will be split.
-	MetricName string `mapstructure:"metric_name"`
-	// DimensionKey is used by "split_metric" translation rule action to specify dimension key
-	// that will be used to translate the metric datapoints. Datapoints that don't have
-	// the specified dimension key will not be translated.
-	// DimensionKey is also used by "copy_metrics" for filterring.
-	DimensionKey string `mapstructure:"dimension_key"`
+	MetricName string `mapstructure:"metric_name" json:"metric_name"`
+	// DimensionKey is used by "split_metric" and "split_metric_by_dimension" translation rule actions
+	// to specify dimension key that will be used to translate the metric datapoints. Datapoints that
+	// don't have the specified dimension key will not be translated.
+	// DimensionKey is also used by "copy_metrics" for filterring, by "extract_dimension_from_name"
+	// to specify the dimension set from the metric name, by "map_dimension_values" and
+	// "decode_dimension_value" to specify the dimension whose values are mapped or decoded and by
+	// "concatenate_dimensions" to specify the dimension set to the concatenated values.
+	DimensionKey string `mapstructure:"dimension_key" json:"dimension_key"`
+
+	// Encoding is used by "decode_dimension_value" translation rule to specify the encoding of the
+	// dimension values.
+	Encoding DimensionValueEncoding `mapstructure:"encoding" json:"encoding"`
+
+	// SourceDimensionKeys is used by "concatenate_dimensions" translation rule to specify the dimensions
+	// whose values are concatenated, in order.
+	SourceDimensionKeys []string `mapstructure:"source_dimension_keys" json:"source_dimension_keys"`
+
+	// Separator is used by "concatenate_dimensions" translation rule to specify the string inserted
+	// between concatenated values.
+	Separator string `mapstructure:"separator" json:"separator"`
+
+	// AllowMissingDimensions is used by "concatenate_dimensions" translation rule to concatenate the values
+	// of the source dimensions present on datapoints missing some of them instead of skipping them.
+	AllowMissingDimensions bool `mapstructure:"allow_missing_dimensions" json:"allow_missing_dimensions"`
 
 	// DimensionValues is used by "copy_metrics" to filter out datapoints with dimensions values
 	// not matching values set in this field
-	DimensionValues map[string]bool `mapstructure:"dimension_values"`
+	DimensionValues map[string]bool `mapstructure:"dimension_values" json:"dimension_values"`
 
 	// TypesMapping is represents metric_name/metric_type key/value pairs,
 	// used by ActionConvertValues.
-	TypesMapping map[string]MetricValueType `mapstructure:"types_mapping"`
+	TypesMapping map[string]MetricValueType `mapstructure:"types_mapping" json:"types_mapping"`
 
 	// AggregationMethod specifies method used by "aggregate_metric" translation rule
-	AggregationMethod AggregationMethod `mapstructure:"aggregation_method"`
+	AggregationMethod AggregationMethod `mapstructure:"aggregation_method" json:"aggregation_method"`
 
 	// WithoutDimensions used by "aggregate_metric" translation rule to specify dimensions to be
 	// excluded by aggregation.
-	WithoutDimensions []string `mapstructure:"without_dimensions"`
+	WithoutDimensions []string `mapstructure:"without_dimensions" json:"without_dimensions"`
 
 	// AddDimensions used by "rename_metrics" translation rule to add dimensions that are necessary for
-	// existing SFx content for desired metric name
-	AddDimensions map[string]string `mapstructure:"add_dimensions"`
+	// existing SFx content for desired metric name. It is also used by "inject_dimension_by_metric"
+	// translation rule to specify the dimensions to inject.
+	AddDimensions map[string]string `mapstructure:"add_dimensions" json:"add_dimensions"`
+
+	// MetricNamePatterns is used by "inject_dimension_by_metric", "multiply_value" and
+	// "extract_dimension_from_name" translation rules to specify regular expressions matched against
+	// metric names.
+	MetricNamePatterns []string `mapstructure:"metric_name_patterns" json:"metric_name_patterns"`
+
+	// ScaleFactor is used by "multiply_value" translation rule to specify the factor values are multiplied by.
+	ScaleFactor float64 `mapstructure:"scale_factor" json:"scale_factor"`
+
+	// OverwriteDimensions is used by "inject_dimension_by_metric", "copy_dimension" and
+	// "concatenate_dimensions" translation rules to overwrite the value of dimensions already present on datapoints instead of keeping it.
+	OverwriteDimensions bool `mapstructure:"overwrite_dimensions" json:"overwrite_dimensions"`
+
+	// ZeroOnReset is used by "delta_metric" translation rule to emit an extra zero delta datapoint one
+	// timestamp unit before counter resets, in addition to the value after the reset. The zero is skipped if
+	// the previous point is too close to leave room for it.
+	ZeroOnReset bool `mapstructure:"zero_on_reset" json:"zero_on_reset"`
+
+	// RemoveMatch is used by "extract_dimension_from_name" translation rule to remove the part of the metric
+	// name matched by the regular expression from the name.
+	RemoveMatch bool `mapstructure:"remove_match" json:"remove_match"`
 
 	// CopyDimensions used by "rename_metrics" translation rule to copy dimensions that are necessary for
 	// existing SFx content for desired metric name.  This will duplicate the dimension value and isn't a rename.
-	CopyDimensions map[string]string `mapstructure:"copy_dimensions"`
+	CopyDimensions map[string]string `mapstructure:"copy_dimensions" json:"copy_dimensions"`
 
 	// MetricNames is used by "rename_dimension_keys" and "drop_metrics" translation rules.
-	MetricNames map[string]bool `mapstructure:"metric_names"`
+	MetricNames map[string]bool `mapstructure:"metric_names" json:"metric_names"`
 
-	Operand1Metric string         `mapstructure:"operand1_metric"`
-	Operand2Metric string         `mapstructure:"operand2_metric"`
-	Operator       MetricOperator `mapstructure:"operator"`
+	Operand1Metric string         `mapstructure:"operand1_metric" json:"operand1_metric"`
+	Operand2Metric string         `mapstructure:"operand2_metric" json:"operand2_metric"`
+	Operator       MetricOperator `mapstructure:"operator" json:"operator"`
 }
 
 type MetricTranslator struct {
@@ -208,6 +372,9 @@ type MetricTranslator struct {
 	// Additional map to be used only for dimension renaming in metadata
 	dimensionsMap map[string]string
 
+	// Compiled Rule.MetricNamePatterns, indexed the same way as rules
+	metricNamePatterns [][]*regexp.Regexp
+
 	deltaTranslator *deltaTranslator
 }
 
@@ -218,12 +385,39 @@ func NewMetricTranslator(rules []Rule, ttl int64) (*MetricTranslator, error) {
 	}
 
 	return &MetricTranslator{
-		rules:           rules,
-		dimensionsMap:   createDimensionsMap(rules),
-		deltaTranslator: newDeltaTranslator(ttl),
+		rules:              rules,
+		dimensionsMap:      createDimensionsMap(rules),
+		metricNamePatterns: compileMetricNamePatterns(rules),
+		deltaTranslator:    newDeltaTranslator(ttl),
 	}, nil
 }
 
+// defaultDeltaTranslationTTL is the TTL in seconds of the delta translator of
+// MetricTranslators created by NewMetricTranslatorFromJSON, the same as the
+// default delta_translation_ttl of the exporter.
+const defaultDeltaTranslationTTL = 3600
+
+// NewMetricTranslatorFromJSON creates a MetricTranslator from a JSON array of
+// translation rules using the same field names as the YAML configuration,
+// e.g. [{"action": "rename_metrics", "mapping": {"old": "new"}}]. Unknown
+// fields are rejected and the rules are validated like by NewMetricTranslator.
+func NewMetricTranslatorFromJSON(data []byte) (*MetricTranslator, error) {
+	var rules []Rule
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("failed to parse translation rules: %v", err)
+	}
+	return NewMetricTranslator(rules, defaultDeltaTranslationTTL)
+}
+
+// ValidateRules checks that the translation rules of the MetricTranslator are
+// valid. Rules are validated by NewMetricTranslator, this allows to check them
+// again if they were changed afterwards.
+func (mp *MetricTranslator) ValidateRules() error {
+	return validateTranslationRules(mp.rules)
+}
+
 func validateTranslationRules(rules []Rule) error {
 	var renameDimensionKeysFound bool
 	for _, tr := range rules {
@@ -281,6 +475,10 @@ func validateTranslationRules(rules []Rule) error {
 					"fields \"metric_name\", \"dimension_key\", and \"mapping\" are required for %q translation rule",
 					tr.Action)
 			}
+		case ActionSplitMetricByDimension:
+			if tr.MetricName == "" || tr.DimensionKey == "" {
+				return fmt.Errorf(`fields "metric_name" and "dimension_key" are required for %q translation rule`, tr.Action)
+			}
 		case ActionConvertValues:
 			if tr.TypesMapping == nil {
 				return fmt.Errorf("field \"types_mapping\" are required for %q translation rule", tr.Action)
@@ -317,6 +515,53 @@ func validateTranslationRules(rules []Rule) error {
 			if len(tr.Mapping) == 0 {
 				return fmt.Errorf(`field "mapping" is required for %q translation rule`, tr.Action)
 			}
+		case ActionInjectDimensionByMetric:
+			if len(tr.MetricNamePatterns) == 0 || len(tr.AddDimensions) == 0 {
+				return fmt.Errorf(`fields "metric_name_patterns" and "add_dimensions" are required for %q translation rule`, tr.Action)
+			}
+			if err := validateMetricNamePatterns(tr); err != nil {
+				return err
+			}
+		case ActionMultiplyValue:
+			if len(tr.MetricNamePatterns) == 0 || tr.ScaleFactor == 0 {
+				return fmt.Errorf(`fields "metric_name_patterns" and "scale_factor" are required for %q translation rule`, tr.Action)
+			}
+			if err := validateMetricNamePatterns(tr); err != nil {
+				return err
+			}
+		case ActionExtractDimensionFromName:
+			if len(tr.MetricNamePatterns) == 0 || tr.DimensionKey == "" {
+				return fmt.Errorf(`fields "metric_name_patterns" and "dimension_key" are required for %q translation rule`, tr.Action)
+			}
+			if err := validateMetricNamePatterns(tr); err != nil {
+				return err
+			}
+			for _, p := range tr.MetricNamePatterns {
+				if regexp.MustCompile(p).NumSubexp() == 0 {
+					return fmt.Errorf("\"metric_name_patterns\" value %q for %q translation rule must have a capture group", p, tr.Action)
+				}
+			}
+		case ActionMapDimensionValues:
+			if tr.DimensionKey == "" || len(tr.Mapping) == 0 {
+				return fmt.Errorf(`fields "dimension_key" and "mapping" are required for %q translation rule`, tr.Action)
+			}
+		case ActionCopyDimension:
+			if len(tr.Mapping) == 0 {
+				return fmt.Errorf("field \"mapping\" is required for %q translation rule", tr.Action)
+			}
+		case ActionDecodeDimensionValue:
+			if tr.DimensionKey == "" || tr.Encoding == "" {
+				return fmt.Errorf(`fields "dimension_key" and "encoding" are required for %q translation rule`, tr.Action)
+			}
+			switch tr.Encoding {
+			case DimensionValueEncodingBase64, DimensionValueEncodingHex:
+			default:
+				return fmt.Errorf("invalid \"encoding\" value %q for %q translation rule", tr.Encoding, tr.Action)
+			}
+		case ActionConcatenateDimensions:
+			if len(tr.SourceDimensionKeys) == 0 || tr.DimensionKey == "" {
+				return fmt.Errorf(`fields "source_dimension_keys" and "dimension_key" are required for %q translation rule`, tr.Action)
+			}
 		default:
 			return fmt.Errorf("unknown \"action\" value: %q", tr.Action)
 		}
@@ -324,6 +569,27 @@ func validateTranslationRules(rules []Rule) error {
 	return nil
 }
 
+func validateMetricNamePatterns(tr Rule) error {
+	for _, p := range tr.MetricNamePatterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return fmt.Errorf("invalid \"metric_name_patterns\" value %q for %q translation rule: %v", p, tr.Action, err)
+		}
+	}
+	return nil
+}
+
+// compileMetricNamePatterns compiles the metric name patterns of all rules,
+// the patterns must be validated beforehand.
+func compileMetricNamePatterns(rules []Rule) [][]*regexp.Regexp {
+	compiled := make([][]*regexp.Regexp, len(rules))
+	for i, tr := range rules {
+		for _, p := range tr.MetricNamePatterns {
+			compiled[i] = append(compiled[i], regexp.MustCompile(p))
+		}
+	}
+	return compiled
+}
+
 // createDimensionsMap creates an additional map for dimensions
 // from ActionRenameDimensionKeys actions in rules.
 func createDimensionsMap(rules []Rule) map[string]string {
@@ -337,11 +603,44 @@ func createDimensionsMap(rules []Rule) map[string]string {
 }
 
 // TranslateDataPoints transforms datapoints to a format compatible with signalfx backend
-// sfxDataPoints represents one metric converted to signalfx protobuf datapoints
+// sfxDataPoints represents one metric converted to signalfx protobuf datapoints.
+// If a rule panics, the panic is logged and sfxDataPoints is returned without
+// applying the remaining rules. Rules applied before the panicking one may
+// have updated the datapoints in place.
 func (mp *MetricTranslator) TranslateDataPoints(logger *zap.Logger, sfxDataPoints []*sfxpb.DataPoint) []*sfxpb.DataPoint {
+	translated, _ := mp.translateDataPoints(logger, sfxDataPoints)
+	return translated
+}
+
+// translateDataPoints translates the datapoints like TranslateDataPoints,
+// also returning false if a rule panicked.
+func (mp *MetricTranslator) translateDataPoints(logger *zap.Logger, sfxDataPoints []*sfxpb.DataPoint) (translated []*sfxpb.DataPoint, ok bool) {
+	ruleIndex := 0
+	defer func() {
+		if r := recover(); r != nil {
+			metric := ""
+			if len(sfxDataPoints) > 0 {
+				metric = sfxDataPoints[0].Metric
+			}
+			logger.Error("translation rule panicked, skipping translation of datapoints",
+				zap.Int("rule_index", ruleIndex),
+				zap.String("action", string(mp.rules[ruleIndex].Action)),
+				zap.String("metric", metric),
+				zap.Any("panic", r),
+				zap.Stack("stack"))
+			translated, ok = sfxDataPoints, false
+		}
+	}()
+	return mp.applyRules(logger, sfxDataPoints, &ruleIndex), true
+}
+
+// applyRules applies the translation rules to the datapoints, setting
+// ruleIndex to the index of the rule being applied.
+func (mp *MetricTranslator) applyRules(logger *zap.Logger, sfxDataPoints []*sfxpb.DataPoint, ruleIndex *int) []*sfxpb.DataPoint {
 	processedDataPoints := sfxDataPoints
 
-	for _, tr := range mp.rules {
+	for i, tr := range mp.rules {
+		*ruleIndex = i
 		switch tr.Action {
 		case ActionRenameDimensionKeys:
 			for _, dp := range processedDataPoints {
@@ -420,6 +719,12 @@ func (mp *MetricTranslator) TranslateDataPoints(logger *zap.Logger, sfxDataPoint
 					splitMetric(dp, tr.DimensionKey, tr.Mapping)
 				}
 			}
+		case ActionSplitMetricByDimension:
+			for _, dp := range processedDataPoints {
+				if tr.MetricName == dp.Metric {
+					splitMetricByDimension(dp, tr.DimensionKey)
+				}
+			}
 		case ActionConvertValues:
 			for _, dp := range processedDataPoints {
 				if newType, ok := tr.TypesMapping[dp.Metric]; ok {
@@ -473,12 +778,214 @@ func (mp *MetricTranslator) TranslateDataPoints(logger *zap.Logger, sfxDataPoint
 
 		case ActionDeltaMetric:
 			processedDataPoints = mp.deltaTranslator.translate(processedDataPoints, tr)
+
+		case ActionInjectDimensionByMetric:
+			for _, dp := range processedDataPoints {
+				if matchesAnyPattern(dp.Metric, mp.metricNamePatterns[i]) {
+					injectDimensions(dp, tr.AddDimensions, tr.OverwriteDimensions)
+				}
+			}
+
+		case ActionMultiplyValue:
+			for _, dp := range processedDataPoints {
+				if matchesAnyPattern(dp.Metric, mp.metricNamePatterns[i]) {
+					multiplyValue(dp, tr.ScaleFactor)
+				}
+			}
+
+		case ActionExtractDimensionFromName:
+			for _, dp := range processedDataPoints {
+				extractDimensionFromName(dp, mp.metricNamePatterns[i], tr.DimensionKey, tr.RemoveMatch)
+			}
+
+		case ActionMapDimensionValues:
+			for _, dp := range processedDataPoints {
+				mapDimensionValue(dp, tr.DimensionKey, tr.Mapping)
+			}
+
+		case ActionCopyDimension:
+			for _, dp := range processedDataPoints {
+				copyDimensions(dp, tr.Mapping, tr.OverwriteDimensions)
+			}
+
+		case ActionDecodeDimensionValue:
+			for _, dp := range processedDataPoints {
+				decodeDimensionValue(logger, dp, tr.DimensionKey, tr.Encoding)
+			}
+
+		case ActionConcatenateDimensions:
+			for _, dp := range processedDataPoints {
+				concatenateDimensions(dp, tr)
+			}
 		}
 	}
 
 	return processedDataPoints
 }
 
+func matchesAnyPattern(metricName string, patterns []*regexp.Regexp) bool {
+	for _, p := range patterns {
+		if p.MatchString(metricName) {
+			return true
+		}
+	}
+	return false
+}
+
+// multiplyValue multiplies the value of the datapoint by factor, converting
+// int values to double values if the result isn't integral.
+func multiplyValue(dp *sfxpb.DataPoint, factor float64) {
+	switch {
+	case dp.Value.IntValue != nil:
+		v := float64(*dp.Value.IntValue) * factor
+		if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+			intVal := int64(v)
+			dp.Value.IntValue = &intVal
+			return
+		}
+		dp.Value.IntValue = nil
+		dp.Value.DoubleValue = &v
+	case dp.Value.DoubleValue != nil:
+		v := *dp.Value.DoubleValue * factor
+		dp.Value.DoubleValue = &v
+	}
+}
+
+// injectDimensions adds the dimensions to the datapoint. Dimensions already
+// present are only updated if overwrite is set.
+func injectDimensions(dp *sfxpb.DataPoint, dims map[string]string, overwrite bool) {
+	for k, v := range dims {
+		setDimension(dp, k, v, overwrite)
+	}
+}
+
+// setDimension adds the dimension to the datapoint. If the dimension is
+// already present it is only updated if overwrite is set.
+func setDimension(dp *sfxpb.DataPoint, key string, value string, overwrite bool) {
+	existing := -1
+	for j, d := range dp.Dimensions {
+		if d.Key == key {
+			existing = j
+			break
+		}
+	}
+	switch {
+	case existing < 0:
+		dp.Dimensions = append(dp.Dimensions, &sfxpb.Dimension{Key: key, Value: value})
+	case overwrite:
+		// Dimensions can be shared between datapoints, replace instead of
+		// updating in place.
+		dp.Dimensions[existing] = &sfxpb.Dimension{Key: key, Value: value}
+	}
+}
+
+// mapDimensionValue replaces the value of the dimension of the datapoint
+// according to mapping, values missing from the mapping are kept.
+func mapDimensionValue(dp *sfxpb.DataPoint, dimensionKey string, mapping map[string]string) {
+	for i, d := range dp.Dimensions {
+		if d.Key != dimensionKey {
+			continue
+		}
+		if v, ok := mapping[d.Value]; ok {
+			// Dimensions can be shared between datapoints, replace instead of
+			// updating in place.
+			dp.Dimensions[i] = &sfxpb.Dimension{Key: dimensionKey, Value: v}
+		}
+		return
+	}
+}
+
+// copyDimensions copies the dimensions of the datapoint whose key is in the
+// mapping to the mapped key. Existing target dimensions are only updated if
+// overwrite is set.
+func copyDimensions(dp *sfxpb.DataPoint, mapping map[string]string, overwrite bool) {
+	// Only copy dimensions present before the rule is applied.
+	dims := dp.Dimensions
+	for _, d := range dims {
+		if target, ok := mapping[d.Key]; ok {
+			setDimension(dp, target, d.Value, overwrite)
+		}
+	}
+}
+
+// decodeDimensionValue decodes the value of the dimension with the given key,
+// keeping the value as is if it fails to decode.
+func decodeDimensionValue(logger *zap.Logger, dp *sfxpb.DataPoint, dimensionKey string, encoding DimensionValueEncoding) {
+	for i, d := range dp.Dimensions {
+		if d.Key != dimensionKey {
+			continue
+		}
+		var decoded []byte
+		var err error
+		switch encoding {
+		case DimensionValueEncodingBase64:
+			decoded, err = base64.StdEncoding.DecodeString(d.Value)
+		case DimensionValueEncodingHex:
+			decoded, err = hex.DecodeString(d.Value)
+		}
+		if err != nil {
+			logger.Debug("failed to decode dimension value, keeping it as is",
+				zap.String("metric", dp.Metric),
+				zap.String("dimension", dimensionKey),
+				zap.String("encoding", string(encoding)),
+				zap.Error(err))
+			return
+		}
+		// Dimensions can be shared between datapoints, replace instead of
+		// updating in place.
+		dp.Dimensions[i] = &sfxpb.Dimension{Key: dimensionKey, Value: string(decoded)}
+		return
+	}
+}
+
+// concatenateDimensions sets the Rule.DimensionKey dimension of the datapoint
+// to the joined values of the Rule.SourceDimensionKeys dimensions.
+func concatenateDimensions(dp *sfxpb.DataPoint, tr Rule) {
+	values := make([]string, 0, len(tr.SourceDimensionKeys))
+	for _, key := range tr.SourceDimensionKeys {
+		found := false
+		for _, d := range dp.Dimensions {
+			if d.Key == key {
+				values = append(values, d.Value)
+				found = true
+				break
+			}
+		}
+		if !found && !tr.AllowMissingDimensions {
+			return
+		}
+	}
+	if len(values) == 0 {
+		return
+	}
+	setDimension(dp, tr.DimensionKey, strings.Join(values, tr.Separator), tr.OverwriteDimensions)
+}
+
+// extractDimensionFromName sets the dimension to the first capture group of
+// the first pattern matching the name of the datapoint, removing the matched
+// part of the name if removeMatch is set. The name is kept if removing the
+// match would leave it empty.
+func extractDimensionFromName(dp *sfxpb.DataPoint, patterns []*regexp.Regexp, dimensionKey string, removeMatch bool) {
+	for _, p := range patterns {
+		loc := p.FindStringSubmatchIndex(dp.Metric)
+		if loc == nil {
+			continue
+		}
+		// The capture group is unset if it didn't participate in the match.
+		if loc[2] < 0 || loc[2] == loc[3] {
+			return
+		}
+		value := dp.Metric[loc[2]:loc[3]]
+		if removeMatch {
+			if name := dp.Metric[:loc[0]] + dp.Metric[loc[1]:]; name != "" {
+				dp.Metric = name
+			}
+		}
+		setDimension(dp, dimensionKey, value, true)
+		return
+	}
+}
+
 func calcNewMetricInputPairs(processedDataPoints []*sfxpb.DataPoint, tr Rule) [][2]*sfxpb.DataPoint {
 	var operand1Pts, operand2Pts []*sfxpb.DataPoint
 	for _, dp := range processedDataPoints {
@@ -738,6 +1245,25 @@ func splitMetric(dp *sfxpb.DataPoint, dimensionKey string, mapping map[string]st
 	dp.Dimensions = dimensions
 }
 
+// splitMetricByDimension appends the value of the dimension to the name of the
+// datapoint and removes the dimension. Datapoints without the dimension or with
+// an empty value are kept as is.
+func splitMetricByDimension(dp *sfxpb.DataPoint, dimensionKey string) {
+	for i, d := range dp.Dimensions {
+		if d.Key != dimensionKey {
+			continue
+		}
+		if d.Value == "" {
+			return
+		}
+		dp.Metric = dp.Metric + "." + d.Value
+		dimensions := make([]*sfxpb.Dimension, 0, len(dp.Dimensions)-1)
+		dimensions = append(dimensions, dp.Dimensions[:i]...)
+		dp.Dimensions = append(dimensions, dp.Dimensions[i+1:]...)
+		return
+	}
+}
+
 func convertMetricValue(logger *zap.Logger, dp *sfxpb.DataPoint, newType MetricValueType) {
 	switch newType {
 	case MetricValueTypeInt:
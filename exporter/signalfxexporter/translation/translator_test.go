@@ -354,6 +354,30 @@ func TestNewMetricTranslator(t *testing.T) {
 			wantError: "fields \"metric_name\", \"dimension_key\", and \"mapping\" are required " +
 				"for \"split_metric\" translation rule",
 		},
+		{
+			name: "split_metric_by_dimension_valid",
+			trs: []Rule{
+				{
+					Action:       ActionSplitMetricByDimension,
+					MetricName:   "metric1",
+					DimensionKey: "dim1",
+				},
+			},
+			wantDimensionsMap: nil,
+			wantError:         "",
+		},
+		{
+			name: "split_metric_by_dimension_invalid",
+			trs: []Rule{
+				{
+					Action:     ActionSplitMetricByDimension,
+					MetricName: "metric1",
+				},
+			},
+			wantDimensionsMap: nil,
+			wantError: `fields "metric_name" and "dimension_key" are required ` +
+				`for "split_metric_by_dimension" translation rule`,
+		},
 		{
 			name: "convert_values_valid",
 			trs: []Rule{
@@ -529,6 +553,224 @@ func TestNewMetricTranslator(t *testing.T) {
 			},
 			wantError: `field "mapping" is required for "delta_metric" translation rule`,
 		},
+		{
+			name: "inject_dimension_by_metric_valid",
+			trs: []Rule{
+				{
+					Action:             ActionInjectDimensionByMetric,
+					MetricNamePatterns: []string{"^http\\."},
+					AddDimensions:      map[string]string{"layer": "http"},
+				},
+			},
+			wantDimensionsMap: nil,
+			wantError:         "",
+		},
+		{
+			name: "inject_dimension_by_metric_invalid_missing_patterns",
+			trs: []Rule{
+				{
+					Action:        ActionInjectDimensionByMetric,
+					AddDimensions: map[string]string{"layer": "http"},
+				},
+			},
+			wantError: `fields "metric_name_patterns" and "add_dimensions" are required for ` +
+				`"inject_dimension_by_metric" translation rule`,
+		},
+		{
+			name: "inject_dimension_by_metric_invalid_missing_dimensions",
+			trs: []Rule{
+				{
+					Action:             ActionInjectDimensionByMetric,
+					MetricNamePatterns: []string{"^http\\."},
+				},
+			},
+			wantError: `fields "metric_name_patterns" and "add_dimensions" are required for ` +
+				`"inject_dimension_by_metric" translation rule`,
+		},
+		{
+			name: "inject_dimension_by_metric_invalid_pattern",
+			trs: []Rule{
+				{
+					Action:             ActionInjectDimensionByMetric,
+					MetricNamePatterns: []string{"http("},
+					AddDimensions:      map[string]string{"layer": "http"},
+				},
+			},
+			wantError: `invalid "metric_name_patterns" value "http(" for "inject_dimension_by_metric" translation rule: ` +
+				"error parsing regexp: missing closing ): `http(`",
+		},
+		{
+			name: "multiply_value_valid",
+			trs: []Rule{
+				{
+					Action:             ActionMultiplyValue,
+					MetricNamePatterns: []string{"^memory\\."},
+					ScaleFactor:        0.001,
+				},
+			},
+			wantDimensionsMap: nil,
+			wantError:         "",
+		},
+		{
+			name: "multiply_value_invalid_missing_factor",
+			trs: []Rule{
+				{
+					Action:             ActionMultiplyValue,
+					MetricNamePatterns: []string{"^memory\\."},
+				},
+			},
+			wantError: `fields "metric_name_patterns" and "scale_factor" are required for ` +
+				`"multiply_value" translation rule`,
+		},
+		{
+			name: "multiply_value_invalid_pattern",
+			trs: []Rule{
+				{
+					Action:             ActionMultiplyValue,
+					MetricNamePatterns: []string{"memory("},
+					ScaleFactor:        0.001,
+				},
+			},
+			wantError: `invalid "metric_name_patterns" value "memory(" for "multiply_value" translation rule: ` +
+				"error parsing regexp: missing closing ): `memory(`",
+		},
+		{
+			name: "extract_dimension_from_name_valid",
+			trs: []Rule{
+				{
+					Action:             ActionExtractDimensionFromName,
+					MetricNamePatterns: []string{"^tenant_(\\d+)_"},
+					DimensionKey:       "tenant",
+				},
+			},
+			wantDimensionsMap: nil,
+			wantError:         "",
+		},
+		{
+			name: "extract_dimension_from_name_invalid_missing_key",
+			trs: []Rule{
+				{
+					Action:             ActionExtractDimensionFromName,
+					MetricNamePatterns: []string{"^tenant_(\\d+)_"},
+				},
+			},
+			wantError: `fields "metric_name_patterns" and "dimension_key" are required for ` +
+				`"extract_dimension_from_name" translation rule`,
+		},
+		{
+			name: "extract_dimension_from_name_invalid_no_capture_group",
+			trs: []Rule{
+				{
+					Action:             ActionExtractDimensionFromName,
+					MetricNamePatterns: []string{"^tenant_\\d+_"},
+					DimensionKey:       "tenant",
+				},
+			},
+			wantError: `"metric_name_patterns" value "^tenant_\\d+_" for "extract_dimension_from_name" ` +
+				`translation rule must have a capture group`,
+		},
+		{
+			name: "map_dimension_values_valid",
+			trs: []Rule{
+				{
+					Action:       ActionMapDimensionValues,
+					DimensionKey: "state",
+					Mapping: map[string]string{
+						"up": "1",
+					},
+				},
+			},
+			wantDimensionsMap: nil,
+			wantError:         "",
+		},
+		{
+			name: "map_dimension_values_invalid_missing_mapping",
+			trs: []Rule{
+				{
+					Action:       ActionMapDimensionValues,
+					DimensionKey: "state",
+				},
+			},
+			wantError: `fields "dimension_key" and "mapping" are required for "map_dimension_values" translation rule`,
+		},
+		{
+			name: "copy_dimension_valid",
+			trs: []Rule{
+				{
+					Action: ActionCopyDimension,
+					Mapping: map[string]string{
+						"host.name": "host",
+					},
+				},
+			},
+			wantDimensionsMap: nil,
+			wantError:         "",
+		},
+		{
+			name: "copy_dimension_invalid_missing_mapping",
+			trs: []Rule{
+				{
+					Action: ActionCopyDimension,
+				},
+			},
+			wantError: `field "mapping" is required for "copy_dimension" translation rule`,
+		},
+		{
+			name: "decode_dimension_value_valid",
+			trs: []Rule{
+				{
+					Action:       ActionDecodeDimensionValue,
+					DimensionKey: "trace_id",
+					Encoding:     DimensionValueEncodingHex,
+				},
+			},
+			wantDimensionsMap: nil,
+			wantError:         "",
+		},
+		{
+			name: "decode_dimension_value_invalid_missing_encoding",
+			trs: []Rule{
+				{
+					Action:       ActionDecodeDimensionValue,
+					DimensionKey: "trace_id",
+				},
+			},
+			wantError: `fields "dimension_key" and "encoding" are required for "decode_dimension_value" translation rule`,
+		},
+		{
+			name: "decode_dimension_value_invalid_encoding",
+			trs: []Rule{
+				{
+					Action:       ActionDecodeDimensionValue,
+					DimensionKey: "trace_id",
+					Encoding:     "base32",
+				},
+			},
+			wantError: `invalid "encoding" value "base32" for "decode_dimension_value" translation rule`,
+		},
+		{
+			name: "concatenate_dimensions_valid",
+			trs: []Rule{
+				{
+					Action:              ActionConcatenateDimensions,
+					SourceDimensionKeys: []string{"service", "environment"},
+					Separator:           "_",
+					DimensionKey:        "service_env",
+				},
+			},
+			wantDimensionsMap: nil,
+			wantError:         "",
+		},
+		{
+			name: "concatenate_dimensions_invalid_missing_source_dimension_keys",
+			trs: []Rule{
+				{
+					Action:       ActionConcatenateDimensions,
+					DimensionKey: "service_env",
+				},
+			},
+			wantError: `fields "source_dimension_keys" and "dimension_key" are required for "concatenate_dimensions" translation rule`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -548,6 +790,77 @@ func TestNewMetricTranslator(t *testing.T) {
 	}
 }
 
+func TestNewMetricTranslatorFromJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		json      string
+		wantRules []Rule
+		wantError string
+	}{
+		{
+			name: "valid",
+			json: `[
+				{"action": "rename_metrics", "mapping": {"old_metric": "new_metric"}},
+				{"action": "drop_metrics", "metric_names": {"metric": true}},
+				{"action": "multiply_value", "metric_name_patterns": ["^memory\\."], "scale_factor": 0.001}
+			]`,
+			wantRules: []Rule{
+				{
+					Action:  ActionRenameMetrics,
+					Mapping: map[string]string{"old_metric": "new_metric"},
+				},
+				{
+					Action:      ActionDropMetrics,
+					MetricNames: map[string]bool{"metric": true},
+				},
+				{
+					Action:             ActionMultiplyValue,
+					MetricNamePatterns: []string{"^memory\\."},
+					ScaleFactor:        0.001,
+				},
+			},
+		},
+		{
+			name:      "empty",
+			json:      `[]`,
+			wantRules: []Rule{},
+		},
+		{
+			name:      "invalid_json",
+			json:      `[{"action": "rename_metrics"`,
+			wantError: "failed to parse translation rules: unexpected EOF",
+		},
+		{
+			name:      "not_an_array",
+			json:      `{"action": "rename_metrics"}`,
+			wantError: "failed to parse translation rules: json: cannot unmarshal object into Go value of type []translation.Rule",
+		},
+		{
+			name:      "unknown_field",
+			json:      `[{"action": "rename_metrics", "mappings": {"old_metric": "new_metric"}}]`,
+			wantError: `failed to parse translation rules: json: unknown field "mappings"`,
+		},
+		{
+			name:      "invalid_rule",
+			json:      `[{"action": "rename_metrics"}]`,
+			wantError: `field "mapping" is required for "rename_metrics" translation rule`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt, err := NewMetricTranslatorFromJSON([]byte(tt.json))
+			if tt.wantError == "" {
+				require.NoError(t, err)
+				require.NotNil(t, mt)
+				assert.Equal(t, tt.wantRules, mt.rules)
+			} else {
+				require.EqualError(t, err, tt.wantError)
+				require.Nil(t, mt)
+			}
+		})
+	}
+}
+
 var msec = time.Now().Unix() * 1e3
 var gaugeType = sfxpb.MetricType_GAUGE
 
@@ -1871,76 +2184,1082 @@ func TestTranslateDataPoints(t *testing.T) {
 				},
 			},
 		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			mt, err := NewMetricTranslator(tt.trs, 1)
-			require.NoError(t, err)
-			assert.NotEqualValues(t, tt.want, tt.dps)
-			got := mt.TranslateDataPoints(zap.NewNop(), tt.dps)
-			assertEqualPoints(t, got, tt.want, tt.trs[0].Action)
-		})
-	}
-}
-
-func assertEqualPoints(t *testing.T, got []*sfxpb.DataPoint, want []*sfxpb.DataPoint, action Action) {
-	// Sort metrics to handle not deterministic order from aggregation
-	if action == ActionAggregateMetric {
-		sort.Sort(byContent(want))
-		sort.Sort(byContent(got))
-	}
-
-	if action == ActionRenameMetrics {
-		for _, dp := range want {
-			sort.Sort(byDimensions(dp.Dimensions))
-		}
-
-		for _, dp := range got {
-			sort.Sort(byDimensions(dp.Dimensions))
-		}
-	}
-
-	// Handle float values separately
-	for i, dp := range got {
-		if dp.GetValue().DoubleValue != nil {
-			assert.InDelta(t, *want[i].GetValue().DoubleValue, *dp.GetValue().DoubleValue, 0.00000001)
-			*dp.GetValue().DoubleValue = *want[i].GetValue().DoubleValue
-		}
-	}
-
-	assert.EqualValues(t, want, got)
-}
-
-func TestTestTranslateDimension(t *testing.T) {
-	mt, err := NewMetricTranslator([]Rule{
 		{
-			Action: ActionRenameDimensionKeys,
-			Mapping: map[string]string{
-				"old_dimension": "new_dimension",
-				"old.dimension": "new.dimension",
+			name: "inject_dimension_by_metric",
+			trs: []Rule{
+				{
+					Action:             ActionInjectDimensionByMetric,
+					MetricNamePatterns: []string{"^http\\.", "^grpc\\.requests$"},
+					AddDimensions:      map[string]string{"layer": "network"},
+				},
 			},
-		},
-	}, 1)
-	require.NoError(t, err)
-
-	assert.Equal(t, "new_dimension", mt.TranslateDimension("old_dimension"))
-	assert.Equal(t, "new.dimension", mt.TranslateDimension("old.dimension"))
-	assert.Equal(t, "another_dimension", mt.TranslateDimension("another_dimension"))
-
-	// Test no rename_dimension_keys translation rule
-	mt, err = NewMetricTranslator([]Rule{}, 1)
-	require.NoError(t, err)
-	assert.Equal(t, "old_dimension", mt.TranslateDimension("old_dimension"))
-}
-
-func TestNewCalculateNewMetricErrors(t *testing.T) {
-	tests := []struct {
-		name    string
-		metric1 string
-		val1    *int64
-		metric2 string
-		val2    *int64
-		wantErr string
+			dps: []*sfxpb.DataPoint{
+				{
+					Metric:     "http.requests",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "host",
+							Value: "a",
+						},
+					},
+				},
+				{
+					Metric:     "grpc.requests",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+				},
+				{
+					Metric:     "grpc.requests.total",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+				},
+				{
+					Metric:     "db.http.requests",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+				},
+			},
+			want: []*sfxpb.DataPoint{
+				{
+					Metric:     "http.requests",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "host",
+							Value: "a",
+						},
+						{
+							Key:   "layer",
+							Value: "network",
+						},
+					},
+				},
+				{
+					Metric:     "grpc.requests",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "layer",
+							Value: "network",
+						},
+					},
+				},
+				{
+					Metric:     "grpc.requests.total",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+				},
+				{
+					Metric:     "db.http.requests",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+				},
+			},
+		},
+		{
+			name: "inject_dimension_by_metric_keep_existing",
+			trs: []Rule{
+				{
+					Action:             ActionInjectDimensionByMetric,
+					MetricNamePatterns: []string{"^http\\.", "^grpc\\.requests$"},
+					AddDimensions:      map[string]string{"layer": "network"},
+				},
+			},
+			dps: []*sfxpb.DataPoint{
+				{
+					Metric:     "http.requests",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "layer",
+							Value: "app",
+						},
+					},
+				},
+				{
+					Metric:     "http.errors",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+				},
+			},
+			want: []*sfxpb.DataPoint{
+				{
+					Metric:     "http.requests",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "layer",
+							Value: "app",
+						},
+					},
+				},
+				{
+					Metric:     "http.errors",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "layer",
+							Value: "network",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "inject_dimension_by_metric_overwrite_existing",
+			trs: []Rule{
+				{
+					Action:              ActionInjectDimensionByMetric,
+					MetricNamePatterns:  []string{"^http\\.", "^grpc\\.requests$"},
+					AddDimensions:       map[string]string{"layer": "network"},
+					OverwriteDimensions: true,
+				},
+			},
+			dps: []*sfxpb.DataPoint{
+				{
+					Metric:     "http.requests",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "layer",
+							Value: "app",
+						},
+						{
+							Key:   "host",
+							Value: "a",
+						},
+					},
+				},
+				{
+					Metric:     "db.requests",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "layer",
+							Value: "app",
+						},
+					},
+				},
+			},
+			want: []*sfxpb.DataPoint{
+				{
+					Metric:     "http.requests",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "layer",
+							Value: "network",
+						},
+						{
+							Key:   "host",
+							Value: "a",
+						},
+					},
+				},
+				{
+					Metric:     "db.requests",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "layer",
+							Value: "app",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "multiply_value",
+			trs: []Rule{
+				{
+					Action:             ActionMultiplyValue,
+					MetricNamePatterns: []string{"^memory\\."},
+					ScaleFactor:        0.001,
+				},
+			},
+			dps: []*sfxpb.DataPoint{
+				{
+					Metric:     "memory.used",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1500),
+					},
+				},
+				{
+					Metric:     "memory.free",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(2000),
+					},
+				},
+				{
+					Metric:     "memory.utilization",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						DoubleValue: generateFloatPtr(1234),
+					},
+				},
+				{
+					Metric:     "cpu.used",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1500),
+					},
+				},
+			},
+			want: []*sfxpb.DataPoint{
+				{
+					Metric:     "memory.used",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						DoubleValue: generateFloatPtr(1.5),
+					},
+				},
+				{
+					Metric:     "memory.free",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(2),
+					},
+				},
+				{
+					Metric:     "memory.utilization",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						DoubleValue: generateFloatPtr(1.234),
+					},
+				},
+				{
+					Metric:     "cpu.used",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1500),
+					},
+				},
+			},
+		},
+		{
+			name: "extract_dimension_from_name",
+			trs: []Rule{
+				{
+					Action:             ActionExtractDimensionFromName,
+					MetricNamePatterns: []string{"^tenant_(\\d+)_"},
+					DimensionKey:       "tenant",
+					RemoveMatch:        true,
+				},
+			},
+			dps: []*sfxpb.DataPoint{
+				{
+					Metric:     "tenant_123_requests",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "tenant",
+							Value: "old",
+						},
+					},
+				},
+				{
+					Metric:     "requests",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(2),
+					},
+				},
+				{
+					Metric:     "tenant_abc_requests",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(3),
+					},
+				},
+			},
+			want: []*sfxpb.DataPoint{
+				{
+					Metric:     "requests",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "tenant",
+							Value: "123",
+						},
+					},
+				},
+				{
+					Metric:     "requests",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(2),
+					},
+				},
+				{
+					Metric:     "tenant_abc_requests",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(3),
+					},
+				},
+			},
+		},
+		{
+			name: "extract_dimension_from_name_keep_name",
+			trs: []Rule{
+				{
+					Action:             ActionExtractDimensionFromName,
+					MetricNamePatterns: []string{"^tenant_(\\d+)_"},
+					DimensionKey:       "tenant",
+				},
+			},
+			dps: []*sfxpb.DataPoint{
+				{
+					Metric:     "tenant_123_requests",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+				},
+			},
+			want: []*sfxpb.DataPoint{
+				{
+					Metric:     "tenant_123_requests",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "tenant",
+							Value: "123",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "map_dimension_values",
+			trs: []Rule{
+				{
+					Action:       ActionMapDimensionValues,
+					DimensionKey: "state",
+					Mapping: map[string]string{
+						"up":   "1",
+						"down": "0",
+					},
+				},
+			},
+			dps: []*sfxpb.DataPoint{
+				{
+					Metric:     "link.status",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "host",
+							Value: "up",
+						},
+						{
+							Key:   "state",
+							Value: "up",
+						},
+					},
+				},
+				{
+					Metric:     "link.status",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "state",
+							Value: "unknown",
+						},
+					},
+				},
+			},
+			want: []*sfxpb.DataPoint{
+				{
+					Metric:     "link.status",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "host",
+							Value: "up",
+						},
+						{
+							Key:   "state",
+							Value: "1",
+						},
+					},
+				},
+				{
+					Metric:     "link.status",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "state",
+							Value: "unknown",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "copy_dimension",
+			trs: []Rule{
+				{
+					Action: ActionCopyDimension,
+					Mapping: map[string]string{
+						"host.name": "host",
+					},
+				},
+			},
+			dps: []*sfxpb.DataPoint{
+				{
+					Metric:     "cpu.utilization",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "host.name",
+							Value: "h1",
+						},
+					},
+				},
+				{
+					Metric:     "cpu.utilization",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "cpu",
+							Value: "0",
+						},
+					},
+				},
+				{
+					Metric:     "cpu.utilization",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "host.name",
+							Value: "h2",
+						},
+						{
+							Key:   "host",
+							Value: "existing",
+						},
+					},
+				},
+			},
+			want: []*sfxpb.DataPoint{
+				{
+					Metric:     "cpu.utilization",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "host.name",
+							Value: "h1",
+						},
+						{
+							Key:   "host",
+							Value: "h1",
+						},
+					},
+				},
+				{
+					Metric:     "cpu.utilization",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "cpu",
+							Value: "0",
+						},
+					},
+				},
+				{
+					Metric:     "cpu.utilization",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "host.name",
+							Value: "h2",
+						},
+						{
+							Key:   "host",
+							Value: "existing",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "copy_dimension_overwrite",
+			trs: []Rule{
+				{
+					Action: ActionCopyDimension,
+					Mapping: map[string]string{
+						"host.name": "host",
+					},
+					OverwriteDimensions: true,
+				},
+			},
+			dps: []*sfxpb.DataPoint{
+				{
+					Metric:     "cpu.utilization",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "host.name",
+							Value: "h2",
+						},
+						{
+							Key:   "host",
+							Value: "existing",
+						},
+					},
+				},
+			},
+			want: []*sfxpb.DataPoint{
+				{
+					Metric:     "cpu.utilization",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "host.name",
+							Value: "h2",
+						},
+						{
+							Key:   "host",
+							Value: "h2",
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "split_metric_by_dimension",
+			trs: []Rule{
+				{
+					Action:       ActionSplitMetricByDimension,
+					MetricName:   "connections",
+					DimensionKey: "state",
+				},
+			},
+			dps: []*sfxpb.DataPoint{
+				{
+					Metric:     "connections",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(3),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "host",
+							Value: "host0",
+						},
+						{
+							Key:   "state",
+							Value: "active",
+						},
+					},
+				},
+				{
+					Metric:     "connections",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(5),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "state",
+							Value: "idle",
+						},
+						{
+							Key:   "host",
+							Value: "host0",
+						},
+					},
+				},
+				{
+					Metric:     "connections",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "state",
+							Value: "closing",
+						},
+					},
+				},
+				{
+					Metric:     "connections",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(9),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "host",
+							Value: "host0",
+						},
+					},
+				},
+			},
+			want: []*sfxpb.DataPoint{
+				{
+					Metric:     "connections.active",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(3),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "host",
+							Value: "host0",
+						},
+					},
+				},
+				{
+					Metric:     "connections.idle",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(5),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "host",
+							Value: "host0",
+						},
+					},
+				},
+				{
+					Metric:     "connections.closing",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(1),
+					},
+					Dimensions: []*sfxpb.Dimension{},
+				},
+				{
+					Metric:     "connections",
+					Timestamp:  msec,
+					MetricType: &gaugeType,
+					Value: sfxpb.Datum{
+						IntValue: generateIntPtr(9),
+					},
+					Dimensions: []*sfxpb.Dimension{
+						{
+							Key:   "host",
+							Value: "host0",
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt, err := NewMetricTranslator(tt.trs, 1)
+			require.NoError(t, err)
+			assert.NotEqualValues(t, tt.want, tt.dps)
+			got := mt.TranslateDataPoints(zap.NewNop(), tt.dps)
+			assertEqualPoints(t, got, tt.want, tt.trs[0].Action)
+		})
+	}
+}
+
+func assertEqualPoints(t *testing.T, got []*sfxpb.DataPoint, want []*sfxpb.DataPoint, action Action) {
+	// Sort metrics to handle not deterministic order from aggregation
+	if action == ActionAggregateMetric {
+		sort.Sort(byContent(want))
+		sort.Sort(byContent(got))
+	}
+
+	if action == ActionRenameMetrics {
+		for _, dp := range want {
+			sort.Sort(byDimensions(dp.Dimensions))
+		}
+
+		for _, dp := range got {
+			sort.Sort(byDimensions(dp.Dimensions))
+		}
+	}
+
+	// Handle float values separately
+	for i, dp := range got {
+		if dp.GetValue().DoubleValue != nil {
+			assert.InDelta(t, *want[i].GetValue().DoubleValue, *dp.GetValue().DoubleValue, 0.00000001)
+			*dp.GetValue().DoubleValue = *want[i].GetValue().DoubleValue
+		}
+	}
+
+	assert.EqualValues(t, want, got)
+}
+
+func TestTranslateDataPointsDecodeDimensionValue(t *testing.T) {
+	tests := []struct {
+		name      string
+		encoding  DimensionValueEncoding
+		value     string
+		wantValue string
+		wantLogs  int
+	}{
+		{
+			name:      "base64",
+			encoding:  DimensionValueEncodingBase64,
+			value:     "YWJjMTIz",
+			wantValue: "abc123",
+		},
+		{
+			name:      "invalid_base64",
+			encoding:  DimensionValueEncodingBase64,
+			value:     "not base64!",
+			wantValue: "not base64!",
+			wantLogs:  1,
+		},
+		{
+			name:      "hex",
+			encoding:  DimensionValueEncodingHex,
+			value:     "616263313233",
+			wantValue: "abc123",
+		},
+		{
+			name:      "invalid_hex",
+			encoding:  DimensionValueEncodingHex,
+			value:     "abc",
+			wantValue: "abc",
+			wantLogs:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt, err := NewMetricTranslator([]Rule{
+				{
+					Action:       ActionDecodeDimensionValue,
+					DimensionKey: "trace_id",
+					Encoding:     tt.encoding,
+				},
+			}, 1)
+			require.NoError(t, err)
+
+			shared := &sfxpb.Dimension{Key: "trace_id", Value: tt.value}
+			dps := []*sfxpb.DataPoint{
+				{
+					Metric:     "span.count",
+					Dimensions: []*sfxpb.Dimension{{Key: "host", Value: "h1"}, shared},
+				},
+				{
+					Metric:     "span.count",
+					Dimensions: []*sfxpb.Dimension{{Key: "host", Value: "h2"}},
+				},
+			}
+
+			core, observedLogs := observer.New(zap.DebugLevel)
+			got := mt.TranslateDataPoints(zap.New(core), dps)
+			require.Len(t, got, 2)
+			assert.Equal(t, []*sfxpb.Dimension{{Key: "host", Value: "h1"}, {Key: "trace_id", Value: tt.wantValue}}, got[0].Dimensions)
+			assert.Equal(t, []*sfxpb.Dimension{{Key: "host", Value: "h2"}}, got[1].Dimensions)
+			// The shared dimension is replaced, not updated in place.
+			assert.Equal(t, tt.value, shared.Value)
+			assert.Equal(t, tt.wantLogs, observedLogs.FilterMessage("failed to decode dimension value, keeping it as is").Len())
+		})
+	}
+}
+
+func TestTranslateDataPointsConcatenateDimensions(t *testing.T) {
+	tests := []struct {
+		name         string
+		allowMissing bool
+		overwrite    bool
+		dims         []*sfxpb.Dimension
+		wantDims     []*sfxpb.Dimension
+	}{
+		{
+			name: "all_present",
+			dims: []*sfxpb.Dimension{{Key: "environment", Value: "prod"}, {Key: "service", Value: "api"}},
+			wantDims: []*sfxpb.Dimension{
+				{Key: "environment", Value: "prod"},
+				{Key: "service", Value: "api"},
+				{Key: "service_env", Value: "api_prod"},
+			},
+		},
+		{
+			name:     "partially_present",
+			dims:     []*sfxpb.Dimension{{Key: "service", Value: "api"}},
+			wantDims: []*sfxpb.Dimension{{Key: "service", Value: "api"}},
+		},
+		{
+			name:         "partially_present_allowed",
+			allowMissing: true,
+			dims:         []*sfxpb.Dimension{{Key: "service", Value: "api"}},
+			wantDims: []*sfxpb.Dimension{
+				{Key: "service", Value: "api"},
+				{Key: "service_env", Value: "api"},
+			},
+		},
+		{
+			name:         "none_present_allowed",
+			allowMissing: true,
+			dims:         []*sfxpb.Dimension{{Key: "host", Value: "h1"}},
+			wantDims:     []*sfxpb.Dimension{{Key: "host", Value: "h1"}},
+		},
+		{
+			name: "target_present",
+			dims: []*sfxpb.Dimension{
+				{Key: "service", Value: "api"},
+				{Key: "environment", Value: "prod"},
+				{Key: "service_env", Value: "existing"},
+			},
+			wantDims: []*sfxpb.Dimension{
+				{Key: "service", Value: "api"},
+				{Key: "environment", Value: "prod"},
+				{Key: "service_env", Value: "existing"},
+			},
+		},
+		{
+			name:      "target_present_overwritten",
+			overwrite: true,
+			dims: []*sfxpb.Dimension{
+				{Key: "service", Value: "api"},
+				{Key: "environment", Value: "prod"},
+				{Key: "service_env", Value: "existing"},
+			},
+			wantDims: []*sfxpb.Dimension{
+				{Key: "service", Value: "api"},
+				{Key: "environment", Value: "prod"},
+				{Key: "service_env", Value: "api_prod"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt, err := NewMetricTranslator([]Rule{
+				{
+					Action:                 ActionConcatenateDimensions,
+					SourceDimensionKeys:    []string{"service", "environment"},
+					Separator:              "_",
+					DimensionKey:           "service_env",
+					AllowMissingDimensions: tt.allowMissing,
+					OverwriteDimensions:    tt.overwrite,
+				},
+			}, 1)
+			require.NoError(t, err)
+
+			got := mt.TranslateDataPoints(zap.NewNop(), []*sfxpb.DataPoint{{Metric: "requests", Dimensions: tt.dims}})
+			require.Len(t, got, 1)
+			assert.Equal(t, tt.wantDims, got[0].Dimensions)
+		})
+	}
+}
+
+func TestTranslateDataPointsRulePanics(t *testing.T) {
+	divisors := map[string]int64{"requests": 10}
+	mt, err := NewMetricTranslator([]Rule{
+		{
+			Action:          ActionDivideInt,
+			ScaleFactorsInt: divisors,
+		},
+		{
+			Action:  ActionRenameMetrics,
+			Mapping: map[string]string{"requests": "renamed"},
+		},
+	}, 1)
+	require.NoError(t, err)
+	// Divisors are validated when creating the translator, break the rule
+	// afterwards to make it panic.
+	divisors["requests"] = 0
+
+	dps := []*sfxpb.DataPoint{
+		{
+			Metric:     "requests",
+			MetricType: &gaugeType,
+			Value:      sfxpb.Datum{IntValue: generateIntPtr(20)},
+		},
+	}
+	core, observedLogs := observer.New(zap.ErrorLevel)
+	var got []*sfxpb.DataPoint
+	require.NotPanics(t, func() {
+		got = mt.TranslateDataPoints(zap.New(core), dps)
+	})
+
+	assert.Equal(t, []*sfxpb.DataPoint{
+		{
+			Metric:     "requests",
+			MetricType: &gaugeType,
+			Value:      sfxpb.Datum{IntValue: generateIntPtr(20)},
+		},
+	}, got)
+
+	logs := observedLogs.FilterMessage("translation rule panicked, skipping translation of datapoints").All()
+	require.Len(t, logs, 1)
+	assert.Equal(t, int64(0), logs[0].ContextMap()["rule_index"])
+	assert.Equal(t, "divide_int", logs[0].ContextMap()["action"])
+	assert.Equal(t, "requests", logs[0].ContextMap()["metric"])
+}
+
+func TestTestTranslateDimension(t *testing.T) {
+	mt, err := NewMetricTranslator([]Rule{
+		{
+			Action: ActionRenameDimensionKeys,
+			Mapping: map[string]string{
+				"old_dimension": "new_dimension",
+				"old.dimension": "new.dimension",
+			},
+		},
+	}, 1)
+	require.NoError(t, err)
+
+	assert.Equal(t, "new_dimension", mt.TranslateDimension("old_dimension"))
+	assert.Equal(t, "new.dimension", mt.TranslateDimension("old.dimension"))
+	assert.Equal(t, "another_dimension", mt.TranslateDimension("another_dimension"))
+
+	// Test no rename_dimension_keys translation rule
+	mt, err = NewMetricTranslator([]Rule{}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "old_dimension", mt.TranslateDimension("old_dimension"))
+}
+
+func TestNewCalculateNewMetricErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		metric1 string
+		val1    *int64
+		metric2 string
+		val2    *int64
+		wantErr string
 	}{
 		{
 			name:    "operand1_nil",
@@ -2519,10 +3838,73 @@ func TestNegativeDeltas(t *testing.T) {
 	}
 }
 
+func TestDeltaMetricZeroOnReset(t *testing.T) {
+	tests := []struct {
+		name     string
+		mds      []pdata.ResourceMetrics
+		getValue func(pt *sfxpb.DataPoint) float64
+	}{
+		{
+			name: "int",
+			mds:  []pdata.ResourceMetrics{intMD(10, 0), intMD(20, 13), intMDAfterReset(30, 5)},
+			getValue: func(pt *sfxpb.DataPoint) float64 {
+				return float64(*pt.Value.IntValue)
+			},
+		},
+		{
+			name: "double",
+			mds:  []pdata.ResourceMetrics{doubleMD(10, 0), doubleMD(20, 13), doubleMD(30, -50)},
+			getValue: func(pt *sfxpb.DataPoint) float64 {
+				return *pt.Value.DoubleValue
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, err := NewMetricTranslator([]Rule{{
+				Action:      ActionDeltaMetric,
+				Mapping:     map[string]string{"system.cpu.time": "system.cpu.delta"},
+				ZeroOnReset: true,
+			}}, 1)
+			require.NoError(t, err)
+			c, err := NewMetricsConverter(zap.NewNop(), tr, MetricsConverterOptions{})
+			require.NoError(t, err)
+
+			dps, _ := c.MetricDataToSignalFxV2(tt.mds[0])
+			require.Empty(t, indexPts(dps)["system.cpu.delta"])
+
+			dps, _ = c.MetricDataToSignalFxV2(tt.mds[1])
+			deltaPts := indexPts(dps)["system.cpu.delta"]
+			require.Len(t, deltaPts, 6)
+			for _, pt := range deltaPts {
+				assert.EqualValues(t, 13, tt.getValue(pt))
+			}
+
+			dps, _ = c.MetricDataToSignalFxV2(tt.mds[2])
+			deltaPts = indexPts(dps)["system.cpu.delta"]
+			currPts := indexPts(dps)["system.cpu.time"]
+			// The counter went down, a zero is emitted right before each delta which keeps the value
+			// after the reset.
+			require.Len(t, deltaPts, 2*len(currPts))
+			for i, currPt := range currPts {
+				zero, delta := deltaPts[2*i], deltaPts[2*i+1]
+				assert.EqualValues(t, 0, tt.getValue(zero))
+				// SignalFx keeps a single point per timestamp, the zero must not overwrite the delta.
+				assert.EqualValues(t, 1600000029999, zero.Timestamp)
+				assert.NotEqual(t, delta.Timestamp, zero.Timestamp)
+				assert.Equal(t, zero.Dimensions, delta.Dimensions)
+				assert.EqualValues(t, tt.getValue(currPt), tt.getValue(delta))
+				assert.EqualValues(t, 1600000030000, delta.Timestamp)
+			}
+		})
+	}
+}
+
 func TestDeltaTranslatorNoMatchingMapping(t *testing.T) {
 	c := testConverter(t, map[string]string{"foo": "bar"})
 	md := intMD(1, 1)
-	idx := indexPts(c.MetricDataToSignalFxV2(md))
+	dps, _ := c.MetricDataToSignalFxV2(md)
+	idx := indexPts(dps)
 	require.Equal(t, 1, len(idx))
 }
 
@@ -2532,11 +3914,11 @@ func TestDeltaTranslatorMismatchedValueTypes(t *testing.T) {
 	md1.SetDataType(pdata.MetricDataTypeIntSum)
 	md1.IntSum().DataPoints().Append(intTS("cpu0", "user", 1, 1, 1))
 
-	_ = c.MetricDataToSignalFxV2(wrapMetric(md1))
+	_, _ = c.MetricDataToSignalFxV2(wrapMetric(md1))
 	md2 := baseMD()
 	md2.SetDataType(pdata.MetricDataTypeDoubleSum)
 	md2.DoubleSum().DataPoints().Append(dblTS("cpu0", "user", 1, 1, 1))
-	pts := c.MetricDataToSignalFxV2(wrapMetric(md2))
+	pts, _ := c.MetricDataToSignalFxV2(wrapMetric(md2))
 	idx := indexPts(pts)
 	require.Equal(t, 1, len(idx))
 }
@@ -2546,11 +3928,11 @@ func requireDeltaMetricOk(t *testing.T, md1, md2, md3 pdata.ResourceMetrics) (
 ) {
 	c := testConverter(t, map[string]string{"system.cpu.time": "system.cpu.delta"})
 
-	dp1 := c.MetricDataToSignalFxV2(md1)
+	dp1, _ := c.MetricDataToSignalFxV2(md1)
 	m1 := indexPts(dp1)
 	require.Equal(t, 1, len(m1))
 
-	dp2 := c.MetricDataToSignalFxV2(md2)
+	dp2, _ := c.MetricDataToSignalFxV2(md2)
 	m2 := indexPts(dp2)
 	require.Equal(t, 2, len(m2))
 
@@ -2565,7 +3947,7 @@ func requireDeltaMetricOk(t *testing.T, md1, md2, md3 pdata.ResourceMetrics) (
 		require.Equal(t, &counterType, pt.MetricType)
 	}
 
-	dp3 := c.MetricDataToSignalFxV2(md3)
+	dp3, _ := c.MetricDataToSignalFxV2(md3)
 	m3 := indexPts(dp3)
 	require.Equal(t, 2, len(m3))
 
@@ -2586,7 +3968,8 @@ func testConverter(t *testing.T, mapping map[string]string) *MetricsConverter {
 	tr, err := NewMetricTranslator(rules, 1)
 	require.NoError(t, err)
 
-	c := NewMetricsConverter(zap.NewNop(), tr)
+	c, err := NewMetricsConverter(zap.NewNop(), tr, MetricsConverterOptions{})
+	require.NoError(t, err)
 	return c
 }
 
@@ -23,6 +23,8 @@ import (
 type TTLMap struct {
 	md            *ttlMapData
 	sweepInterval int64
+	done          chan struct{}
+	shutdownOnce  sync.Once
 }
 
 // New creates a TTLMap. The sweepIntervalSeconds arg indicates how often
@@ -34,19 +36,34 @@ func New(sweepIntervalSeconds int64, maxAgeSeconds int64) *TTLMap {
 	return &TTLMap{
 		sweepInterval: sweepIntervalSeconds,
 		md:            newTTLMapData(maxAgeSeconds),
+		done:          make(chan struct{}),
 	}
 }
 
 // Start starts periodic sweeps for expired entries in the underlying map.
 func (m *TTLMap) Start() {
 	go func() {
-		d := time.Duration(m.sweepInterval) * time.Second
-		for now := range time.Tick(d) {
-			m.md.sweep(now.Unix())
+		ticker := time.NewTicker(time.Duration(m.sweepInterval) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				m.md.sweep(now.Unix())
+			case <-m.done:
+				return
+			}
 		}
 	}()
 }
 
+// Shutdown stops the periodic sweeps started by Start. Entries are no longer
+// evicted afterwards. It is safe to call more than once.
+func (m *TTLMap) Shutdown() {
+	m.shutdownOnce.Do(func() {
+		close(m.done)
+	})
+}
+
 // Put adds the passed-in key and value to the underlying map. The current time
 // is attached to the entry for periodic expiration checking and eviction when
 // necessary.
@@ -54,6 +71,12 @@ func (m *TTLMap) Put(k string, v interface{}) {
 	m.md.put(k, v, time.Now().Unix())
 }
 
+// Len returns the number of entries in the underlying map, including expired
+// entries not swept yet.
+func (m *TTLMap) Len() int {
+	return m.md.len()
+}
+
 // Get returns the object in the underlying map at the given key. If there is no
 // value at that key, Get returns nil.
 func (m *TTLMap) Get(k string) interface{} {
@@ -95,6 +118,12 @@ func (d *ttlMapData) get(k string) interface{} {
 	return entry.v
 }
 
+func (d *ttlMapData) len() int {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	return len(d.m)
+}
+
 func (d *ttlMapData) sweep(currTime int64) {
 	d.mux.Lock()
 	for k, v := range d.m {
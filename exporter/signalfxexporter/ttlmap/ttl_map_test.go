@@ -31,6 +31,19 @@ func TestTTLMapData(t *testing.T) {
 	require.Nil(t, m.get("bob"))
 }
 
+func TestTTLMapDataLen(t *testing.T) {
+	m := newTTLMapData(10)
+	require.Equal(t, 0, m.len())
+	m.put("foo", "xyz", 2)
+	m.put("bob", "xyz", 5)
+	m.put("foo", "abc", 2)
+	require.Equal(t, 2, m.len())
+	m.sweep(13)
+	require.Equal(t, 1, m.len())
+	m.sweep(16)
+	require.Equal(t, 0, m.len())
+}
+
 func TestTTLMapSimple(t *testing.T) {
 	m := New(5, 10)
 	require.EqualValues(t, m.sweepInterval, 5)
@@ -51,3 +64,17 @@ func TestTTLMapLong(t *testing.T) {
 	time.Sleep(time.Second * 3)
 	require.Nil(t, m.Get("foo"))
 }
+
+func TestTTLMapShutdown(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping TestTTLMapShutdown in short mode")
+	}
+	m := New(1, 1)
+	m.Start()
+	m.Shutdown()
+	m.Shutdown()
+	m.Put("foo", "bar")
+	time.Sleep(time.Second * 3)
+	// No sweeps once shut down.
+	require.Equal(t, "bar", m.Get("foo"))
+}